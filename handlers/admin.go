@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"waku/services"
+	"waku/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetWebhookRoutesRequest represents the request body for routing a device's webhooks
+type SetWebhookRoutesRequest struct {
+	URLs    []string          `json:"urls"`
+	Headers map[string]string `json:"headers"`
+}
+
+// SetWebhookRoutes replaces the webhook targets of a device. An empty list
+// falls back to the global WEBHOOK_URL. Headers are replaced only when sent.
+func SetWebhookRoutes(c *gin.Context) {
+	deviceID := c.Param("device_id")
+
+	var req SetWebhookRoutesRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	for _, url := range req.URLs {
+		if err := services.ValidateWebhookURL(url); err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+	for name, value := range req.Headers {
+		if err := services.ValidateWebhookHeader(name, value); err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	waService := services.GetWhatsAppService()
+	config, err := waService.UpdateDeviceConfig(deviceID, func(config *services.DeviceConfig) error {
+		config.WebhookURLs = req.URLs
+		if req.Headers != nil {
+			config.WebhookHeaders = req.Headers
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Webhook routes updated", gin.H{
+		"device_id":    deviceID,
+		"webhook_urls": config.WebhookURLs,
+		// Header values may be credentials, so only their names are echoed
+		"webhook_headers": config.View().WebhookHeaders,
+	})
+}
+
+// GetWebhookStats returns aggregated webhook delivery results per target
+func GetWebhookStats(c *gin.Context) {
+	stats := services.GetWebhookService().Stats()
+
+	utils.SuccessResponse(c, http.StatusOK, "Webhook stats retrieved", gin.H{
+		"total":   len(stats),
+		"targets": stats,
+	})
+}
+
+// GetStoreStats returns per-device message counts and the size of the message store
+func GetStoreStats(c *gin.Context) {
+	stats, err := services.GetWhatsAppService().GetStoreStats()
+	if errors.Is(err, services.ErrStoreDisabled) {
+		respondError(c, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Store stats retrieved", stats)
+}
+
+// GetNumberCacheStats returns the on-WhatsApp lookup cache counters
+func GetNumberCacheStats(c *gin.Context) {
+	stats := services.GetWhatsAppService().NumberCacheStats()
+	utils.SuccessResponse(c, http.StatusOK, "Number cache stats retrieved", stats)
+}
+
+// GetReport returns server and session state in one document for support tickets
+func GetReport(c *gin.Context) {
+	report := services.GetWhatsAppService().GetReport()
+	utils.SuccessResponse(c, http.StatusOK, "Report generated", report)
+}
+
+// SimulateIncomingRequest represents the request body for simulating an incoming message
+type SimulateIncomingRequest struct {
+	DeviceID string `json:"device_id" binding:"required"`
+	Type     string `json:"type"`
+	From     string `json:"from" binding:"required"`
+	Body     string `json:"body"`
+}
+
+// SimulateIncoming fires the webhook with a synthetic incoming message
+func SimulateIncoming(c *gin.Context) {
+	var req SimulateIncomingRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	messageID, err := services.GetWhatsAppService().SimulateIncoming(req.DeviceID, req.Type, req.From, req.Body)
+	if errors.Is(err, services.ErrInvalidSimulation) {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Incoming message simulated", gin.H{
+		"message_id": messageID,
+	})
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"waku/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bindJSON decodes the request body into req and checks the token scope
+// against the device_id it names, answering 400 or 403 itself on failure.
+// Checking the bound struct means duplicate or differently-cased keys resolve
+// exactly as the handler sees them.
+func bindJSON(c *gin.Context, req interface{}) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return false
+	}
+	return allowDevice(c, boundDeviceID(req))
+}
+
+// allowDevice answers 403 when the token may not operate on deviceID
+func allowDevice(c *gin.Context, deviceID string) bool {
+	if deviceID == "" || utils.Scope(c).Allows(deviceID) {
+		return true
+	}
+	utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: token is not allowed to access device "+deviceID)
+	return false
+}
+
+// boundDeviceID returns the string field of req bound from the device_id JSON key
+func boundDeviceID(req interface{}) string {
+	v := reflect.Indirect(reflect.ValueOf(req))
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	for i := 0; i < v.NumField(); i++ {
+		name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+		if name == "device_id" && v.Field(i).Kind() == reflect.String {
+			return v.Field(i).String()
+		}
+	}
+	return ""
+}
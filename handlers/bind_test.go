@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"waku/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func serveBind(scope *utils.TokenScope, body string) int {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/send", func(c *gin.Context) {
+		c.Set(utils.TokenScopeKey, scope)
+		var req SendMessageRequest
+		if !bindJSON(c, &req) {
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/send", strings.NewReader(body)))
+	return rec.Code
+}
+
+func TestBindJSONScope(t *testing.T) {
+	tenant := &utils.TokenScope{Name: "tenant", Devices: map[string]bool{"mine": true}}
+	admin := &utils.TokenScope{Name: "admin", All: true}
+
+	tests := []struct {
+		name  string
+		scope *utils.TokenScope
+		body  string
+		want  int
+	}{
+		{"own device", tenant, `{"device_id":"mine","phone":"628123456789","message":"hi"}`, http.StatusOK},
+		{"other device", tenant, `{"device_id":"victim","phone":"628123456789","message":"hi"}`, http.StatusForbidden},
+		{"duplicate key, last wins", tenant, `{"device_id":"mine","device_id":"victim","phone":"628123456789","message":"hi"}`, http.StatusForbidden},
+		{"case-variant key", tenant, `{"Device_ID":"victim","phone":"628123456789","message":"hi"}`, http.StatusForbidden},
+		{"admin on any device", admin, `{"device_id":"victim","phone":"628123456789","message":"hi"}`, http.StatusOK},
+		{"invalid body", tenant, `{"device_id":`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serveBind(tt.scope, tt.body); got != tt.want {
+				t.Fatalf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"waku/services"
+	"waku/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FetchChatHistory requests older messages of a chat from the phone and stores them
+func FetchChatHistory(c *gin.Context) {
+	deviceID := c.Param("device_id")
+	chatJID := c.Param("jid")
+
+	count, err := strconv.Atoi(c.DefaultQuery("count", "50"))
+	if err != nil || count <= 0 || count > 500 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "count must be between 1 and 500")
+		return
+	}
+	wait, err := strconv.Atoi(c.DefaultQuery("wait", "30"))
+	if err != nil || wait < 0 || wait > 120 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "wait must be between 0 and 120 seconds")
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	retrieved, completed, err := waService.FetchChatHistory(deviceID, chatJID, count, time.Duration(wait)*time.Second)
+	if errors.Is(err, services.ErrStoreDisabled) {
+		respondError(c, http.StatusConflict, err)
+		return
+	}
+	if errors.Is(err, services.ErrNoHistoryAnchor) {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !completed {
+		// The phone may still answer; messages are stored when they arrive
+		utils.SuccessResponse(c, http.StatusAccepted, "History requested, waiting for the phone to respond", gin.H{
+			"chat":      chatJID,
+			"status":    "pending",
+			"retrieved": 0,
+		})
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "History fetched", gin.H{
+		"chat":      chatJID,
+		"status":    "completed",
+		"retrieved": retrieved,
+	})
+}
+
+// ChatHistoryRequest represents the request body for fetching recent chat history
+type ChatHistoryRequest struct {
+	// JID is a phone number, personal JID or group JID
+	JID   string `json:"jid" binding:"required"`
+	Count int    `json:"count"`
+	// TimeoutSeconds is how long to wait for the phone, 30 by default
+	TimeoutSeconds *int `json:"timeout_seconds"`
+}
+
+// GetChatHistory requests older messages of a chat from the phone and returns them
+func GetChatHistory(c *gin.Context) {
+	var req ChatHistoryRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if req.Count == 0 {
+		req.Count = 20
+	}
+	if req.Count < 0 || req.Count > 100 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "count must be between 1 and 100")
+		return
+	}
+	timeout := 30
+	if req.TimeoutSeconds != nil {
+		timeout = *req.TimeoutSeconds
+	}
+	if timeout < 0 || timeout > 120 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "timeout_seconds must be between 0 and 120")
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	messages, completed, err := waService.ChatHistory(c.Param("device_id"), req.JID, req.Count, time.Duration(timeout)*time.Second)
+	if errors.Is(err, services.ErrNoHistoryAnchor) {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	if !completed {
+		// Messages arriving later are still buffered for the next request
+		utils.SuccessResponse(c, http.StatusAccepted, "History requested, waiting for the phone to respond", gin.H{
+			"jid":      req.JID,
+			"status":   "pending",
+			"messages": []services.StoredMessage{},
+		})
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "History fetched", gin.H{
+		"jid":      req.JID,
+		"status":   "completed",
+		"count":    len(messages),
+		"messages": messages,
+	})
+}
+
+// SendPresenceRequest represents the request body for showing a chat presence
+type SendPresenceRequest struct {
+	DeviceID string `json:"device_id" binding:"required"`
+	Phone    string `json:"phone"`
+	GroupJID string `json:"group_jid"`
+	// State is composing, recording or paused
+	State string `json:"state" binding:"required"`
+}
+
+// SendPresence shows or clears the typing or recording indicator in a chat
+func SendPresence(c *gin.Context) {
+	var req SendPresenceRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if msg := validateTarget(req.Phone, req.GroupJID); msg != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	err := services.GetWhatsAppService().SendChatPresence(req.DeviceID, req.Phone, req.GroupJID, req.State)
+	if err != nil {
+		respondError(c, presenceErrorStatus(err), err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Presence sent", gin.H{
+		"device_id": req.DeviceID,
+		"state":     req.State,
+	})
+}
+
+// SetAvailabilityRequest represents the request body for setting a session's availability
+type SetAvailabilityRequest struct {
+	DeviceID string `json:"device_id" binding:"required"`
+	// Availability is available or unavailable
+	Availability string `json:"availability" binding:"required"`
+}
+
+// SetAvailability marks a session as online or offline
+func SetAvailability(c *gin.Context) {
+	var req SetAvailabilityRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	err := services.GetWhatsAppService().SetAvailability(req.DeviceID, req.Availability)
+	if err != nil {
+		respondError(c, presenceErrorStatus(err), err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Availability updated", gin.H{
+		"device_id":    req.DeviceID,
+		"availability": req.Availability,
+	})
+}
+
+// SubscribePresenceRequest represents the request body for subscribing to a contact's presence
+type SubscribePresenceRequest struct {
+	DeviceID string `json:"device_id" binding:"required"`
+	Phone    string `json:"phone" binding:"required"`
+}
+
+// SubscribePresence subscribes to a contact's online status, delivered as presence webhooks
+func SubscribePresence(c *gin.Context) {
+	var req SubscribePresenceRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if msg := validateTarget(req.Phone, ""); msg != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	jid, err := services.GetWhatsAppService().SubscribePresence(req.DeviceID, req.Phone)
+	if err != nil {
+		respondError(c, presenceErrorStatus(err), err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Subscribed to presence", gin.H{
+		"device_id": req.DeviceID,
+		"phone":     req.Phone,
+		"jid":       jid.String(),
+	})
+}
+
+// GetContactPresence returns the last-known presence of a subscribed contact
+func GetContactPresence(c *gin.Context) {
+	phone, ok := services.NormalizePhoneNumber(c.Query("phone"))
+	if !ok {
+		utils.ErrorResponse(c, http.StatusBadRequest, "phone query parameter must be a phone number with country code (e.g., 628123456789)")
+		return
+	}
+
+	presence, err := services.GetWhatsAppService().GetContactPresence(c.Param("device_id"), phone)
+	if err != nil {
+		respondError(c, presenceErrorStatus(err), err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Presence retrieved", presence)
+}
+
+// presenceErrorStatus picks the HTTP status for a presence error
+func presenceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidPresence):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrSessionNotFound), errors.Is(err, services.ErrPresenceUnknown):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrSessionNotConnected):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// DisappearingRequest represents the request body for setting a chat's disappearing timer
+type DisappearingRequest struct {
+	// JID is a phone number, personal JID or group JID
+	JID string `json:"jid" binding:"required"`
+	// Duration is one of off, 24h, 7d or 90d
+	Duration string `json:"duration" binding:"required"`
+}
+
+// SetChatDisappearing sets the disappearing-message timer of a chat
+func SetChatDisappearing(c *gin.Context) {
+	var req DisappearingRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	seconds, err := waService.SetChatDisappearing(c.Param("device_id"), req.JID, req.Duration)
+	if errors.Is(err, services.ErrInvalidDisappearingTimer) {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		respondError(c, groupErrorStatus(err), err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Disappearing timer updated", gin.H{
+		"jid":      req.JID,
+		"duration": req.Duration,
+		"seconds":  seconds,
+	})
+}
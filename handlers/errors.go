@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"errors"
+	"waku/services"
+	"waku/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mau.fi/whatsmeow"
+)
+
+// errorCodes maps service errors to the error codes clients branch on
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{services.ErrSessionNotFound, utils.CodeSessionNotFound},
+	{services.ErrSessionNotConnected, utils.CodeSessionNotConnected},
+	{services.ErrSessionExists, utils.CodeSessionExists},
+	{services.ErrAlreadyPaired, utils.CodeAlreadyPaired},
+	{services.ErrInvalidDeviceID, utils.CodeInvalidRequest},
+	{services.ErrNotOnWhatsApp, utils.CodeRecipientNotFound},
+	{services.ErrMessageRejected, utils.CodeMessageRejected},
+	{services.ErrStoreDisabled, utils.CodeStoreDisabled},
+	{services.ErrNotGroupMember, utils.CodeNotGroupMember},
+	{services.ErrMediaNotFound, utils.CodeMediaNotFound},
+	{services.ErrEditWindowExpired, utils.CodeEditWindowExpired},
+	{services.ErrMessageNotFound, utils.CodeMessageNotFound},
+	{services.ErrRevokeNotPermitted, utils.CodeRevokeNotPermitted},
+	{services.ErrReconnectTimeout, utils.CodeReconnectTimeout},
+	{services.ErrNotGroupAdmin, utils.CodeNotGroupAdmin},
+	{services.ErrGroupNotFound, utils.CodeGroupNotFound},
+	{whatsmeow.ErrProfilePictureUnauthorized, utils.CodeProfilePictureRestricted},
+	{utils.ErrInsufficientStorage, utils.CodeInsufficientStorage},
+}
+
+// errorCode returns the error code for a service error, falling back to the status's generic code
+func errorCode(statusCode int, err error) string {
+	var pendingErr *services.SessionPendingError
+	if errors.As(err, &pendingErr) {
+		return utils.CodeSessionPending
+	}
+	for _, mapping := range errorCodes {
+		if errors.Is(err, mapping.err) {
+			return mapping.code
+		}
+	}
+	return utils.CodeForStatus(statusCode)
+}
+
+// respondError sends an error response for a service error with its error code
+func respondError(c *gin.Context, statusCode int, err error) {
+	utils.ErrorResponseWithCode(c, statusCode, errorCode(statusCode, err), err.Error())
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+	"waku/services"
+	"waku/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.mau.fi/whatsmeow"
+)
+
+// CreateGroupRequest represents the request body for creating a group
+type CreateGroupRequest struct {
+	DeviceID     string   `json:"device_id" binding:"required"`
+	Name         string   `json:"name" binding:"required"`
+	Participants []string `json:"participants" binding:"required,min=1"`
+}
+
+// groupErrorStatus maps group management errors to HTTP statuses
+func groupErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidGroup), errors.Is(err, whatsmeow.ErrInviteLinkInvalid):
+		return http.StatusBadRequest
+	case errors.Is(err, whatsmeow.ErrInviteLinkRevoked):
+		return http.StatusGone
+	case errors.Is(err, services.ErrNotGroupAdmin), errors.Is(err, services.ErrNotGroupMember):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrSessionNotFound), errors.Is(err, services.ErrGroupNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrSessionNotConnected):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// CreateGroup creates a WhatsApp group with the given participants
+func CreateGroup(c *gin.Context) {
+	var req CreateGroupRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if len(req.Participants) > services.MaxCheckNumbers {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("At most %d participants can be added at once", services.MaxCheckNumbers))
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	group, err := waService.CreateGroup(req.DeviceID, req.Name, req.Participants)
+	if err != nil {
+		respondError(c, groupErrorStatus(err), err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Group created successfully", group)
+}
+
+// GroupParticipantsRequest represents the request body for changing group participants
+type GroupParticipantsRequest struct {
+	DeviceID     string   `json:"device_id" binding:"required"`
+	Participants []string `json:"participants" binding:"required,min=1"`
+}
+
+// AddGroupParticipants adds participants to a group
+func AddGroupParticipants(c *gin.Context) {
+	updateGroupParticipants(c, whatsmeow.ParticipantChangeAdd, "Group participants added")
+}
+
+// RemoveGroupParticipants removes participants from a group
+func RemoveGroupParticipants(c *gin.Context) {
+	updateGroupParticipants(c, whatsmeow.ParticipantChangeRemove, "Group participants removed")
+}
+
+// PromoteGroupParticipants makes participants group admins
+func PromoteGroupParticipants(c *gin.Context) {
+	updateGroupParticipants(c, whatsmeow.ParticipantChangePromote, "Group participants promoted")
+}
+
+// DemoteGroupParticipants removes participants' group admin rights
+func DemoteGroupParticipants(c *gin.Context) {
+	updateGroupParticipants(c, whatsmeow.ParticipantChangeDemote, "Group participants demoted")
+}
+
+// updateGroupParticipants applies a participant change and reports each participant's outcome
+func updateGroupParticipants(c *gin.Context, action whatsmeow.ParticipantChange, message string) {
+	groupJID := c.Param("group_jid")
+
+	var req GroupParticipantsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if !isGroupJID(groupJID) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid group JID format. Should end with @g.us")
+		return
+	}
+	if len(req.Participants) > services.MaxCheckNumbers {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("At most %d participants can be changed at once", services.MaxCheckNumbers))
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	results, err := waService.UpdateGroupParticipants(req.DeviceID, groupJID, req.Participants, action)
+	if err != nil {
+		respondError(c, groupErrorStatus(err), err)
+		return
+	}
+
+	succeeded := 0
+	for _, result := range results {
+		if result.OK {
+			succeeded++
+		}
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, message, gin.H{
+		"group_jid":    groupJID,
+		"total":        len(results),
+		"succeeded":    succeeded,
+		"failed":       len(results) - succeeded,
+		"participants": results,
+	})
+}
+
+// GetGroupInviteLink returns a group's invite link, regenerating it when reset=true
+func GetGroupInviteLink(c *gin.Context) {
+	groupJID := c.Param("group_jid")
+	deviceID := c.Query("device_id")
+	reset, _ := strconv.ParseBool(c.Query("reset"))
+
+	if deviceID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "device_id query parameter is required")
+		return
+	}
+	if !isGroupJID(groupJID) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid group JID format. Should end with @g.us")
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	invite, err := waService.GetGroupInviteLink(deviceID, groupJID, reset)
+	if err != nil {
+		respondError(c, groupErrorStatus(err), err)
+		return
+	}
+
+	message := "Invite link retrieved"
+	if reset {
+		message = "Invite link reset"
+	}
+	utils.SuccessResponse(c, http.StatusOK, message, gin.H{
+		"group_jid":   groupJID,
+		"invite_link": invite.Link,
+		"invite_code": invite.Code,
+		"reset":       reset,
+	})
+}
+
+// GetGroupInfo returns a group's full metadata and participants
+func GetGroupInfo(c *gin.Context) {
+	groupJID := c.Param("group_jid")
+	deviceID := c.Query("device_id")
+
+	if deviceID == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "device_id query parameter is required")
+		return
+	}
+	if !isGroupJID(groupJID) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid group JID format. Should end with @g.us")
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	details, err := waService.GetGroupDetails(deviceID, groupJID)
+	if err != nil {
+		respondError(c, groupErrorStatus(err), err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Group info retrieved", details)
+}
+
+// JoinGroupRequest represents the request body for joining a group by invite
+type JoinGroupRequest struct {
+	DeviceID string `json:"device_id" binding:"required"`
+	// Invite is a chat.whatsapp.com link or just its code
+	Invite string `json:"invite" binding:"required"`
+}
+
+// JoinGroup joins a group with an invite link or code
+func JoinGroup(c *gin.Context) {
+	var req JoinGroupRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	jid, err := waService.JoinGroup(req.DeviceID, req.Invite)
+	if err != nil {
+		respondError(c, groupErrorStatus(err), err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Joined group successfully", gin.H{
+		"group_jid": jid.String(),
+	})
+}
+
+// GroupSubjectRequest represents the request body for renaming a group
+type GroupSubjectRequest struct {
+	DeviceID string `json:"device_id" binding:"required"`
+	Subject  string `json:"subject" binding:"required"`
+}
+
+// SetGroupSubject renames a group
+func SetGroupSubject(c *gin.Context) {
+	groupJID := c.Param("group_jid")
+
+	var req GroupSubjectRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if !isGroupJID(groupJID) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid group JID format. Should end with @g.us")
+		return
+	}
+	if utf8.RuneCountInString(req.Subject) > services.MaxGroupNameLength {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Subject exceeds the maximum length of %d characters", services.MaxGroupNameLength))
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	subject, err := waService.SetGroupSubject(req.DeviceID, groupJID, req.Subject)
+	if err != nil {
+		respondError(c, groupErrorStatus(err), err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Group subject updated", gin.H{
+		"group_jid": groupJID,
+		"subject":   subject,
+	})
+}
+
+// GroupDescriptionRequest represents the request body for changing a group's description
+type GroupDescriptionRequest struct {
+	DeviceID string `json:"device_id" binding:"required"`
+	// Description replaces the current one; empty clears it
+	Description string `json:"description"`
+}
+
+// SetGroupDescription changes a group's description
+func SetGroupDescription(c *gin.Context) {
+	groupJID := c.Param("group_jid")
+
+	var req GroupDescriptionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if !isGroupJID(groupJID) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid group JID format. Should end with @g.us")
+		return
+	}
+	if utf8.RuneCountInString(req.Description) > services.MaxGroupDescriptionLength {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Description exceeds the maximum length of %d characters", services.MaxGroupDescriptionLength))
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	description, err := waService.SetGroupDescription(req.DeviceID, groupJID, req.Description)
+	if err != nil {
+		respondError(c, groupErrorStatus(err), err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Group description updated", gin.H{
+		"group_jid":   groupJID,
+		"description": description,
+	})
+}
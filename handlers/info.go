@@ -1,11 +1,18 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 	"waku/services"
 	"waku/utils"
 
 	"github.com/gin-gonic/gin"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
 )
 
 // GetContacts retrieves the contact list for a device
@@ -15,7 +22,7 @@ func GetContacts(c *gin.Context) {
 	waService := services.GetWhatsAppService()
 	contacts, err := waService.GetContacts(deviceID)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
@@ -25,20 +32,261 @@ func GetContacts(c *gin.Context) {
 	})
 }
 
+// CheckNumbersRequest represents the request body for checking which numbers use WhatsApp
+type CheckNumbersRequest struct {
+	DeviceID    string   `json:"device_id" binding:"required"`
+	Phones      []string `json:"phones" binding:"required,min=1"`
+	BypassCache bool     `json:"bypass_cache"`
+}
+
+// CheckNumbers reports which phone numbers are registered on WhatsApp
+func CheckNumbers(c *gin.Context) {
+	var req CheckNumbersRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// Normalize and de-duplicate, rejecting malformed numbers up front
+	seen := make(map[string]bool, len(req.Phones))
+	phones := make([]string, 0, len(req.Phones))
+	var invalid []string
+	for _, raw := range req.Phones {
+		phone, ok := services.NormalizePhoneNumber(raw)
+		if !ok {
+			invalid = append(invalid, raw)
+			continue
+		}
+		if !seen[phone] {
+			seen[phone] = true
+			phones = append(phones, phone)
+		}
+	}
+	if len(invalid) > 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid phone numbers: "+strings.Join(invalid, ", "))
+		return
+	}
+
+	if len(phones) > services.MaxCheckNumbers {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("At most %d numbers can be checked at once", services.MaxCheckNumbers))
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	results, err := waService.CheckNumbers(req.DeviceID, phones, req.BypassCache)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Numbers checked", gin.H{
+		"total":   len(results),
+		"results": results,
+	})
+}
+
 // GetGroups retrieves the group list for a device
 func GetGroups(c *gin.Context) {
 	deviceID := c.Param("device_id")
 
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil || limit < 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "limit must be a non-negative integer")
+		return
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "offset must be a non-negative integer")
+		return
+	}
+	includeParticipants, _ := strconv.ParseBool(c.Query("include_participants"))
+
 	waService := services.GetWhatsAppService()
-	groups, err := waService.GetGroups(deviceID)
+	groups, err := waService.GetGroups(c.Request.Context(), deviceID, limit, offset, includeParticipants)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
 	utils.SuccessResponse(c, http.StatusOK, "Groups retrieved", gin.H{
-		"total":  len(groups),
-		"groups": groups,
+		"total":  groups.Total,
+		"count":  len(groups.Groups),
+		"limit":  limit,
+		"offset": offset,
+		"groups": groups.Groups,
 	})
 }
 
+
+// GetGroupIcon returns the profile picture URL of a group
+func GetGroupIcon(c *gin.Context) {
+	deviceID := c.Param("device_id")
+	groupJID := c.Param("group_jid")
+	preview, _ := strconv.ParseBool(c.Query("preview"))
+
+	// Validate group JID format
+	if !isGroupJID(groupJID) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid group JID format. Should end with @g.us")
+		return
+	}
+
+	jid, err := types.ParseJID(groupJID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid group JID: "+err.Error())
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	icon, err := waService.GetProfilePicture(deviceID, jid, preview)
+	if err != nil {
+		if errors.Is(err, whatsmeow.ErrProfilePictureUnauthorized) {
+			respondError(c, http.StatusForbidden, err)
+			return
+		}
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Group icon retrieved", icon)
+}
+
+// GetProfilePicture returns the profile picture of a contact or group. The
+// jid query parameter takes a full JID or a phone number; preview=true
+// returns the thumbnail instead of the full-resolution image.
+func GetProfilePicture(c *gin.Context) {
+	deviceID := c.Param("device_id")
+	raw := strings.TrimSpace(c.Query("jid"))
+	preview, _ := strconv.ParseBool(c.Query("preview"))
+
+	if raw == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "jid query parameter is required")
+		return
+	}
+
+	var jid types.JID
+	if strings.Contains(raw, "@") {
+		parsed, err := types.ParseJID(raw)
+		if err != nil {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Invalid JID: "+err.Error())
+			return
+		}
+		jid = parsed
+	} else {
+		jid = types.NewJID(strings.TrimPrefix(raw, "+"), types.DefaultUserServer)
+	}
+
+	waService := services.GetWhatsAppService()
+	picture, err := waService.GetProfilePicture(deviceID, jid, preview)
+	if err != nil {
+		switch {
+		case errors.Is(err, whatsmeow.ErrProfilePictureUnauthorized):
+			respondError(c, http.StatusForbidden, err)
+		case errors.Is(err, services.ErrSessionNotFound):
+			respondError(c, http.StatusNotFound, err)
+		case errors.Is(err, services.ErrSessionNotConnected):
+			respondError(c, http.StatusConflict, err)
+		default:
+			respondError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	if picture.URL == nil {
+		utils.ErrorResponseWithCode(c, http.StatusNotFound, utils.CodeProfilePictureNotSet, "No profile picture set for "+jid.String())
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Profile picture retrieved", picture)
+}
+
+// SyncContacts forces a fresh contact pull from WhatsApp and reports the
+// number of stored contacts
+func SyncContacts(c *gin.Context) {
+	deviceID := c.Param("device_id")
+
+	timeout, err := strconv.Atoi(c.DefaultQuery("timeout", "30"))
+	if err != nil || timeout <= 0 || timeout > 120 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "timeout must be between 1 and 120 seconds")
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	count, completed, err := waService.SyncContacts(deviceID, time.Duration(timeout)*time.Second)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrSessionNotFound):
+			respondError(c, http.StatusNotFound, err)
+		case errors.Is(err, services.ErrSessionNotConnected):
+			respondError(c, http.StatusConflict, err)
+		default:
+			respondError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	if !completed {
+		// Contacts that arrived before the deadline are already stored
+		utils.SuccessResponse(c, http.StatusAccepted, "Contact sync did not finish in time, returning partial results", gin.H{
+			"device_id": deviceID,
+			"status":    "partial",
+			"total":     count,
+		})
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Contacts synced", gin.H{
+		"device_id": deviceID,
+		"status":    "completed",
+		"total":     count,
+	})
+}
+
+// GetContactStatus returns a contact's about text and business profile
+func GetContactStatus(c *gin.Context) {
+	deviceID := c.Param("device_id")
+
+	phone, ok := services.NormalizePhoneNumber(c.Query("phone"))
+	if !ok {
+		utils.ErrorResponse(c, http.StatusBadRequest, "phone query parameter must be a phone number in international format")
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	status, err := waService.GetContactStatus(deviceID, phone)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotOnWhatsApp), errors.Is(err, services.ErrSessionNotFound):
+			respondError(c, http.StatusNotFound, err)
+		case errors.Is(err, services.ErrSessionNotConnected):
+			respondError(c, http.StatusConflict, err)
+		default:
+			respondError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Contact status retrieved", status)
+}
+
+// GetCapabilities describes the media rules and optional features of this server
+func GetCapabilities(c *gin.Context) {
+	mediaTypes := []utils.MediaType{utils.MediaTypeImage, utils.MediaTypeVideo, utils.MediaTypeAudio, utils.MediaTypeDocument}
+
+	media := make(gin.H, len(mediaTypes))
+	for _, mediaType := range mediaTypes {
+		extensions := utils.MediaExtensions[mediaType]
+		if extensions == nil {
+			// Documents accept any extension
+			extensions = []string{"*"}
+		}
+		media[string(mediaType)] = gin.H{
+			"extensions":     extensions,
+			"max_size_bytes": utils.MediaLimits[mediaType],
+		}
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Capabilities retrieved", gin.H{
+		"media":              media,
+		"max_message_length": services.MaxMessageLength,
+		"max_caption_length": services.MaxCaptionLength,
+		"features":           services.Features(),
+	})
+}
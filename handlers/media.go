@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"mime"
 	"net/http"
 	"os"
+	"strconv"
 	"waku/services"
 	"waku/utils"
 
@@ -14,12 +17,22 @@ func SendMediaMessage(c *gin.Context) {
 	deviceID := c.PostForm("device_id")
 	phone := c.PostForm("phone")
 	caption := c.PostForm("caption")
+	ptt, _ := strconv.ParseBool(c.PostForm("ptt"))
+	footer := formFooter(c)
+	priority, _ := strconv.ParseBool(c.PostForm("priority"))
+	verify, _ := strconv.ParseBool(c.PostForm("verify"))
+	expireSeconds, _ := strconv.Atoi(c.PostForm("expire_after_seconds"))
+	revokeAfterReadSeconds, _ := strconv.Atoi(c.PostForm("revoke_after_read_seconds"))
+	viewOnce, _ := strconv.ParseBool(c.PostForm("view_once"))
 
 	// Validate required fields
 	if deviceID == "" || phone == "" {
 		utils.ErrorResponse(c, http.StatusBadRequest, "device_id and phone are required")
 		return
 	}
+	if !allowDevice(c, deviceID) {
+		return
+	}
 
 	// Validate phone number format
 	if len(phone) < 10 {
@@ -27,6 +40,30 @@ func SendMediaMessage(c *gin.Context) {
 		return
 	}
 
+	opts := services.SendOptions{PTT: ptt, Footer: footer, Priority: priority, RequestID: utils.RequestID(c), ViewOnce: viewOnce}
+	if msg := timedOptions(&opts, expireSeconds, revokeAfterReadSeconds); msg != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := disappearingOption(&opts, formInt(c, "disappearing_seconds")); msg != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+
+	// Optionally correct the number to the JID WhatsApp actually uses
+	var verifiedJID *string
+	if verify {
+		jid, ok := verifyRecipient(c, waService, deviceID, phone)
+		if !ok {
+			return
+		}
+		phone = jid.User
+		verified := jid.String()
+		verifiedJID = &verified
+	}
+
 	// Get uploaded file
 	file, err := c.FormFile("file")
 	if err != nil {
@@ -36,7 +73,13 @@ func SendMediaMessage(c *gin.Context) {
 
 	// Validate file size
 	if err := utils.ValidateFileSize(file); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	// Documents can't be view-once
+	if viewOnce && utils.GetMediaType(file.Filename) == utils.MediaTypeDocument {
+		utils.ErrorResponse(c, http.StatusBadRequest, "view_once is only supported for image, video and audio files")
 		return
 	}
 
@@ -46,29 +89,46 @@ func SendMediaMessage(c *gin.Context) {
 		tempDir = "./temp"
 	}
 
+	// Fail early with a clear error when the temp disk is full
+	if err := utils.EnsureDiskSpace(tempDir, file.Size); err != nil {
+		respondError(c, http.StatusInsufficientStorage, err)
+		return
+	}
+
 	filePath, err := utils.SaveUploadedFile(file, tempDir)
+	if errors.Is(err, utils.ErrInsufficientStorage) {
+		respondError(c, http.StatusInsufficientStorage, err)
+		return
+	}
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to save file: "+err.Error())
 		return
 	}
 
 	// Send media message
-	waService := services.GetWhatsAppService()
-	messageID, mediaType, fileSize, err := waService.SendMediaMessage(deviceID, phone, filePath, caption)
+	messageID, mediaType, fileSize, err := waService.SendMediaMessage(deviceID, phone, filePath, caption, opts)
 
 	// Delete temp file after sending
 	defer utils.DeleteFile(filePath)
 
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, "Media sent successfully", gin.H{
+	data := gin.H{
 		"message_id": messageID,
 		"media_type": mediaType,
 		"file_size":  fileSize,
-	})
+		"view_once":  viewOnce,
+	}
+	if verifiedJID != nil {
+		data["jid"] = *verifiedJID
+	}
+	if warning := services.VoiceNoteWarning(file.Filename, ptt); warning != "" {
+		data["warning"] = warning
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Media sent successfully", data)
 }
 
 // SendGroupMediaMessage sends a media message to a group
@@ -76,12 +136,20 @@ func SendGroupMediaMessage(c *gin.Context) {
 	deviceID := c.PostForm("device_id")
 	groupJID := c.PostForm("group_jid")
 	caption := c.PostForm("caption")
+	ptt, _ := strconv.ParseBool(c.PostForm("ptt"))
+	footer := formFooter(c)
+	priority, _ := strconv.ParseBool(c.PostForm("priority"))
+	expireSeconds, _ := strconv.Atoi(c.PostForm("expire_after_seconds"))
+	revokeAfterReadSeconds, _ := strconv.Atoi(c.PostForm("revoke_after_read_seconds"))
 
 	// Validate required fields
 	if deviceID == "" || groupJID == "" {
 		utils.ErrorResponse(c, http.StatusBadRequest, "device_id and group_jid are required")
 		return
 	}
+	if !allowDevice(c, deviceID) {
+		return
+	}
 
 	// Validate group JID format
 	if len(groupJID) < 10 || groupJID[len(groupJID)-5:] != "@g.us" {
@@ -89,6 +157,16 @@ func SendGroupMediaMessage(c *gin.Context) {
 		return
 	}
 
+	opts := services.SendOptions{PTT: ptt, Footer: footer, Priority: priority, RequestID: utils.RequestID(c)}
+	if msg := timedOptions(&opts, expireSeconds, revokeAfterReadSeconds); msg != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := disappearingOption(&opts, formInt(c, "disappearing_seconds")); msg != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, msg)
+		return
+	}
+
 	// Get uploaded file
 	file, err := c.FormFile("file")
 	if err != nil {
@@ -98,7 +176,7 @@ func SendGroupMediaMessage(c *gin.Context) {
 
 	// Validate file size
 	if err := utils.ValidateFileSize(file); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
@@ -108,7 +186,17 @@ func SendGroupMediaMessage(c *gin.Context) {
 		tempDir = "./temp"
 	}
 
+	// Fail early with a clear error when the temp disk is full
+	if err := utils.EnsureDiskSpace(tempDir, file.Size); err != nil {
+		respondError(c, http.StatusInsufficientStorage, err)
+		return
+	}
+
 	filePath, err := utils.SaveUploadedFile(file, tempDir)
+	if errors.Is(err, utils.ErrInsufficientStorage) {
+		respondError(c, http.StatusInsufficientStorage, err)
+		return
+	}
 	if err != nil {
 		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to save file: "+err.Error())
 		return
@@ -116,19 +204,196 @@ func SendGroupMediaMessage(c *gin.Context) {
 
 	// Send media message
 	waService := services.GetWhatsAppService()
-	messageID, mediaType, fileSize, err := waService.SendGroupMediaMessage(deviceID, groupJID, filePath, caption)
+	messageID, mediaType, fileSize, err := waService.SendGroupMediaMessage(deviceID, groupJID, filePath, caption, opts)
 
 	// Delete temp file after sending
 	defer utils.DeleteFile(filePath)
 
+	if errors.Is(err, services.ErrNotGroupMember) {
+		respondError(c, http.StatusForbidden, err)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	data := gin.H{
+		"message_id": messageID,
+		"media_type": mediaType,
+		"file_size":  fileSize,
+	}
+	if warning := services.VoiceNoteWarning(file.Filename, ptt); warning != "" {
+		data["warning"] = warning
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Group media sent successfully", data)
+}
+
+// SendMediaBase64Request represents the request body for sending base64-encoded media
+type SendMediaBase64Request struct {
+	DeviceID string `json:"device_id" binding:"required"`
+	Phone    string `json:"phone"`
+	GroupJID string `json:"group_jid"`
+	Filename string `json:"filename" binding:"required"`
+	Caption  string `json:"caption"`
+	// Data is the file as base64, optionally as a data URI
+	Data     string  `json:"data" binding:"required"`
+	PTT      bool    `json:"ptt"`
+	Footer   *string `json:"footer"`
+	Priority bool    `json:"priority"`
+}
+
+// SendMediaBase64 sends media supplied as base64 in a JSON body to a contact or group
+func SendMediaBase64(c *gin.Context) {
+	// Oversized bodies are cut off while reading rather than buffered whole
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, utils.MaxBase64BodySize())
+
+	var req SendMediaBase64Request
+	if err := c.ShouldBindJSON(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.ErrorResponse(c, http.StatusRequestEntityTooLarge, "Request body exceeds the maximum media size")
+			return
+		}
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+		return
+	}
+	if !allowDevice(c, req.DeviceID) {
+		return
+	}
+
+	if msg := validateTarget(req.Phone, req.GroupJID); msg != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	filePath, err := utils.SaveBase64File(req.Filename, req.Data, utils.TempMediaDir())
+	if errors.Is(err, utils.ErrInsufficientStorage) {
+		respondError(c, http.StatusInsufficientStorage, err)
+		return
+	}
+	if errors.Is(err, utils.ErrInvalidBase64Upload) {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusInternalServerError, "Failed to save file: "+err.Error())
+		return
+	}
+	defer utils.DeleteFile(filePath)
+
+	opts := services.SendOptions{PTT: req.PTT, Footer: req.Footer, Priority: req.Priority, RequestID: utils.RequestID(c)}
+	waService := services.GetWhatsAppService()
+
+	var messageID, mediaType string
+	var fileSize int64
+	if req.GroupJID != "" {
+		messageID, mediaType, fileSize, err = waService.SendGroupMediaMessage(req.DeviceID, req.GroupJID, filePath, req.Caption, opts)
+	} else {
+		messageID, mediaType, fileSize, err = waService.SendMediaMessage(req.DeviceID, req.Phone, filePath, req.Caption, opts)
+	}
+	if errors.Is(err, services.ErrNotGroupMember) {
+		respondError(c, http.StatusForbidden, err)
+		return
+	}
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, "Group media sent successfully", gin.H{
+	data := gin.H{
 		"message_id": messageID,
 		"media_type": mediaType,
 		"file_size":  fileSize,
+	}
+	if warning := services.VoiceNoteWarning(req.Filename, req.PTT); warning != "" {
+		data["warning"] = warning
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Media sent successfully", data)
+}
+
+// formFooter returns the per-request footer override, or nil when the field is absent
+func formFooter(c *gin.Context) *string {
+	if footer, ok := c.GetPostForm("footer"); ok {
+		return &footer
+	}
+	return nil
+}
+
+// DownloadMedia returns the decrypted attachment of a received message
+func DownloadMedia(c *gin.Context) {
+	deviceID := c.Param("device_id")
+	messageID := c.Param("message_id")
+
+	waService := services.GetWhatsAppService()
+	media, err := waService.DownloadMedia(deviceID, messageID)
+	if errors.Is(err, services.ErrMediaNotFound) || errors.Is(err, services.ErrSessionNotFound) {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	if media.FileName != "" {
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": media.FileName}))
+	}
+	c.Data(http.StatusOK, media.Mimetype, media.Data)
+}
+
+// ServeDownload serves media saved for webhooks, only to tokens allowed on the device that received it
+func ServeDownload(c *gin.Context) {
+	path, err := services.DownloadFilePath(c.Param("device_id"), c.Param("name"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+	c.File(path)
+}
+
+// formInt returns an optional integer form field, or nil when it is absent or not a number
+func formInt(c *gin.Context, name string) *int {
+	raw, ok := c.GetPostForm(name)
+	if !ok {
+		return nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+// GetMediaStatus reports whether a media reference can still be downloaded
+func GetMediaStatus(c *gin.Context) {
+	deviceID := c.Param("device_id")
+	mediaURL := c.Query("url")
+	directPath := c.Query("direct_path")
+
+	if mediaURL == "" && directPath == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "url or direct_path is required")
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	status, statusCode, err := waService.CheckMediaAvailability(deviceID, mediaURL, directPath)
+	if errors.Is(err, services.ErrSessionNotFound) {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+	if errors.Is(err, services.ErrInvalidMediaURL) {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusBadGateway, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Media status retrieved", gin.H{
+		"status":      status,
+		"available":   status == services.MediaAvailable,
+		"http_status": statusCode,
 	})
 }
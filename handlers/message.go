@@ -1,32 +1,69 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 	"waku/services"
 	"waku/utils"
 
 	"github.com/gin-gonic/gin"
+	"go.mau.fi/whatsmeow/types"
 )
 
 // SendMessageRequest represents the request body for sending a message
 type SendMessageRequest struct {
-	DeviceID string `json:"device_id" binding:"required"`
-	Phone    string `json:"phone" binding:"required"`
-	Message  string `json:"message" binding:"required"`
+	DeviceID string  `json:"device_id" binding:"required"`
+	Phone    string  `json:"phone" binding:"required"`
+	Message  string  `json:"message" binding:"required"`
+	Footer   *string `json:"footer"`
+	Priority bool    `json:"priority"`
+	// Verify resolves the phone through WhatsApp and sends to the canonical JID
+	Verify bool `json:"verify"`
+	// ExpireAfterSeconds revokes the message if it isn't delivered in time; 0 disables
+	ExpireAfterSeconds int `json:"expire_after_seconds"`
+	// RevokeAfterReadSeconds revokes the message this long after it is read; 0 disables
+	RevokeAfterReadSeconds int `json:"revoke_after_read_seconds"`
+	// QuotedMessageID sends the message as a reply to this message
+	QuotedMessageID string `json:"quoted_message_id"`
+	// QuotedParticipant is the sender of the quoted message (phone or JID)
+	QuotedParticipant string `json:"quoted_participant"`
+	// DisappearingSeconds overrides the chat's disappearing timer; 0 sends a normal message
+	DisappearingSeconds *int `json:"disappearing_seconds"`
+	// GeneratePreview attaches a rich preview of the first link in the message
+	GeneratePreview bool `json:"generate_preview"`
 }
 
 // SendGroupMessageRequest represents the request body for sending a group message
 type SendGroupMessageRequest struct {
-	DeviceID string `json:"device_id" binding:"required"`
-	GroupJID string `json:"group_jid" binding:"required"`
-	Message  string `json:"message" binding:"required"`
+	DeviceID string  `json:"device_id" binding:"required"`
+	GroupJID string  `json:"group_jid" binding:"required"`
+	Message  string  `json:"message" binding:"required"`
+	Footer   *string `json:"footer"`
+	Priority bool    `json:"priority"`
+	// ExpireAfterSeconds revokes the message if it isn't delivered in time; 0 disables
+	ExpireAfterSeconds int `json:"expire_after_seconds"`
+	// RevokeAfterReadSeconds revokes the message this long after it is read; 0 disables
+	RevokeAfterReadSeconds int `json:"revoke_after_read_seconds"`
+	// QuotedMessageID sends the message as a reply to this message
+	QuotedMessageID string `json:"quoted_message_id"`
+	// QuotedParticipant is the group member who sent the quoted message (phone or JID)
+	QuotedParticipant string `json:"quoted_participant"`
+	// DisappearingSeconds overrides the group's disappearing timer; 0 sends a normal message
+	DisappearingSeconds *int `json:"disappearing_seconds"`
+	// Mentions are the phone numbers of members to notify; each needs an @number token in the message
+	Mentions []string `json:"mentions"`
+	// GeneratePreview attaches a rich preview of the first link in the message
+	GeneratePreview bool `json:"generate_preview"`
 }
 
 // SendMessage sends a personal message
 func SendMessage(c *gin.Context) {
 	var req SendMessageRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -36,24 +73,70 @@ func SendMessage(c *gin.Context) {
 		return
 	}
 
+	opts := services.SendOptions{Footer: req.Footer, Priority: req.Priority, RequestID: utils.RequestID(c)}
+	if msg := timedOptions(&opts, req.ExpireAfterSeconds, req.RevokeAfterReadSeconds); msg != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := quoteOption(&opts, req.QuotedMessageID, req.QuotedParticipant, false); msg != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := disappearingOption(&opts, req.DisappearingSeconds); msg != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, msg)
+		return
+	}
+
 	waService := services.GetWhatsAppService()
-	messageID, timestamp, err := waService.SendMessage(req.DeviceID, req.Phone, req.Message)
+
+	// Optionally correct the number to the JID WhatsApp actually uses
+	phone := req.Phone
+	var verifiedJID *string
+	if req.Verify {
+		jid, ok := verifyRecipient(c, waService, req.DeviceID, phone)
+		if !ok {
+			return
+		}
+		phone = jid.User
+		verified := jid.String()
+		verifiedJID = &verified
+	}
+
+	opts.LinkPreview = waService.LinkPreviewFor(req.Message, req.GeneratePreview)
+	messageID, timestamp, err := waService.SendMessage(req.DeviceID, phone, req.Message, opts)
+	if errors.Is(err, services.ErrInvalidQuote) {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	var pendingErr *services.SessionPendingError
+	if errors.As(err, &pendingErr) {
+		utils.ErrorResponseWithData(c, http.StatusConflict, utils.CodeSessionPending, pendingErr.Error(), gin.H{
+			"device_id": pendingErr.DeviceID,
+			"qr_url":    pendingErr.QRURL,
+			"status":    "waiting_for_qr_scan",
+		})
+		return
+	}
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
-	utils.SuccessResponse(c, http.StatusOK, "Message sent successfully", gin.H{
-		"message_id": messageID,
-		"timestamp":  timestamp,
-	})
+	data := gin.H{
+		"message_id":   messageID,
+		"timestamp":    timestamp,
+		"link_preview": opts.LinkPreview != nil,
+	}
+	if verifiedJID != nil {
+		data["jid"] = *verifiedJID
+	}
+	utils.SuccessResponse(c, http.StatusOK, "Message sent successfully", data)
 }
 
 // SendGroupMessage sends a group message
 func SendGroupMessage(c *gin.Context) {
 	var req SendGroupMessageRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -63,16 +146,705 @@ func SendGroupMessage(c *gin.Context) {
 		return
 	}
 
+	opts := services.SendOptions{Footer: req.Footer, Priority: req.Priority, RequestID: utils.RequestID(c)}
+	if msg := timedOptions(&opts, req.ExpireAfterSeconds, req.RevokeAfterReadSeconds); msg != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := quoteOption(&opts, req.QuotedMessageID, req.QuotedParticipant, true); msg != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := disappearingOption(&opts, req.DisappearingSeconds); msg != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, msg)
+		return
+	}
+	if msg := mentionsOption(&opts, req.Message, req.Mentions); msg != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, msg)
+		return
+	}
+
 	waService := services.GetWhatsAppService()
-	messageID, timestamp, err := waService.SendGroupMessage(req.DeviceID, req.GroupJID, req.Message)
+	opts.LinkPreview = waService.LinkPreviewFor(req.Message, req.GeneratePreview)
+	messageID, timestamp, err := waService.SendGroupMessage(req.DeviceID, req.GroupJID, req.Message, opts)
+	if errors.Is(err, services.ErrInvalidQuote) {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, services.ErrNotGroupMember) {
+		respondError(c, http.StatusForbidden, err)
+		return
+	}
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusInternalServerError, err.Error())
+		respondError(c, http.StatusInternalServerError, err)
 		return
 	}
 
+	mentioned := make([]string, 0, len(opts.Mentions))
+	for _, jid := range opts.Mentions {
+		mentioned = append(mentioned, jid.String())
+	}
+
 	utils.SuccessResponse(c, http.StatusOK, "Group message sent successfully", gin.H{
+		"message_id":     messageID,
+		"timestamp":      timestamp,
+		"mentioned_jids": mentioned,
+		"link_preview":   opts.LinkPreview != nil,
+	})
+}
+
+
+// validateTarget checks that exactly one well-formed recipient was supplied
+// and returns a user-facing error message otherwise
+func validateTarget(phone, groupJID string) string {
+	if (phone == "") == (groupJID == "") {
+		return "Either phone or group_jid is required"
+	}
+	if phone != "" && len(phone) < 10 {
+		return "Invalid phone number format. Use: country_code + number (e.g., 628123456789)"
+	}
+	if groupJID != "" && !isGroupJID(groupJID) {
+		return "Invalid group JID format. Should end with @g.us"
+	}
+	return ""
+}
+
+// verifyRecipient resolves a phone to its canonical JID, writing the error
+// response and returning false when it can't
+func verifyRecipient(c *gin.Context, waService *services.WhatsAppService, deviceID, phone string) (types.JID, bool) {
+	jid, err := waService.VerifyRecipient(deviceID, phone)
+	if errors.Is(err, services.ErrNotOnWhatsApp) {
+		respondError(c, http.StatusUnprocessableEntity, err)
+		return jid, false
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return jid, false
+	}
+	return jid, true
+}
+
+// timedOptions fills the expiry and revoke-after-read options from their
+// request fields and returns a user-facing error message when out of range
+func timedOptions(opts *services.SendOptions, expireSeconds, revokeAfterReadSeconds int) string {
+	maxSeconds := int(services.MaxExpireAfter.Seconds())
+	if expireSeconds < 0 || expireSeconds > maxSeconds {
+		return fmt.Sprintf("expire_after_seconds must be between 0 and %d", maxSeconds)
+	}
+	if revokeAfterReadSeconds < 0 || revokeAfterReadSeconds > maxSeconds {
+		return fmt.Sprintf("revoke_after_read_seconds must be between 0 and %d", maxSeconds)
+	}
+
+	opts.ExpireAfter = time.Duration(expireSeconds) * time.Second
+	opts.RevokeAfterRead = time.Duration(revokeAfterReadSeconds) * time.Second
+	return ""
+}
+
+// quoteOption fills the reply-to option from its request fields and returns a
+// user-facing error message when they are malformed
+func quoteOption(opts *services.SendOptions, messageID, participant string, group bool) string {
+	if messageID == "" {
+		if participant != "" {
+			return "quoted_participant requires quoted_message_id"
+		}
+		return ""
+	}
+	if strings.ContainsAny(messageID, " @") {
+		return "Invalid quoted_message_id format"
+	}
+	if group && participant == "" {
+		return "quoted_participant is required when replying in a group"
+	}
+
+	opts.Quote = &services.QuotedMessage{ID: messageID, Participant: participant}
+	return ""
+}
+
+// disappearingOption fills the disappearing timer override and returns a
+// user-facing error message when it is out of range
+func disappearingOption(opts *services.SendOptions, seconds *int) string {
+	if seconds == nil {
+		return ""
+	}
+	if *seconds < 0 || *seconds > services.MaxDisappearingSeconds {
+		return fmt.Sprintf("disappearing_seconds must be between 0 and %d", services.MaxDisappearingSeconds)
+	}
+
+	timer := uint32(*seconds)
+	opts.Disappearing = &timer
+	return ""
+}
+
+// mentionsOption resolves mentioned phone numbers to JIDs and returns a
+// user-facing error message when one is malformed or missing from the text
+func mentionsOption(opts *services.SendOptions, message string, mentions []string) string {
+	for _, phone := range mentions {
+		phone = strings.TrimPrefix(strings.TrimSpace(phone), "+")
+		if len(phone) < 10 || len(phone) > 15 || strings.Trim(phone, "0123456789") != "" {
+			return fmt.Sprintf("Invalid mention %q. Use: country_code + number (e.g., 628123456789)", phone)
+		}
+		if !strings.Contains(message, "@"+phone) {
+			return fmt.Sprintf("message must contain @%s for each mentioned number", phone)
+		}
+		opts.Mentions = append(opts.Mentions, types.NewJID(phone, types.DefaultUserServer))
+	}
+	return ""
+}
+
+// isGroupJID checks that a JID looks like a group JID
+func isGroupJID(jid string) bool {
+	return len(jid) >= 10 && strings.HasSuffix(jid, "@g.us")
+}
+
+// SendCTARequest represents the request body for sending a call-to-action button message
+type SendCTARequest struct {
+	DeviceID    string  `json:"device_id" binding:"required"`
+	Phone       string  `json:"phone"`
+	GroupJID    string  `json:"group_jid"`
+	Message     string  `json:"message" binding:"required"`
+	ButtonLabel string  `json:"button_label" binding:"required"`
+	URL         string  `json:"url"`
+	CallNumber  string  `json:"call_number"`
+	Footer      *string `json:"footer"`
+	Priority    bool    `json:"priority"`
+}
+
+// SendCTA sends an interactive message with a URL or call button
+func SendCTA(c *gin.Context) {
+	var req SendCTARequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if msg := validateTarget(req.Phone, req.GroupJID); msg != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	if (req.URL == "") == (req.CallNumber == "") {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Either url or call_number is required")
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	messageID, timestamp, err := waService.SendCTAMessage(req.DeviceID, req.Phone, req.GroupJID, req.Message, services.CTAButton{
+		Label:       req.ButtonLabel,
+		URL:         req.URL,
+		PhoneNumber: req.CallNumber,
+	}, services.SendOptions{Footer: req.Footer, Priority: req.Priority, RequestID: utils.RequestID(c)})
+	if errors.Is(err, services.ErrInvalidCTAButton) {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, services.ErrNotGroupMember) {
+		respondError(c, http.StatusForbidden, err)
+		return
+	}
+	if errors.Is(err, services.ErrMessageRejected) {
+		respondError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "CTA message sent successfully", gin.H{
 		"message_id": messageID,
 		"timestamp":  timestamp,
 	})
 }
 
+// EditMessageRequest represents the request body for editing a sent message
+type EditMessageRequest struct {
+	DeviceID  string `json:"device_id" binding:"required"`
+	Phone     string `json:"phone"`
+	GroupJID  string `json:"group_jid"`
+	MessageID string `json:"message_id" binding:"required"`
+	Message   string `json:"message" binding:"required"`
+}
+
+// EditMessage replaces the text of a previously sent message
+func EditMessage(c *gin.Context) {
+	var req EditMessageRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if msg := validateTarget(req.Phone, req.GroupJID); msg != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	messageID, timestamp, err := waService.EditMessage(req.DeviceID, req.Phone, req.GroupJID, req.MessageID, req.Message)
+	if errors.Is(err, services.ErrEditWindowExpired) || errors.Is(err, services.ErrMessageRejected) {
+		respondError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+	if errors.Is(err, services.ErrNotGroupMember) {
+		respondError(c, http.StatusForbidden, err)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Message edited successfully", gin.H{
+		"message_id":        messageID,
+		"edited_message_id": req.MessageID,
+		"timestamp":         timestamp,
+	})
+}
+
+// RevokeMessageRequest represents the request body for deleting a message for everyone
+type RevokeMessageRequest struct {
+	DeviceID  string `json:"device_id" binding:"required"`
+	Phone     string `json:"phone"`
+	GroupJID  string `json:"group_jid"`
+	MessageID string `json:"message_id" binding:"required"`
+	// Sender is the group member who sent the message (phone or JID); empty for own messages
+	Sender string `json:"sender"`
+}
+
+// RevokeMessage deletes a message for everyone in the chat
+func RevokeMessage(c *gin.Context) {
+	var req RevokeMessageRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if msg := validateTarget(req.Phone, req.GroupJID); msg != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	revokeID, err := waService.RevokeMessage(req.DeviceID, req.Phone, req.GroupJID, req.MessageID, req.Sender)
+	if errors.Is(err, services.ErrMessageNotFound) {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+	if errors.Is(err, services.ErrRevokeNotPermitted) {
+		respondError(c, http.StatusForbidden, err)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Message revoked successfully", gin.H{
+		"message_id":         revokeID,
+		"revoked_message_id": req.MessageID,
+	})
+}
+
+// SendLocationRequestRequest represents the request body for asking a contact to share their location
+type SendLocationRequestRequest struct {
+	DeviceID string  `json:"device_id" binding:"required"`
+	Phone    string  `json:"phone" binding:"required"`
+	Message  string  `json:"message" binding:"required"`
+	Footer   *string `json:"footer"`
+	Priority bool    `json:"priority"`
+}
+
+// SendLocationRequest sends a "share your location" request to a contact
+func SendLocationRequest(c *gin.Context) {
+	var req SendLocationRequestRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if isGroupJID(req.Phone) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Location requests can only be sent to contacts")
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	messageID, timestamp, err := waService.SendLocationRequest(req.DeviceID, req.Phone, req.Message, services.SendOptions{Footer: req.Footer, Priority: req.Priority, RequestID: utils.RequestID(c)})
+	if errors.Is(err, services.ErrMessageRejected) {
+		respondError(c, http.StatusUnprocessableEntity, err)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Location request sent successfully", gin.H{
+		"message_id": messageID,
+		"timestamp":  timestamp,
+	})
+}
+
+// SendLocationMessageRequest represents the request body for sharing a location
+type SendLocationMessageRequest struct {
+	DeviceID  string   `json:"device_id" binding:"required"`
+	Phone     string   `json:"phone"`
+	GroupJID  string   `json:"group_jid"`
+	Latitude  *float64 `json:"latitude" binding:"required"`
+	Longitude *float64 `json:"longitude" binding:"required"`
+	Name      string   `json:"name"`
+	Address   string   `json:"address"`
+	Priority  bool     `json:"priority"`
+}
+
+// SendLocationMessage shares GPS coordinates with a contact or group
+func SendLocationMessage(c *gin.Context) {
+	var req SendLocationMessageRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if msg := validateTarget(req.Phone, req.GroupJID); msg != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, msg)
+		return
+	}
+	if *req.Latitude < -90 || *req.Latitude > 90 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "latitude must be between -90 and 90")
+		return
+	}
+	if *req.Longitude < -180 || *req.Longitude > 180 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "longitude must be between -180 and 180")
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	messageID, timestamp, err := waService.SendLocationMessage(req.DeviceID, req.Phone, req.GroupJID, services.Location{
+		Latitude:  *req.Latitude,
+		Longitude: *req.Longitude,
+		Name:      req.Name,
+		Address:   req.Address,
+	}, services.SendOptions{Priority: req.Priority, RequestID: utils.RequestID(c)})
+	if errors.Is(err, services.ErrNotGroupMember) {
+		respondError(c, http.StatusForbidden, err)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Location sent successfully", gin.H{
+		"message_id": messageID,
+		"timestamp":  timestamp,
+	})
+}
+
+// ContactCardRequest is a contact card to share
+type ContactCardRequest struct {
+	Name  string `json:"name" binding:"required"`
+	Phone string `json:"phone"`
+	// VCard is sent as-is instead of a card generated from name and phone
+	VCard string `json:"vcard"`
+}
+
+// SendContactRequest represents the request body for sharing contact cards
+type SendContactRequest struct {
+	DeviceID string              `json:"device_id" binding:"required"`
+	Phone    string              `json:"phone"`
+	GroupJID string              `json:"group_jid"`
+	Contact  *ContactCardRequest `json:"contact"`
+	// Contacts sends several cards in one message, instead of contact
+	Contacts []ContactCardRequest `json:"contacts" binding:"dive"`
+	Priority bool                 `json:"priority"`
+}
+
+// SendContact shares one or more contact cards with a contact or group
+func SendContact(c *gin.Context) {
+	var req SendContactRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if msg := validateTarget(req.Phone, req.GroupJID); msg != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, msg)
+		return
+	}
+	if (req.Contact == nil) == (len(req.Contacts) == 0) {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Either contact or contacts is required")
+		return
+	}
+
+	cards := req.Contacts
+	if req.Contact != nil {
+		cards = []ContactCardRequest{*req.Contact}
+	}
+	contacts := make([]services.SharedContact, 0, len(cards))
+	for _, card := range cards {
+		if card.Phone == "" && card.VCard == "" {
+			utils.ErrorResponse(c, http.StatusBadRequest, "Each contact needs a phone or vcard")
+			return
+		}
+		contacts = append(contacts, services.SharedContact{Name: card.Name, Phone: card.Phone, VCard: card.VCard})
+	}
+
+	waService := services.GetWhatsAppService()
+	messageID, timestamp, err := waService.SendContactMessage(req.DeviceID, req.Phone, req.GroupJID, contacts, services.SendOptions{Priority: req.Priority, RequestID: utils.RequestID(c)})
+	if errors.Is(err, services.ErrInvalidContact) {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, services.ErrNotGroupMember) {
+		respondError(c, http.StatusForbidden, err)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Contact sent successfully", gin.H{
+		"message_id": messageID,
+		"timestamp":  timestamp,
+	})
+}
+
+// SendPollRequest represents the request body for sending a poll
+type SendPollRequest struct {
+	DeviceID string   `json:"device_id" binding:"required"`
+	Phone    string   `json:"phone"`
+	GroupJID string   `json:"group_jid"`
+	Question string   `json:"question" binding:"required"`
+	Options  []string `json:"options" binding:"required"`
+	// SelectableCount is how many options a voter may pick; 0 allows any number
+	SelectableCount int  `json:"selectable_count"`
+	Priority        bool `json:"priority"`
+}
+
+// SendPoll sends a poll to a contact or group
+func SendPoll(c *gin.Context) {
+	var req SendPollRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if msg := validateTarget(req.Phone, req.GroupJID); msg != "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, msg)
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	messageID, timestamp, err := waService.SendPoll(req.DeviceID, req.Phone, req.GroupJID, req.Question, req.Options, req.SelectableCount, services.SendOptions{Priority: req.Priority, RequestID: utils.RequestID(c)})
+	if errors.Is(err, services.ErrInvalidPoll) {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, services.ErrNotGroupMember) {
+		respondError(c, http.StatusForbidden, err)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Poll sent successfully", gin.H{
+		"message_id": messageID,
+		"timestamp":  timestamp,
+	})
+}
+
+// BulkRecipientRequest is one recipient of a bulk send
+type BulkRecipientRequest struct {
+	Phone string `json:"phone" binding:"required"`
+	// Message overrides the request-wide message for this recipient
+	Message string `json:"message"`
+}
+
+// SendBulkRequest represents the request body for sending a message to many recipients
+type SendBulkRequest struct {
+	DeviceID   string                 `json:"device_id" binding:"required"`
+	Message    string                 `json:"message"`
+	Recipients []BulkRecipientRequest `json:"recipients" binding:"dive"`
+	// Phones are recipients of the request-wide message, alongside Recipients
+	Phones []string `json:"phones"`
+	Footer *string  `json:"footer"`
+	// Concurrency is the number of parallel senders, up to BULK_MAX_CONCURRENCY
+	Concurrency *int `json:"concurrency"`
+	// DelayMs is the pause each sender takes between messages, within BULK_MIN/MAX_DELAY_MS
+	DelayMs *int `json:"delay_ms"`
+	// Async runs the send as a background job; by default only lists over BULK_SYNC_LIMIT do
+	Async *bool `json:"async"`
+}
+
+// SendBulk sends a message to many recipients with caller-tuned pacing
+func SendBulk(c *gin.Context) {
+	var req SendBulkRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	for _, phone := range req.Phones {
+		req.Recipients = append(req.Recipients, BulkRecipientRequest{Phone: phone})
+	}
+	if len(req.Recipients) == 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "recipients or phones is required")
+		return
+	}
+
+	limits := services.GetBulkLimits()
+	if len(req.Recipients) > limits.MaxRecipients {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("At most %d recipients are allowed per request", limits.MaxRecipients))
+		return
+	}
+
+	concurrency := 1
+	if req.Concurrency != nil {
+		concurrency = *req.Concurrency
+	}
+	if concurrency < 1 || concurrency > limits.MaxConcurrency {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("concurrency must be between 1 and %d", limits.MaxConcurrency))
+		return
+	}
+
+	delayMs := limits.MinDelayMs
+	if req.DelayMs != nil {
+		delayMs = *req.DelayMs
+	}
+	if delayMs < limits.MinDelayMs || delayMs > limits.MaxDelayMs {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("delay_ms must be between %d and %d", limits.MinDelayMs, limits.MaxDelayMs))
+		return
+	}
+
+	recipients := make([]services.BulkRecipient, len(req.Recipients))
+	for i, r := range req.Recipients {
+		message := r.Message
+		if message == "" {
+			message = req.Message
+		}
+		if message == "" {
+			utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("recipients[%d]: message is required", i))
+			return
+		}
+		if len(r.Phone) < 10 {
+			utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("recipients[%d]: invalid phone number format", i))
+			return
+		}
+		recipients[i] = services.BulkRecipient{Phone: r.Phone, Message: message}
+	}
+
+	waService := services.GetWhatsAppService()
+	if _, err := waService.GetSession(req.DeviceID); err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	opts := services.BulkOptions{
+		Concurrency: concurrency,
+		Delay:       time.Duration(delayMs) * time.Millisecond,
+		Send:        services.SendOptions{Footer: req.Footer, RequestID: utils.RequestID(c)},
+		Wait:        utils.RateLimitWait(c),
+	}
+
+	async := len(recipients) > services.BulkSyncLimit()
+	if req.Async != nil {
+		async = *req.Async
+	}
+	if async {
+		job := waService.StartBulkJob(req.DeviceID, recipients, opts)
+		status := job.Status()
+		utils.SuccessResponse(c, http.StatusAccepted, "Bulk send started", gin.H{
+			"job_id":      status.ID,
+			"status":      status.Status,
+			"total":       status.Total,
+			"concurrency": concurrency,
+			"delay_ms":    delayMs,
+			"status_url":  "/job/" + status.ID,
+		})
+		return
+	}
+
+	results := waService.SendBulk(req.DeviceID, recipients, opts)
+
+	sent := 0
+	for _, result := range results {
+		if result.Error == "" {
+			sent++
+		}
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Bulk send completed", gin.H{
+		"total":       len(results),
+		"sent":        sent,
+		"failed":      len(results) - sent,
+		"concurrency": concurrency,
+		"delay_ms":    delayMs,
+		"results":     results,
+	})
+}
+
+// GetJob reports the progress of a background bulk send
+func GetJob(c *gin.Context) {
+	waService := services.GetWhatsAppService()
+	job, ok := waService.GetJob(c.Param("id"))
+	// Jobs of devices outside the token's scope are reported as missing
+	if !ok || !utils.Scope(c).Allows(job.DeviceID()) {
+		utils.ErrorResponse(c, http.StatusNotFound, "Job not found or expired")
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Job status retrieved", job.Status())
+}
+
+// GetMessageStatus reports the delivery state of a sent message
+func GetMessageStatus(c *gin.Context) {
+	waService := services.GetWhatsAppService()
+	sent, err := waService.GetMessageStatus(c.Param("device_id"), c.Param("message_id"))
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Message status retrieved", sent)
+}
+
+// GetMessages lists a device's stored messages, newest first, optionally
+// filtered by chat and by a from/to range (Unix seconds or RFC 3339)
+func GetMessages(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 500 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "limit must be between 1 and 500")
+		return
+	}
+
+	query := services.MessageQuery{ChatJID: c.Query("chat"), Limit: limit}
+	if query.Since, err = parseTimeParam(c.Query("from")); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "from must be a Unix timestamp or RFC 3339 time")
+		return
+	}
+	if query.Until, err = parseTimeParam(c.Query("to")); err != nil {
+		utils.ErrorResponse(c, http.StatusBadRequest, "to must be a Unix timestamp or RFC 3339 time")
+		return
+	}
+
+	deviceID := c.Param("device_id")
+	messages, err := services.GetWhatsAppService().QueryMessages(deviceID, query)
+	if errors.Is(err, services.ErrStoreDisabled) {
+		respondError(c, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusBadRequest, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Messages retrieved", gin.H{
+		"device_id": deviceID,
+		"count":     len(messages),
+		"messages":  messages,
+	})
+}
+
+// parseTimeParam reads a Unix timestamp or RFC 3339 time; empty means unbounded
+func parseTimeParam(raw string) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 	"waku/services"
 	"waku/utils"
 
@@ -14,35 +18,155 @@ import (
 // CreateSessionRequest represents the request body for creating a session
 type CreateSessionRequest struct {
 	DeviceID string `json:"device_id" binding:"required"`
+	// PairingMethod is "qr" (default) or "code" to pair with POST /session/:device_id/pair-code
+	PairingMethod string `json:"pairing_method"`
+	// Name is a human-readable label shown in session lists
+	Name string `json:"name"`
 }
 
 // CreateSession creates a new WhatsApp session
 func CreateSession(c *gin.Context) {
 	var req CreateSessionRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid request: "+err.Error())
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if req.PairingMethod != "" && req.PairingMethod != "qr" && req.PairingMethod != "code" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "pairing_method must be qr or code")
+		return
+	}
+	pairWithCode := req.PairingMethod == "code"
+
+	if utf8.RuneCountInString(req.Name) > services.MaxDisplayNameLength {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("name exceeds the maximum length of %d characters", services.MaxDisplayNameLength))
 		return
 	}
 
 	waService := services.GetWhatsAppService()
 
 	// Create session
-	deviceClient, err := waService.CreateSession(req.DeviceID)
+	_, err := waService.CreateSession(req.DeviceID, services.SessionOptions{PairWithCode: pairWithCode, Name: req.Name})
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusBadRequest, err.Error())
+		respondError(c, http.StatusBadRequest, err)
 		return
 	}
 
-	// Setup webhook event handler
-	services.SetupEventHandler(req.DeviceID, deviceClient)
+	if pairWithCode {
+		utils.SuccessResponse(c, http.StatusOK, "Session created successfully", gin.H{
+			"device_id":     req.DeviceID,
+			"name":          req.Name,
+			"pair_code_url": fmt.Sprintf("/session/%s/pair-code", req.DeviceID),
+			"status":        "waiting_for_pair_code",
+		})
+		return
+	}
 
 	utils.SuccessResponse(c, http.StatusOK, "Session created successfully", gin.H{
 		"device_id": req.DeviceID,
+		"name":      req.Name,
 		"qr_url":    fmt.Sprintf("/qr/%s", req.DeviceID),
 		"status":    "waiting_for_qr_scan",
 	})
 }
 
+// PairCodeRequest represents the request body for pairing a session by phone number
+type PairCodeRequest struct {
+	Phone string `json:"phone" binding:"required"`
+}
+
+// PairWithCode requests a pairing code to link a session without scanning a QR code
+func PairWithCode(c *gin.Context) {
+	deviceID := c.Param("device_id")
+
+	var req PairCodeRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if len(req.Phone) < 10 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Invalid phone number format. Use: country_code + number (e.g., 628123456789)")
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	code, err := waService.PairWithCode(deviceID, req.Phone)
+	if errors.Is(err, services.ErrSessionNotFound) {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+	if errors.Is(err, services.ErrAlreadyPaired) {
+		respondError(c, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Pairing code generated", gin.H{
+		"device_id": deviceID,
+		"code":      code,
+		"status":    "waiting_for_pair_code",
+	})
+}
+
+// RepairPairingRequest represents the optional request body for re-pairing a session
+type RepairPairingRequest struct {
+	// PairingMethod is "qr" (default) or "code"
+	PairingMethod string `json:"pairing_method"`
+}
+
+// RepairPairing restarts pairing for a logged-out session without deleting its settings
+func RepairPairing(c *gin.Context) {
+	deviceID := c.Param("device_id")
+
+	var req RepairPairingRequest
+	if c.Request.ContentLength > 0 {
+		if !bindJSON(c, &req) {
+			return
+		}
+	}
+
+	if req.PairingMethod != "" && req.PairingMethod != "qr" && req.PairingMethod != "code" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "pairing_method must be qr or code")
+		return
+	}
+	pairWithCode := req.PairingMethod == "code"
+
+	waService := services.GetWhatsAppService()
+	_, err := waService.RepairPairing(deviceID, services.SessionOptions{PairWithCode: pairWithCode})
+	if errors.Is(err, services.ErrSessionNotFound) {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+	if errors.Is(err, services.ErrAlreadyPaired) {
+		respondError(c, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, err)
+		return
+	}
+
+	if pairWithCode {
+		utils.SuccessResponse(c, http.StatusOK, "Pairing reset", gin.H{
+			"device_id":     deviceID,
+			"pair_code_url": fmt.Sprintf("/session/%s/pair-code", deviceID),
+			"status":        "waiting_for_pair_code",
+		})
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Pairing reset", gin.H{
+		"device_id": deviceID,
+		"qr_url":    fmt.Sprintf("/qr/%s", deviceID),
+		"status":    "waiting_for_qr_scan",
+	})
+}
+
+// qrCodeWait bounds how long GetQRCode waits for WhatsApp to issue a code
+const qrCodeWait = 15 * time.Second
+
 // GetQRCode returns the QR code for a session
 func GetQRCode(c *gin.Context) {
 	deviceID := c.Param("device_id")
@@ -60,14 +184,14 @@ func GetQRCode(c *gin.Context) {
 	}
 
 	// Check if already connected
-	if deviceClient.Connected {
+	if deviceClient.IsConnected() {
 		if isBrowserRequest(c) {
-			renderHTMLConnected(c, deviceID, deviceClient.Phone)
+			renderHTMLConnected(c, deviceID, deviceClient.GetPhone())
 		} else {
 			utils.SuccessResponse(c, http.StatusOK, "Already connected", gin.H{
 				"device_id": deviceID,
 				"status":    "connected",
-				"phone":     deviceClient.Phone,
+				"phone":     deviceClient.GetPhone(),
 			})
 		}
 		return
@@ -87,95 +211,61 @@ func GetQRCode(c *gin.Context) {
 	if deviceClient.Client.IsLoggedIn() {
 		// Client is logged in but not marked as connected in our state
 		if deviceClient.Client.Store.ID != nil {
-			deviceClient.Connected = true
-			deviceClient.Phone = deviceClient.Client.Store.ID.User
-			deviceClient.ConnectedAt = time.Now()
+			deviceClient.MarkConnected(deviceClient.Client.Store.ID.User)
 
 			if isBrowserRequest(c) {
-				renderHTMLConnected(c, deviceID, deviceClient.Phone)
+				renderHTMLConnected(c, deviceID, deviceClient.GetPhone())
 			} else {
 				utils.SuccessResponse(c, http.StatusOK, "Already connected", gin.H{
 					"device_id": deviceID,
 					"status":    "connected",
-					"phone":     deviceClient.Phone,
+					"phone":     deviceClient.GetPhone(),
 				})
 			}
 			return
 		}
 	}
 
-	// Try to get QR code with shorter timeout first
-	timeout1 := 5 * time.Second
-	timeout2 := 15 * time.Second
+	// Browsers get the page right away; it follows the QR stream as codes rotate
+	if isBrowserRequest(c) {
+		renderHTMLQRCode(c, deviceID)
+		return
+	}
 
-	// First attempt - short timeout
-	select {
-	case qrCode := <-deviceClient.QRChan:
-		if qrCode != "" {
-			if isBrowserRequest(c) {
-				renderHTMLQRCode(c, deviceID, qrCode)
-			} else {
-				utils.SuccessResponse(c, http.StatusOK, "QR code generated", gin.H{
-					"device_id":  deviceID,
-					"qr_code":    qrCode,
-					"expires_in": 60,
-				})
-			}
-			return
-		}
-	case <-time.After(timeout1):
-		// If we have a client but no QR code yet, try longer timeout
-		if deviceClient.Client.Store.ID == nil {
-			select {
-			case qrCode := <-deviceClient.QRChan:
-				if qrCode != "" {
-					if isBrowserRequest(c) {
-						renderHTMLQRCode(c, deviceID, qrCode)
-					} else {
-						utils.SuccessResponse(c, http.StatusOK, "QR code generated", gin.H{
-							"device_id":  deviceID,
-							"qr_code":    qrCode,
-							"expires_in": 60,
-						})
-					}
-					return
-				}
-			case <-time.After(timeout2 - timeout1):
-				// Check client state
-				if deviceClient.Client.Store.ID == nil {
-					if isBrowserRequest(c) {
-						renderHTMLError(c, "QR code generation in progress", "QR code is being generated. This may take up to 30 seconds. Please refresh the page.")
-					} else {
-						utils.ErrorResponse(c, http.StatusRequestTimeout, "QR code generation in progress. Please try again in a few seconds.")
-					}
-				} else {
-					// Client connected during waiting
-					if isBrowserRequest(c) {
-						renderHTMLConnected(c, deviceID, deviceClient.Client.Store.ID.User)
-					} else {
-						utils.SuccessResponse(c, http.StatusOK, "Connected", gin.H{
-							"device_id": deviceID,
-							"status":    "connected",
-							"phone":     deviceClient.Client.Store.ID.User,
-						})
-					}
-				}
-				return
-			}
-		} else {
-			// Client has ID but not connected in our state
-			if isBrowserRequest(c) {
-				renderHTMLConnected(c, deviceID, deviceClient.Client.Store.ID.User)
-			} else {
-				utils.SuccessResponse(c, http.StatusOK, "Connected", gin.H{
-					"device_id": deviceID,
-					"status":    "connected",
-					"phone":     deviceClient.Client.Store.ID.User,
-				})
-			}
+	// Client has ID but not connected in our state
+	if deviceClient.Client.Store.ID != nil {
+		utils.SuccessResponse(c, http.StatusOK, "Connected", gin.H{
+			"device_id": deviceID,
+			"status":    "connected",
+			"phone":     deviceClient.Client.Store.ID.User,
+		})
+		return
+	}
+
+	// Every caller gets the current code; waiting doesn't take it from anyone else
+	ctx, cancel := context.WithTimeout(c.Request.Context(), qrCodeWait)
+	defer cancel()
+
+	qr, err := deviceClient.WaitQRCode(ctx)
+	if err != nil {
+		if deviceClient.Client.Store.ID != nil {
+			// Client connected during waiting
+			utils.SuccessResponse(c, http.StatusOK, "Connected", gin.H{
+				"device_id": deviceID,
+				"status":    "connected",
+				"phone":     deviceClient.Client.Store.ID.User,
+			})
 			return
 		}
+		utils.ErrorResponse(c, http.StatusRequestTimeout, "QR code generation in progress. Please try again in a few seconds.")
+		return
 	}
+
+	utils.SuccessResponse(c, http.StatusOK, "QR code generated", gin.H{
+		"device_id":  deviceID,
+		"qr_code":    qr.Code,
+		"expires_in": int(qr.ExpiresIn.Seconds()),
+	})
 }
 
 // isBrowserRequest checks if the request is from a web browser
@@ -199,8 +289,8 @@ func isBrowserRequest(c *gin.Context) bool {
 	return false
 }
 
-// renderHTMLQRCode renders HTML page with QR code
-func renderHTMLQRCode(c *gin.Context, deviceID, qrCode string) {
+// renderHTMLQRCode renders the QR page, which draws each code from the QR stream
+func renderHTMLQRCode(c *gin.Context, deviceID string) {
 	html := `<!DOCTYPE html>
 <html lang="en">
 <head>
@@ -338,7 +428,7 @@ func renderHTMLQRCode(c *gin.Context, deviceID, qrCode string) {
         </div>
 
         <div class="qr-container">
-            <div id="qrcode"></div>
+            <div id="qrcode"><div class="spinner"></div></div>
         </div>
 
         <div class="instructions">
@@ -354,33 +444,29 @@ func renderHTMLQRCode(c *gin.Context, deviceID, qrCode string) {
 
         <div class="status">
             <div class="spinner"></div>
-            <span>Waiting for scan...</span>
+            <span>Generating QR code...</span>
         </div>
 
         <button class="refresh-btn" onclick="location.reload()">🔄 Refresh QR Code</button>
 
-        <div class="footer">
-            QR Code expires in 60 seconds
-        </div>
+        <div class="footer" id="expiry"></div>
     </div>
 
     <script>
-        // Wait for QRCode library to load
-        function initQRCode() {
+        const deviceID = '` + deviceID + `';
+        const qrContainer = document.getElementById('qrcode');
+        const statusEl = document.querySelector('.status');
+        const expiryEl = document.getElementById('expiry');
+
+        // Draw a code in place, waiting for the QRCode library if needed
+        function drawQRCode(code) {
             if (typeof QRCode === 'undefined') {
-                setTimeout(initQRCode, 100);
+                setTimeout(() => drawQRCode(code), 100);
                 return;
             }
-
-            // Generate QR Code
-            const qrCode = '` + qrCode + `';
-            const qrContainer = document.getElementById('qrcode');
-
-            // Clear previous QR code
             qrContainer.innerHTML = '';
-
             new QRCode(qrContainer, {
-                text: qrCode,
+                text: code,
                 width: 280,
                 height: 280,
                 colorDark: "#000000",
@@ -389,35 +475,34 @@ func renderHTMLQRCode(c *gin.Context, deviceID, qrCode string) {
             });
         }
 
-        // Initialize QR code when page loads
-        if (document.readyState === 'loading') {
-            document.addEventListener('DOMContentLoaded', initQRCode);
-        } else {
-            initQRCode();
-        }
-
-        // Auto refresh after 55 seconds
-        setTimeout(() => {
-            location.reload();
-        }, 55000);
-
-        // Check connection status every 3 seconds
-        let checkInterval = setInterval(async () => {
-            try {
-                const response = await fetch('/session/' + deviceID + '/status');
-                const data = await response.json();
-
-                if (data.data && data.data.connected) {
-                    clearInterval(checkInterval);
-                    document.querySelector('.status').innerHTML = '<span style="color: #28a745;">✅ Connected Successfully!</span>';
-                    setTimeout(() => {
-                        window.location.href = '/qr/' + deviceID;
-                    }, 2000);
-                }
-            } catch (error) {
-                console.error('Error checking status:', error);
-            }
-        }, 3000);
+        const stream = new EventSource('/qr/' + encodeURIComponent(deviceID) + '/stream');
+
+        stream.addEventListener('qr', (event) => {
+            const data = JSON.parse(event.data);
+            drawQRCode(data.qr_code);
+            statusEl.innerHTML = '<div class="spinner"></div><span>Waiting for scan...</span>';
+            expiryEl.textContent = 'QR Code expires in ' + data.expires_in + ' seconds';
+        });
+
+        stream.addEventListener('connected', () => {
+            stream.close();
+            statusEl.innerHTML = '<span style="color: #28a745;">✅ Connected Successfully!</span>';
+            setTimeout(() => {
+                window.location.href = '/qr/' + encodeURIComponent(deviceID);
+            }, 2000);
+        });
+
+        stream.addEventListener('timeout', (event) => {
+            stream.close();
+            qrContainer.innerHTML = '';
+            statusEl.innerHTML = '<span style="color: #dc3545;">' + JSON.parse(event.data).message + '</span>';
+            expiryEl.textContent = '';
+        });
+
+        stream.addEventListener('logged_out', () => {
+            stream.close();
+            statusEl.innerHTML = '<span style="color: #dc3545;">Session logged out. Recreate the session to pair again.</span>';
+        });
     </script>
 </body>
 </html>`
@@ -633,7 +718,7 @@ func LogoutSession(c *gin.Context) {
 	waService := services.GetWhatsAppService()
 	err := waService.Logout(deviceID)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		respondError(c, http.StatusNotFound, err)
 		return
 	}
 
@@ -650,7 +735,7 @@ func DeleteSession(c *gin.Context) {
 	waService := services.GetWhatsAppService()
 	err := waService.DeleteSession(deviceID)
 	if err != nil {
-		utils.ErrorResponse(c, http.StatusNotFound, err.Error())
+		respondError(c, http.StatusNotFound, err)
 		return
 	}
 
@@ -660,6 +745,417 @@ func DeleteSession(c *gin.Context) {
 	})
 }
 
+// PingSession sends a keepalive on a session's socket and reports the round-trip time
+func PingSession(c *gin.Context) {
+	deviceID := c.Param("device_id")
+
+	waService := services.GetWhatsAppService()
+	if _, err := waService.GetSession(deviceID); err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	rtt, err := waService.Ping(deviceID)
+	if err != nil {
+		respondError(c, http.StatusServiceUnavailable, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Keepalive succeeded", gin.H{
+		"device_id": deviceID,
+		"rtt_ms":    rtt.Milliseconds(),
+	})
+}
+
+// ReconnectSession reconnects a session with its stored credentials and
+// reports the resulting status
+func ReconnectSession(c *gin.Context) {
+	deviceID := c.Param("device_id")
+
+	waService := services.GetWhatsAppService()
+	client, reconnected, err := waService.Reconnect(deviceID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrSessionNotFound):
+			respondError(c, http.StatusNotFound, err)
+		case errors.Is(err, services.ErrSessionNotConnected):
+			respondError(c, http.StatusConflict, err)
+		case errors.Is(err, services.ErrReconnectTimeout):
+			respondError(c, http.StatusGatewayTimeout, err)
+		default:
+			respondError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	message := "Session reconnected successfully"
+	if !reconnected {
+		message = "Session already connected"
+	}
+	utils.SuccessResponse(c, http.StatusOK, message, gin.H{
+		"device_id":   deviceID,
+		"status":      "connected",
+		"connected":   client.IsConnected(),
+		"reconnected": reconnected,
+		"phone":       client.GetPhone(),
+	})
+}
+
+// ListPendingSessions lists sessions still waiting for a QR scan with their age
+func ListPendingSessions(c *gin.Context) {
+	waService := services.GetWhatsAppService()
+	scope := utils.Scope(c)
+	pending := make([]services.PendingSession, 0)
+	for _, session := range waService.GetPendingSessions() {
+		if scope.Allows(session.DeviceID) {
+			pending = append(pending, session)
+		}
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Pending sessions retrieved", gin.H{
+		"total":    len(pending),
+		"sessions": pending,
+	})
+}
+
+// RenameSessionRequest represents the request body for renaming a session
+type RenameSessionRequest struct {
+	NewDeviceID string `json:"new_device_id" binding:"required"`
+}
+
+// RenameSession moves a session to a new device ID without re-pairing
+func RenameSession(c *gin.Context) {
+	deviceID := c.Param("device_id")
+
+	var req RenameSessionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// The new ID must stay within the token's scope
+	if !utils.Scope(c).Allows(req.NewDeviceID) {
+		utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: token is not allowed to access device "+req.NewDeviceID)
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	if _, err := waService.GetSession(deviceID); err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	client, err := waService.RenameSession(deviceID, req.NewDeviceID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidDeviceID):
+			respondError(c, http.StatusBadRequest, err)
+		case errors.Is(err, services.ErrSessionExists):
+			respondError(c, http.StatusConflict, err)
+		default:
+			respondError(c, http.StatusInternalServerError, err)
+		}
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Session renamed successfully", gin.H{
+		"old_device_id": deviceID,
+		"device_id":     req.NewDeviceID,
+		"connected":     client.IsConnected(),
+		"phone":         client.GetPhone(),
+	})
+}
+
+// BulkDeleteSessionsRequest represents the request body for deleting multiple sessions
+type BulkDeleteSessionsRequest struct {
+	DeviceIDs []string `json:"device_ids"`
+	Prefix    string   `json:"prefix"`
+	Confirm   bool     `json:"confirm"`
+}
+
+// BulkDeleteSessions deletes several sessions selected by ID list or prefix
+func BulkDeleteSessions(c *gin.Context) {
+	var req BulkDeleteSessionsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if len(req.DeviceIDs) == 0 && req.Prefix == "" {
+		utils.ErrorResponse(c, http.StatusBadRequest, "device_ids or prefix is required")
+		return
+	}
+
+	// Require explicit confirmation to protect against accidental mass deletion
+	if !req.Confirm {
+		utils.ErrorResponse(c, http.StatusBadRequest, "Bulk delete requires confirm=true")
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	scope := utils.Scope(c)
+
+	// Collect target device IDs without duplicates
+	seen := make(map[string]bool)
+	targets := make([]string, 0, len(req.DeviceIDs))
+	for _, deviceID := range req.DeviceIDs {
+		if deviceID != "" && !scope.Allows(deviceID) {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: token is not allowed to access device "+deviceID)
+			return
+		}
+		if deviceID != "" && !seen[deviceID] {
+			seen[deviceID] = true
+			targets = append(targets, deviceID)
+		}
+	}
+	if req.Prefix != "" {
+		for _, session := range waService.GetAllSessions() {
+			if strings.HasPrefix(session.DeviceID, req.Prefix) && scope.Allows(session.DeviceID) && !seen[session.DeviceID] {
+				seen[session.DeviceID] = true
+				targets = append(targets, session.DeviceID)
+			}
+		}
+	}
+
+	results := make([]gin.H, 0, len(targets))
+	deletedCount := 0
+	for _, deviceID := range targets {
+		if err := waService.DeleteSession(deviceID); err != nil {
+			results = append(results, gin.H{
+				"device_id": deviceID,
+				"deleted":   false,
+				"error":     err.Error(),
+			})
+			continue
+		}
+
+		deletedCount++
+		results = append(results, gin.H{
+			"device_id": deviceID,
+			"deleted":   true,
+		})
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Bulk delete completed", gin.H{
+		"total":      len(targets),
+		"deleted":    deletedCount,
+		"failed":     len(targets) - deletedCount,
+		"results":    results,
+		"deleted_at": time.Now().Format(time.RFC3339),
+	})
+}
+
+// UpdateSessionSettingsRequest represents the request body for updating session settings
+type UpdateSessionSettingsRequest struct {
+	DisplayName *string `json:"display_name"`
+	// WebhookURL replaces the device's webhook targets; empty falls back to WEBHOOK_URL
+	WebhookURL           *string `json:"webhook_url"`
+	Footer               *string `json:"footer"`
+	MessageRetentionDays *int    `json:"message_retention_days"`
+	AutoRead             *bool   `json:"auto_read"`
+	TypingSeconds        *int    `json:"typing_seconds"`
+}
+
+// UpdateSessionSettings updates the per-device settings of a session
+func UpdateSessionSettings(c *gin.Context) {
+	updateSessionConfig(c, "Session settings updated", "settings")
+}
+
+// GetSessionConfig returns the persisted configuration of a session
+func GetSessionConfig(c *gin.Context) {
+	deviceID := c.Param("device_id")
+
+	waService := services.GetWhatsAppService()
+	client, err := waService.GetSession(deviceID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Session config retrieved", gin.H{
+		"device_id": deviceID,
+		"config":    client.GetConfig().View(),
+	})
+}
+
+// UpdateSessionConfig updates and persists the configuration of a session
+func UpdateSessionConfig(c *gin.Context) {
+	updateSessionConfig(c, "Session config updated", "config")
+}
+
+// updateSessionConfig applies the fields present in the request to a
+// session's configuration and responds with the result under key
+func updateSessionConfig(c *gin.Context, message, key string) {
+	deviceID := c.Param("device_id")
+
+	var req UpdateSessionSettingsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if req.DisplayName != nil && utf8.RuneCountInString(*req.DisplayName) > services.MaxDisplayNameLength {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("display_name exceeds the maximum length of %d characters", services.MaxDisplayNameLength))
+		return
+	}
+
+	if req.WebhookURL != nil && *req.WebhookURL != "" {
+		if err := services.ValidateWebhookURL(*req.WebhookURL); err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	// The footer must fit inside a media caption on its own
+	if req.Footer != nil && utf8.RuneCountInString(*req.Footer) > services.MaxCaptionLength {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("Footer exceeds the maximum length of %d characters", services.MaxCaptionLength))
+		return
+	}
+
+	if req.MessageRetentionDays != nil && *req.MessageRetentionDays < 0 {
+		utils.ErrorResponse(c, http.StatusBadRequest, "message_retention_days must be 0 or greater")
+		return
+	}
+
+	if req.TypingSeconds != nil && (*req.TypingSeconds < -1 || *req.TypingSeconds > services.MaxTypingSeconds) {
+		utils.ErrorResponse(c, http.StatusBadRequest, fmt.Sprintf("typing_seconds must be between -1 and %d", services.MaxTypingSeconds))
+		return
+	}
+
+	waService := services.GetWhatsAppService()
+	config, err := waService.UpdateDeviceConfig(deviceID, func(config *services.DeviceConfig) error {
+		if req.DisplayName != nil {
+			config.DisplayName = *req.DisplayName
+		}
+		if req.WebhookURL != nil {
+			config.WebhookURLs = nil
+			if *req.WebhookURL != "" {
+				config.WebhookURLs = []string{*req.WebhookURL}
+			}
+		}
+		if req.Footer != nil {
+			config.Footer = *req.Footer
+		}
+		if req.MessageRetentionDays != nil {
+			config.MessageRetentionDays = req.MessageRetentionDays
+		}
+		if req.AutoRead != nil {
+			config.AutoRead = *req.AutoRead
+		}
+		if req.TypingSeconds != nil {
+			config.TypingSeconds = *req.TypingSeconds
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, message, gin.H{
+		"device_id": deviceID,
+		key:         config.View(),
+	})
+}
+
+// SetSessionWebhookRequest represents the request body for setting a device's webhook URL
+type SetSessionWebhookRequest struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+}
+
+// SetSessionWebhook points a device's webhooks at a single URL. An empty URL
+// falls back to the global WEBHOOK_URL. Headers are replaced only when sent.
+func SetSessionWebhook(c *gin.Context) {
+	deviceID := c.Param("device_id")
+
+	var req SetSessionWebhookRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var urls []string
+	if req.URL != "" {
+		if err := services.ValidateWebhookURL(req.URL); err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+		urls = []string{req.URL}
+	}
+	for name, value := range req.Headers {
+		if err := services.ValidateWebhookHeader(name, value); err != nil {
+			respondError(c, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	waService := services.GetWhatsAppService()
+	config, err := waService.UpdateDeviceConfig(deviceID, func(config *services.DeviceConfig) error {
+		config.WebhookURLs = urls
+		if req.Headers != nil {
+			config.WebhookHeaders = req.Headers
+		}
+		return nil
+	})
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Session webhook updated", gin.H{
+		"device_id":    deviceID,
+		"webhook_urls": config.WebhookURLs,
+		// Header values may be credentials, so only their names are echoed
+		"webhook_headers": config.View().WebhookHeaders,
+		"uses_global":     len(config.WebhookURLs) == 0,
+	})
+}
+
+// GetSessionLogs returns the most recent log lines captured for a session
+func GetSessionLogs(c *gin.Context) {
+	deviceID := c.Param("device_id")
+
+	lines := 100
+	if raw := c.Query("lines"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			utils.ErrorResponse(c, http.StatusBadRequest, "lines must be a positive integer")
+			return
+		}
+		lines = n
+	}
+
+	waService := services.GetWhatsAppService()
+	logs, err := waService.GetDeviceLogs(deviceID, lines)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Session logs retrieved", gin.H{
+		"device_id": deviceID,
+		"total":     len(logs),
+		"logs":      logs,
+	})
+}
+
+// GetPhoneState returns the last-known battery and connectivity state of a session's phone
+func GetPhoneState(c *gin.Context) {
+	deviceID := c.Param("device_id")
+
+	waService := services.GetWhatsAppService()
+	state, err := waService.GetPhoneState(deviceID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+
+	utils.SuccessResponse(c, http.StatusOK, "Phone state retrieved", gin.H{
+		"device_id":     deviceID,
+		"battery_level": state.BatteryLevel,
+		"charging":      state.Charging,
+		"connection":    state.Connection,
+		"updated_at":    state.UpdatedAt,
+	})
+}
+
 // GetSessionStatus returns the status of a session
 func GetSessionStatus(c *gin.Context) {
 	deviceID := c.Param("device_id")
@@ -676,7 +1172,7 @@ func GetSessionStatus(c *gin.Context) {
 	}
 
 	status := "disconnected"
-	if deviceClient.Connected {
+	if deviceClient.IsConnected() {
 		status = "connected"
 	} else if deviceClient.Client.Store.ID == nil {
 		status = "waiting_for_qr_scan"
@@ -684,14 +1180,21 @@ func GetSessionStatus(c *gin.Context) {
 
 	data := gin.H{
 		"device_id": deviceID,
+		"name":      deviceClient.GetConfig().DisplayName,
 		"status":    status,
-		"phone":     deviceClient.Phone,
-		"connected": deviceClient.Connected, // Add connected field for browser JavaScript
+		"phone":     deviceClient.GetPhone(),
+		"connected": deviceClient.IsConnected(), // Add connected field for browser JavaScript
 	}
 
-	if deviceClient.Connected {
-		data["connected_at"] = deviceClient.ConnectedAt.Format(time.RFC3339)
+	if deviceClient.IsConnected() {
+		data["connected_at"] = deviceClient.GetConnectedAt().Format(time.RFC3339)
+	}
+	if lastKeepAlive := deviceClient.LastKeepAlive(); !lastKeepAlive.IsZero() {
+		data["last_keepalive"] = lastKeepAlive.Format(time.RFC3339)
 	}
+	reconnect := deviceClient.ReconnectStatus()
+	data["reconnecting"] = reconnect.Reconnecting
+	data["reconnect_attempts"] = reconnect.Attempts
 
 	utils.SuccessResponse(c, http.StatusOK, "Session status retrieved", data)
 }
@@ -700,11 +1203,16 @@ func GetSessionStatus(c *gin.Context) {
 func ListSessions(c *gin.Context) {
 	waService := services.GetWhatsAppService()
 	sessions := waService.GetAllSessions()
+	scope := utils.Scope(c)
 
 	sessionList := make([]gin.H, 0)
 	for _, session := range sessions {
+		if !scope.Allows(session.DeviceID) {
+			continue
+		}
+
 		status := "disconnected"
-		if session.Connected {
+		if session.IsConnected() {
 			status = "connected"
 		} else if session.Client.Store.ID == nil {
 			status = "waiting_for_qr_scan"
@@ -712,8 +1220,9 @@ func ListSessions(c *gin.Context) {
 
 		sessionList = append(sessionList, gin.H{
 			"device_id": session.DeviceID,
+			"name":      session.GetConfig().DisplayName,
 			"status":    status,
-			"phone":     session.Phone,
+			"phone":     session.GetPhone(),
 		})
 	}
 
@@ -731,3 +1240,33 @@ func HealthCheck(c *gin.Context) {
 		"time":    time.Now().Format(time.RFC3339),
 	})
 }
+
+// Liveness reports that the process is up, for container liveness probes
+func Liveness(c *gin.Context) {
+	waService := services.GetWhatsAppService()
+	c.JSON(http.StatusOK, gin.H{
+		"status":         "ok",
+		"uptime_seconds": int64(services.Uptime().Seconds()),
+		"sessions":       len(waService.GetAllSessions()),
+	})
+}
+
+// Readiness reports each session's connection state, answering 503 when
+// sessions exist but none is connected
+func Readiness(c *gin.Context) {
+	readiness := services.GetWhatsAppService().GetReadiness()
+
+	statusCode := http.StatusOK
+	status := "ready"
+	if !readiness.Ready {
+		statusCode = http.StatusServiceUnavailable
+		status = "not_ready"
+	}
+	c.JSON(statusCode, gin.H{
+		"status":         status,
+		"uptime_seconds": readiness.UptimeSeconds,
+		"sessions":       readiness.Sessions,
+		"connected":      readiness.ConnectedCount,
+		"devices":        readiness.Devices,
+	})
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"time"
+	"waku/services"
+	"waku/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// streamPingInterval keeps idle WebSocket connections alive through proxies
+	streamPingInterval = 30 * time.Second
+	// streamWriteTimeout drops clients that stop reading
+	streamWriteTimeout = 10 * time.Second
+	// sseKeepAliveInterval sends SSE comments so proxies don't close an idle QR stream
+	sseKeepAliveInterval = 15 * time.Second
+)
+
+// streamUpgrader accepts any origin, like the CORS config; access is
+// controlled by the API token
+var streamUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// StreamEvents upgrades to a WebSocket and streams a device's QR codes,
+// connection changes and incoming messages as JSON frames
+func StreamEvents(c *gin.Context) {
+	deviceID := c.Param("device_id")
+
+	events, unsubscribe, err := services.GetWhatsAppService().SubscribeEvents(deviceID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+	defer unsubscribe()
+
+	conn, err := streamUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader already wrote the error response
+		return
+	}
+	defer conn.Close()
+
+	// Clients don't send anything; reading only notices when they disconnect
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(streamPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case evt, ok := <-events:
+			if !ok {
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if err := conn.WriteJSON(evt); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// StreamQRCode streams a session's QR codes as server-sent events: a qr event
+// whenever the code to show changes, then connected once the phone has
+// scanned it, or timeout when every code expired
+func StreamQRCode(c *gin.Context) {
+	deviceID := c.Param("device_id")
+
+	waService := services.GetWhatsAppService()
+	deviceClient, err := waService.GetSession(deviceID)
+	if err != nil {
+		utils.ErrorResponse(c, http.StatusNotFound, "Session not found. Please create session first via POST /session/create")
+		return
+	}
+
+	// Connection changes come from the event stream; codes from the session's QR state
+	events, unsubscribe, err := waService.SubscribeEvents(deviceID)
+	if err != nil {
+		respondError(c, http.StatusNotFound, err)
+		return
+	}
+	defer unsubscribe()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	if deviceClient.IsConnected() {
+		c.SSEvent("connected", services.ConnectionEvent{Status: "connected", Phone: deviceClient.GetPhone()})
+		return
+	}
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	shown := ""
+	c.Stream(func(w io.Writer) bool {
+		qr, changed := deviceClient.CurrentQRCode()
+		if qr.Expired {
+			c.SSEvent("timeout", gin.H{"message": "QR code expired. Recreate the session to pair again."})
+			return false
+		}
+
+		// Wake up when the code rotates as well as when a new pairing attempt replaces it
+		var rotate <-chan time.Time
+		if qr.Code != "" {
+			if qr.Code != shown {
+				shown = qr.Code
+				c.SSEvent("qr", gin.H{"qr_code": qr.Code, "expires_in": int(qr.ExpiresIn.Seconds())})
+				return true
+			}
+			rotate = time.After(qr.ExpiresIn)
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-changed:
+			return true
+		case <-rotate:
+			return true
+		case evt, ok := <-events:
+			if !ok {
+				return false
+			}
+			if data, isConnection := evt.Data.(services.ConnectionEvent); isConnection && (data.Status == "connected" || data.Status == "logged_out") {
+				c.SSEvent(data.Status, data)
+				return false
+			}
+			return true
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keepalive\n\n")
+			return err == nil
+		}
+	})
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 func main() {
@@ -33,16 +34,27 @@ func main() {
 		log.Fatalf("Failed to create session directory: %v", err)
 	}
 
-	tempDir := os.Getenv("TEMP_MEDIA_DIR")
-	if tempDir == "" {
-		tempDir = "./temp"
-	}
+	tempDir := utils.TempMediaDir()
 	if err := utils.EnsureDir(tempDir); err != nil {
 		log.Fatalf("Failed to create temp directory: %v", err)
 	}
+	go utils.RunJanitor(tempDir, utils.TempFileMaxAge(), services.AppLogger())
+
+	// Inbound media gets its own retention unless it shares the temp directory
+	if downloadDir := utils.DownloadMediaDir(); downloadDir != tempDir {
+		if err := utils.EnsureDir(downloadDir); err != nil {
+			log.Fatalf("Failed to create download directory: %v", err)
+		}
+		go utils.RunJanitor(downloadDir, utils.DownloadFileMaxAge(), services.AppLogger())
+	}
+
+	// Apply configured media size limits
+	utils.LoadMediaLimits()
 
 	// Initialize webhook service
-	services.InitWebhookService()
+	if err := services.InitWebhookService(services.AppLogger().Sub("Webhook")); err != nil {
+		log.Fatalf("Failed to initialize webhook service: %v", err)
+	}
 
 	// Set Gin mode
 	logLevel := os.Getenv("LOG_LEVEL")
@@ -52,22 +64,30 @@ func main() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
-	// Create Gin router
-	router := gin.Default()
+	// Create Gin router; every request gets an ID that shows up in logs, responses and webhooks
+	router := gin.New()
+	router.Use(middleware.RequestIDMiddleware(), middleware.LoggerMiddleware(), gin.Recovery())
 
 	// Add CORS middleware for all routes
 	router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length", "Content-Type"},
+		AllowHeaders:     []string{"Content-Type", "Authorization", middleware.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", "Content-Type", middleware.RequestIDHeader},
 		AllowCredentials: false,
 		MaxAge:           12 * time.Hour,
 	}))
 
 	// Public routes (no authentication required)
 	router.GET("/health", handlers.HealthCheck)
+	router.GET("/healthz", handlers.Liveness)
+	router.GET("/readyz", handlers.Readiness)
+	if services.MetricsEnabled() {
+		router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	}
+	router.GET("/capabilities", handlers.GetCapabilities)
 	router.GET("/qr/:device_id", handlers.GetQRCode)
+	router.GET("/qr/:device_id/stream", handlers.StreamQRCode)
 	router.GET("/session/:device_id/status", handlers.GetSessionStatus) // Make status public for browser polling
 
 	// Protected routes (require authentication)
@@ -76,21 +96,84 @@ func main() {
 	{
 		// Session management
 		protected.POST("/session/create", handlers.CreateSession)
+		protected.POST("/session/:device_id/pair-code", handlers.PairWithCode)
+		protected.POST("/session/:device_id/repair-pairing", handlers.RepairPairing)
 		protected.POST("/logout/:device_id", handlers.LogoutSession)
 		protected.DELETE("/session/:device_id", handlers.DeleteSession)
+		protected.PUT("/session/:device_id/settings", handlers.UpdateSessionSettings)
+		protected.GET("/session/:device_id/config", handlers.GetSessionConfig)
+		protected.PUT("/session/:device_id/config", handlers.UpdateSessionConfig)
+		protected.POST("/session/:device_id/webhook", handlers.SetSessionWebhook)
+		protected.POST("/session/:device_id/rename", handlers.RenameSession)
+		protected.GET("/session/:device_id/logs", handlers.GetSessionLogs)
+		protected.GET("/session/:device_id/phone-state", handlers.GetPhoneState)
+		protected.POST("/session/:device_id/ping", handlers.PingSession)
+		protected.POST("/session/:device_id/reconnect", handlers.ReconnectSession)
+		protected.POST("/session/:device_id/sync-contacts", handlers.SyncContacts)
 		protected.GET("/sessions", handlers.ListSessions)
-
-		// Messaging
-		protected.POST("/send", handlers.SendMessage)
-		protected.POST("/send-group", handlers.SendGroupMessage)
+		protected.POST("/sessions/delete", handlers.BulkDeleteSessions)
+		protected.GET("/sessions/pending", handlers.ListPendingSessions)
+		protected.GET("/ws/:device_id", handlers.StreamEvents)
+
+		// Messaging, rate limited per device and token
+		sending := protected.Group("/", middleware.RateLimitMiddleware())
+		sending.POST("/send", handlers.SendMessage)
+		sending.POST("/send-group", handlers.SendGroupMessage)
+		sending.POST("/send-bulk", handlers.SendBulk)
+		protected.GET("/job/:id", handlers.GetJob)
+		sending.POST("/send-cta", handlers.SendCTA)
+		sending.POST("/request-location", handlers.SendLocationRequest)
+		sending.POST("/send-location", handlers.SendLocationMessage)
+		sending.POST("/send-contact", handlers.SendContact)
+		sending.POST("/send-poll", handlers.SendPoll)
+		sending.POST("/edit", handlers.EditMessage)
+		sending.POST("/revoke", handlers.RevokeMessage)
+		protected.GET("/message/:device_id/:message_id/status", handlers.GetMessageStatus)
+		protected.GET("/messages/:device_id", handlers.GetMessages)
+		sending.POST("/presence", handlers.SendPresence)
+		sending.POST("/presence/availability", handlers.SetAvailability)
+		protected.POST("/presence/subscribe", handlers.SubscribePresence)
+		protected.GET("/contact/presence/:device_id", handlers.GetContactPresence)
 
 		// Media
-		protected.POST("/send-media", handlers.SendMediaMessage)
-		protected.POST("/send-group-media", handlers.SendGroupMediaMessage)
+		sending.POST("/send-media", handlers.SendMediaMessage)
+		sending.POST("/send-group-media", handlers.SendGroupMediaMessage)
+		sending.POST("/send-media-base64", handlers.SendMediaBase64)
+		protected.GET("/media-status/:device_id", handlers.GetMediaStatus)
+		protected.GET("/media/:device_id/:message_id", handlers.DownloadMedia)
+		protected.GET(services.DownloadsPath+"/:device_id/:name", handlers.ServeDownload)
 
 		// Information
 		protected.GET("/contacts/:device_id", handlers.GetContacts)
 		protected.GET("/groups/:device_id", handlers.GetGroups)
+		protected.POST("/check-numbers", handlers.CheckNumbers)
+		protected.GET("/groups/:device_id/:group_jid/icon", handlers.GetGroupIcon)
+		protected.GET("/profile-picture/:device_id", handlers.GetProfilePicture)
+		protected.GET("/contact/status/:device_id", handlers.GetContactStatus)
+		protected.POST("/chat/:device_id/:jid/fetch-history", handlers.FetchChatHistory)
+		protected.POST("/chat/:device_id/history", handlers.GetChatHistory)
+		protected.POST("/chat/:device_id/disappearing", handlers.SetChatDisappearing)
+
+		// Group management
+		protected.POST("/group/create", handlers.CreateGroup)
+		protected.POST("/group/join", handlers.JoinGroup)
+		protected.GET("/group/:group_jid/info", handlers.GetGroupInfo)
+		protected.GET("/group/:group_jid/invite-link", handlers.GetGroupInviteLink)
+		protected.PUT("/group/:group_jid/subject", handlers.SetGroupSubject)
+		protected.PUT("/group/:group_jid/description", handlers.SetGroupDescription)
+		protected.POST("/group/:group_jid/participants", handlers.AddGroupParticipants)
+		protected.DELETE("/group/:group_jid/participants", handlers.RemoveGroupParticipants)
+		protected.POST("/group/:group_jid/promote", handlers.PromoteGroupParticipants)
+		protected.POST("/group/:group_jid/demote", handlers.DemoteGroupParticipants)
+
+		// Administration
+		admin := protected.Group("/admin", middleware.RequireAdmin())
+		admin.PUT("/webhook-routes/:device_id", handlers.SetWebhookRoutes)
+		admin.GET("/webhook-stats", handlers.GetWebhookStats)
+		admin.GET("/store-stats", handlers.GetStoreStats)
+		admin.GET("/number-cache-stats", handlers.GetNumberCacheStats)
+		admin.GET("/report", handlers.GetReport)
+		admin.POST("/simulate-incoming", handlers.SimulateIncoming)
 	}
 
 	// Get host and port from environment
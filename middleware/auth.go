@@ -1,48 +1,80 @@
 package middleware
 
 import (
-	"os"
+	"fmt"
+	"net/http"
 	"strings"
 	"waku/utils"
 
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware validates the API token from Authorization header
+// AuthMiddleware validates the API token from Authorization header and
+// rejects requests for devices outside the token's scope
 func AuthMiddleware() gin.HandlerFunc {
+	tokens := mustLoadTokens()
+
 	return func(c *gin.Context) {
+		// Cap the body before anything reads it; no endpoint accepts more than base64 media
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, utils.MaxBase64BodySize())
+		}
+
 		// Get Authorization header
 		authHeader := c.GetHeader("Authorization")
-		
+
+		// Browsers can't set headers on WebSocket handshakes, so those may pass ?token=
+		if authHeader == "" && strings.EqualFold(c.GetHeader("Upgrade"), "websocket") && c.Query("token") != "" {
+			authHeader = "Bearer " + c.Query("token")
+		}
+
 		// Check if Authorization header exists
 		if authHeader == "" {
 			utils.ErrorResponse(c, 401, "Unauthorized: Missing Authorization header")
 			c.Abort()
 			return
 		}
-		
+
 		// Check if it starts with "Bearer "
 		if !strings.HasPrefix(authHeader, "Bearer ") {
 			utils.ErrorResponse(c, 401, "Unauthorized: Invalid Authorization format. Use 'Bearer <token>'")
 			c.Abort()
 			return
 		}
-		
-		// Extract token
+
+		// Extract token and resolve its scope
 		token := strings.TrimPrefix(authHeader, "Bearer ")
-		
-		// Get expected token from environment
-		expectedToken := os.Getenv("API_TOKEN")
-		
-		// Validate token
-		if token != expectedToken {
+		scope := lookupToken(tokens, token)
+		if token == "" || scope == nil {
 			utils.ErrorResponse(c, 401, "Unauthorized: Invalid API token")
 			c.Abort()
 			return
 		}
-		
+		c.Set(utils.TokenScopeKey, scope)
+
+		// Scoped tokens may only operate on their own devices. Devices named in
+		// the body are checked by the handler against the struct it binds.
+		for _, deviceID := range []string{c.Param("device_id"), c.Query("device_id")} {
+			if deviceID != "" && !scope.Allows(deviceID) {
+				utils.ErrorResponse(c, http.StatusForbidden, fmt.Sprintf("Forbidden: token is not allowed to access device %s", deviceID))
+				c.Abort()
+				return
+			}
+		}
+
 		// Token is valid, continue to next handler
 		c.Next()
 	}
 }
 
+// RequireAdmin rejects tokens that aren't scoped to every device
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !utils.Scope(c).IsAdmin() {
+			utils.ErrorResponse(c, http.StatusForbidden, "Forbidden: admin token required")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newAuthRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Setenv("API_TOKEN", "admin-token")
+	t.Setenv("API_TOKENS_FILE", "")
+	t.Setenv("API_TOKENS", `[{"name":"tenant","token":"tenant-token","devices":["mine"]}]`)
+
+	router := gin.New()
+	router.Use(AuthMiddleware())
+	router.GET("/session/:device_id/status", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/groups", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func TestAuthMiddlewareScope(t *testing.T) {
+	router := newAuthRouter(t)
+
+	tests := []struct {
+		name  string
+		token string
+		path  string
+		want  int
+	}{
+		{"scoped token on own device", "tenant-token", "/session/mine/status", http.StatusOK},
+		{"scoped token on other device", "tenant-token", "/session/victim/status", http.StatusForbidden},
+		{"scoped token with own query device", "tenant-token", "/groups?device_id=mine", http.StatusOK},
+		{"scoped token with other query device", "tenant-token", "/groups?device_id=victim", http.StatusForbidden},
+		{"path allowed but query names other device", "tenant-token", "/session/mine/status?device_id=victim", http.StatusForbidden},
+		{"admin token on any device", "admin-token", "/session/victim/status", http.StatusOK},
+		{"unknown token", "nope", "/session/mine/status", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"waku/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// rateLimitCleanupInterval is how often idle buckets are dropped
+	rateLimitCleanupInterval = time.Minute
+	// rateLimitIdleTTL is how long an untouched bucket is kept; by then it has refilled anyway
+	rateLimitIdleTTL = 10 * time.Minute
+)
+
+// tokenBucket holds the tokens left for one key and when they were last refilled
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter is an in-memory token bucket limiter allowing perMinute
+// requests per key, with bursts of up to perMinute
+type RateLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	rate    float64 // tokens per second
+	burst   float64
+}
+
+// NewRateLimiter creates a limiter and starts the cleanup of idle buckets
+func NewRateLimiter(perMinute int) *RateLimiter {
+	l := &RateLimiter{
+		buckets: make(map[string]*tokenBucket),
+		rate:    float64(perMinute) / 60,
+		burst:   float64(perMinute),
+	}
+	go l.cleanupLoop()
+	return l
+}
+
+// Allow takes a token for key. When none is left it returns false and how
+// long until the next token is available.
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = bucket
+	}
+
+	bucket.tokens = math.Min(l.burst, bucket.tokens+now.Sub(bucket.last).Seconds()*l.rate)
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		wait := time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+		return false, wait
+	}
+	bucket.tokens--
+	return true, 0
+}
+
+// Wait takes a token for key, sleeping until one is available
+func (l *RateLimiter) Wait(key string) {
+	for {
+		ok, wait := l.Allow(key)
+		if ok {
+			return
+		}
+		time.Sleep(wait)
+	}
+}
+
+// cleanupLoop periodically drops buckets that have been idle for rateLimitIdleTTL
+func (l *RateLimiter) cleanupLoop() {
+	ticker := time.NewTicker(rateLimitCleanupInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		l.mu.Lock()
+		cutoff := time.Now().Add(-rateLimitIdleTTL)
+		for key, bucket := range l.buckets {
+			if bucket.last.Before(cutoff) {
+				delete(l.buckets, key)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// envPerMinute reads a per-minute limit; 0 or unset disables it
+func envPerMinute(name string) int {
+	n, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// RateLimitMiddleware limits requests per device to RATE_LIMIT_PER_MINUTE and
+// per API token to RATE_LIMIT_PER_TOKEN_PER_MINUTE, answering 429 with a
+// Retry-After header when a limit is hit. Unset limits are disabled.
+func RateLimitMiddleware() gin.HandlerFunc {
+	var devices, tokens *RateLimiter
+	if perMinute := envPerMinute("RATE_LIMIT_PER_MINUTE"); perMinute > 0 {
+		devices = NewRateLimiter(perMinute)
+	}
+	if perMinute := envPerMinute("RATE_LIMIT_PER_TOKEN_PER_MINUTE"); perMinute > 0 {
+		tokens = NewRateLimiter(perMinute)
+	}
+
+	return func(c *gin.Context) {
+		var tokenKey, deviceID string
+		if tokens != nil {
+			// Only a digest of the token is kept in memory
+			sum := sha256.Sum256([]byte(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")))
+			tokenKey = hex.EncodeToString(sum[:])
+			if !allowOrAbort(c, tokens, tokenKey, "API token") {
+				return
+			}
+		}
+		if devices != nil {
+			if deviceID = requestDeviceID(c); deviceID != "" && !allowOrAbort(c, devices, deviceID, "device "+deviceID) {
+				return
+			}
+		}
+
+		// Requests sending many messages pay for the rest of them as they go
+		if tokens != nil || devices != nil {
+			c.Set(utils.RateLimitWaitKey, func() {
+				if tokens != nil {
+					tokens.Wait(tokenKey)
+				}
+				if devices != nil && deviceID != "" {
+					devices.Wait(deviceID)
+				}
+			})
+		}
+		c.Next()
+	}
+}
+
+// allowOrAbort takes a token from limiter and answers 429 when none is left
+func allowOrAbort(c *gin.Context, limiter *RateLimiter, key, subject string) bool {
+	ok, wait := limiter.Allow(key)
+	if ok {
+		return true
+	}
+
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	utils.ErrorResponse(c, http.StatusTooManyRequests, fmt.Sprintf("Rate limit exceeded for %s, retry in %d seconds", subject, retryAfter))
+	c.Abort()
+	return false
+}
+
+// deviceScanLimit bounds how much of a body is read looking for its device_id;
+// larger bodies such as base64 media are never buffered here
+const deviceScanLimit = 64 << 10
+
+// requestDeviceID finds the device a request targets in its path, query,
+// form or JSON body for rate limiting. Only the first deviceScanLimit bytes of
+// the body are read, and they are replayed ahead of the rest for the handler.
+func requestDeviceID(c *gin.Context) string {
+	if deviceID := c.Param("device_id"); deviceID != "" {
+		return deviceID
+	}
+	if deviceID := c.Query("device_id"); deviceID != "" {
+		return deviceID
+	}
+	if c.Request.Body == nil {
+		return ""
+	}
+
+	body := c.Request.Body
+	prefix, err := io.ReadAll(io.LimitReader(body, deviceScanLimit))
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(prefix), body), body}
+	if err != nil {
+		return ""
+	}
+
+	switch {
+	case strings.HasPrefix(c.ContentType(), "multipart/"):
+		return multipartDeviceID(prefix, c.GetHeader("Content-Type"))
+	case c.ContentType() == "application/x-www-form-urlencoded":
+		if len(prefix) == deviceScanLimit {
+			// Drop the pair cut off by the limit
+			if i := bytes.LastIndexByte(prefix, '&'); i >= 0 {
+				prefix = prefix[:i]
+			}
+		}
+		values, _ := url.ParseQuery(string(prefix))
+		return values.Get("device_id")
+	default:
+		return jsonDeviceID(bytes.NewReader(prefix))
+	}
+}
+
+// jsonDeviceID reads the top-level device_id of a JSON object the way binding
+// does: keys match case-insensitively and the last one wins
+func jsonDeviceID(r io.Reader) string {
+	dec := json.NewDecoder(r)
+	if token, err := dec.Token(); err != nil || token != json.Delim('{') {
+		return ""
+	}
+
+	deviceID := ""
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return deviceID
+		}
+		if name, ok := key.(string); ok && strings.EqualFold(name, "device_id") {
+			var value string
+			if err := dec.Decode(&value); err != nil {
+				return deviceID
+			}
+			deviceID = value
+			continue
+		}
+		var skip json.RawMessage
+		if err := dec.Decode(&skip); err != nil {
+			return deviceID
+		}
+	}
+	return deviceID
+}
+
+// multipartDeviceID reads the device_id field from the start of a multipart
+// body, stopping at the first file part
+func multipartDeviceID(prefix []byte, contentType string) string {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil || params["boundary"] == "" {
+		return ""
+	}
+
+	reader := multipart.NewReader(bytes.NewReader(prefix), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err != nil || part.FileName() != "" {
+			return ""
+		}
+		if part.FormName() == "device_id" {
+			value, _ := io.ReadAll(io.LimitReader(part, 256))
+			return string(value)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"waku/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+func deviceIDOf(t *testing.T, req *http.Request) (string, []byte) {
+	t.Helper()
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = req
+	deviceID := requestDeviceID(c)
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		t.Fatalf("reading replayed body: %v", err)
+	}
+	return deviceID, body
+}
+
+func TestRequestDeviceIDJSON(t *testing.T) {
+	body := `{"device_id":"mine","Device_ID":"other","phone":"628123456789"}`
+	req := httptest.NewRequest(http.MethodPost, "/send", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	deviceID, replayed := deviceIDOf(t, req)
+	if deviceID != "other" {
+		t.Fatalf("device ID = %q, want the last case-insensitive match", deviceID)
+	}
+	if string(replayed) != body {
+		t.Fatal("body not replayed intact")
+	}
+}
+
+func TestRequestDeviceIDStopsAtScanLimit(t *testing.T) {
+	body := `{"data":"` + strings.Repeat("A", 4*deviceScanLimit) + `","device_id":"mine"}`
+	req := httptest.NewRequest(http.MethodPost, "/send-media-base64", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	deviceID, replayed := deviceIDOf(t, req)
+	if deviceID != "" {
+		t.Fatalf("device ID = %q, want none past the scan limit", deviceID)
+	}
+	if string(replayed) != body {
+		t.Fatal("body not replayed intact")
+	}
+}
+
+func TestRequestDeviceIDMultipart(t *testing.T) {
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	form.WriteField("device_id", "mine")
+	file, _ := form.CreateFormFile("file", "photo.jpg")
+	file.Write(bytes.Repeat([]byte{0xff}, 2*deviceScanLimit))
+	form.Close()
+	body := buf.String()
+
+	req := httptest.NewRequest(http.MethodPost, "/send-media", strings.NewReader(body))
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	deviceID, replayed := deviceIDOf(t, req)
+	if deviceID != "mine" {
+		t.Fatalf("device ID = %q, want mine", deviceID)
+	}
+	if string(replayed) != body {
+		t.Fatal("body not replayed intact")
+	}
+}
+
+func TestRateLimiterRefillsOverTime(t *testing.T) {
+	limiter := &RateLimiter{buckets: make(map[string]*tokenBucket), rate: 1, burst: 3}
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.Allow("dev"); !ok {
+			t.Fatalf("request %d of the burst was limited", i+1)
+		}
+	}
+	ok, wait := limiter.Allow("dev")
+	if ok || wait <= 0 || wait > time.Second {
+		t.Fatalf("Allow after the burst = %v, %s; want limited for up to a second", ok, wait)
+	}
+
+	// Move the last refill back instead of sleeping: 1.5s adds 1.5 tokens
+	limiter.buckets["dev"].last = limiter.buckets["dev"].last.Add(-1500 * time.Millisecond)
+	if ok, _ := limiter.Allow("dev"); !ok {
+		t.Fatal("no token refilled after 1.5s")
+	}
+	if ok, _ := limiter.Allow("dev"); ok {
+		t.Fatal("refill added more than the elapsed time allows")
+	}
+
+	// Refill never goes past the burst
+	limiter.buckets["dev"].last = limiter.buckets["dev"].last.Add(-time.Hour)
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.Allow("dev"); !ok {
+			t.Fatalf("request %d after a long idle was limited", i+1)
+		}
+	}
+	if ok, _ := limiter.Allow("dev"); ok {
+		t.Fatal("bucket refilled past its burst")
+	}
+}
+
+func TestRateLimitWaitChargesPerMessage(t *testing.T) {
+	t.Setenv("RATE_LIMIT_PER_MINUTE", "600")
+	t.Setenv("RATE_LIMIT_PER_TOKEN_PER_MINUTE", "")
+	gin.SetMode(gin.TestMode)
+
+	var wait func()
+	router := gin.New()
+	router.POST("/send-bulk/:device_id", RateLimitMiddleware(), func(c *gin.Context) {
+		wait = utils.RateLimitWait(c)
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/send-bulk/dev", nil))
+	if wait == nil {
+		t.Fatal("handler did not run")
+	}
+
+	// 600 per minute is a burst of 600; the request took one and 598 more fit
+	start := time.Now()
+	for i := 0; i < 599; i++ {
+		wait()
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Fatalf("waits within the burst took %s", elapsed)
+	}
+
+	// The next message waits for the refill of 10 tokens per second
+	start = time.Now()
+	wait()
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("wait past the burst returned after %s, want about 100ms", elapsed)
+	}
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+	"waku/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request ID in requests and responses
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied request IDs
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware accepts the caller's X-Request-ID or generates one,
+// stores it on the context and echoes it in the response header
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+
+		c.Set(utils.RequestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// LoggerMiddleware logs each request with its request ID
+func LoggerMiddleware() gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		requestID, _ := param.Keys[utils.RequestIDKey].(string)
+		return fmt.Sprintf("[GIN] %s | %3d | %13v | %15s | %-7s %s | request_id=%s %s\n",
+			param.TimeStamp.Format(time.RFC3339),
+			param.StatusCode,
+			param.Latency,
+			param.ClientIP,
+			param.Method,
+			logPath(param),
+			requestID,
+			param.ErrorMessage,
+		)
+	})
+}
+
+// logPath returns the request path and query with the WebSocket ?token= removed,
+// so API tokens never reach the access log
+func logPath(param gin.LogFormatterParams) string {
+	if param.Request == nil {
+		return param.Path
+	}
+	query := param.Request.URL.Query()
+	if !query.Has("token") {
+		return param.Path
+	}
+	query.Del("token")
+	if len(query) == 0 {
+		return param.Request.URL.Path
+	}
+	return param.Request.URL.Path + "?" + query.Encode()
+}
+
+// validRequestID accepts short printable ASCII IDs
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID returns a random 128-bit hex ID
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
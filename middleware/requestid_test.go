@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLoggerMiddlewareOmitsQueryToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var logs bytes.Buffer
+	defaultWriter := gin.DefaultWriter
+	gin.DefaultWriter = &logs
+	defer func() { gin.DefaultWriter = defaultWriter }()
+
+	router := gin.New()
+	router.Use(LoggerMiddleware())
+	router.GET("/ws/:device_id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ws/mine?token=secret-token&events=message", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ws/mine?token=secret-token", nil))
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("logged %d lines, want 2:\n%s", len(lines), logs.String())
+	}
+	if strings.Contains(logs.String(), "secret-token") {
+		t.Fatalf("access log contains the API token:\n%s", logs.String())
+	}
+	if !strings.Contains(lines[0], " /ws/mine?events=message |") {
+		t.Errorf("other query parameters dropped: %s", lines[0])
+	}
+	if !strings.Contains(lines[1], " /ws/mine |") {
+		t.Errorf("path not logged: %s", lines[1])
+	}
+}
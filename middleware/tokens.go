@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"waku/utils"
+)
+
+// wildcardDevice in a token's device list grants access to every device
+const wildcardDevice = "*"
+
+// tokenEntry is one API token and the devices it may operate on
+type tokenEntry struct {
+	Name    string   `json:"name"`
+	Token   string   `json:"token"`
+	Devices []string `json:"devices"`
+}
+
+// scopedToken pairs a token with its resolved scope
+type scopedToken struct {
+	token string
+	scope *utils.TokenScope
+}
+
+// loadTokens reads the API tokens: API_TOKEN as an admin token, plus the
+// scoped tokens in the API_TOKENS_FILE JSON file and the API_TOKENS JSON value
+func loadTokens() ([]scopedToken, error) {
+	var tokens []scopedToken
+	if token := os.Getenv("API_TOKEN"); token != "" {
+		tokens = append(tokens, scopedToken{token: token, scope: &utils.TokenScope{Name: "API_TOKEN", All: true}})
+	}
+
+	var entries []tokenEntry
+	if path := os.Getenv("API_TOKENS_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read API_TOKENS_FILE: %v", err)
+		}
+		var fromFile []tokenEntry
+		if err := json.Unmarshal(data, &fromFile); err != nil {
+			return nil, fmt.Errorf("invalid API_TOKENS_FILE: %v", err)
+		}
+		entries = append(entries, fromFile...)
+	}
+	if raw := os.Getenv("API_TOKENS"); raw != "" {
+		var fromEnv []tokenEntry
+		if err := json.Unmarshal([]byte(raw), &fromEnv); err != nil {
+			return nil, fmt.Errorf("invalid API_TOKENS: %v", err)
+		}
+		entries = append(entries, fromEnv...)
+	}
+
+	for i, entry := range entries {
+		if entry.Token == "" {
+			return nil, fmt.Errorf("API token %d has no token", i+1)
+		}
+		scope := &utils.TokenScope{Name: entry.Name, Devices: make(map[string]bool)}
+		if scope.Name == "" {
+			scope.Name = fmt.Sprintf("token-%d", i+1)
+		}
+		for _, deviceID := range entry.Devices {
+			if deviceID == wildcardDevice {
+				scope.All = true
+			}
+			scope.Devices[deviceID] = true
+		}
+		tokens = append(tokens, scopedToken{token: entry.Token, scope: scope})
+	}
+
+	return tokens, nil
+}
+
+// mustLoadTokens loads the API tokens, exiting on a malformed token store so
+// a typo can't silently lock clients out or widen their access
+func mustLoadTokens() []scopedToken {
+	tokens, err := loadTokens()
+	if err != nil {
+		log.Fatalf("Failed to load API tokens: %v", err)
+	}
+	return tokens
+}
+
+// lookupToken returns the scope of a token, comparing against every known
+// token in constant time
+func lookupToken(tokens []scopedToken, token string) *utils.TokenScope {
+	var found *utils.TokenScope
+	for _, candidate := range tokens {
+		if subtle.ConstantTimeCompare([]byte(candidate.token), []byte(token)) == 1 {
+			found = candidate.scope
+		}
+	}
+	return found
+}
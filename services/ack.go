@@ -0,0 +1,136 @@
+package services
+
+import (
+	"os"
+	"strconv"
+
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// Delivery lifecycle webhook events
+const (
+	EventMessageAck       = "message_ack"
+	EventMessageDelivered = "message_delivered"
+	EventMessageRead      = "message_read"
+	EventReceipt          = "receipt"
+)
+
+// StatusServerAck is the status reported when the WhatsApp server accepts a send
+const StatusServerAck = "server_ack"
+
+// DeliveryEvent is the data of a message lifecycle webhook
+type DeliveryEvent struct {
+	MessageID   string `json:"message_id"`
+	Chat        string `json:"chat"`
+	MessageType string `json:"message_type"`
+	Status      string `json:"status"`
+	Timestamp   int64  `json:"timestamp"`
+	// OriginRequestID is the API request that sent the message
+	OriginRequestID string `json:"origin_request_id,omitempty"`
+}
+
+// newDeliveryEvent builds the webhook data for a tracked message reaching status
+func newDeliveryEvent(sent SentMessage, status string, timestamp int64) DeliveryEvent {
+	return DeliveryEvent{
+		MessageID:       sent.MessageID,
+		Chat:            sent.Chat,
+		MessageType:     sent.MessageType,
+		Status:          status,
+		Timestamp:       timestamp,
+		OriginRequestID: sent.OriginRequestID,
+	}
+}
+
+// ackEventsEnabled reports whether WEBHOOK_ACK_EVENTS is set
+func ackEventsEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("WEBHOOK_ACK_EVENTS"))
+	return enabled
+}
+
+// receiptEventsEnabled reports whether WEBHOOK_RECEIPT_EVENTS is set
+func receiptEventsEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("WEBHOOK_RECEIPT_EVENTS"))
+	return enabled
+}
+
+// rawReceiptsEnabled reports whether WEBHOOK_RAW_RECEIPTS is set
+func rawReceiptsEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("WEBHOOK_RAW_RECEIPTS"))
+	return enabled
+}
+
+// ReceiptPayload is the webhook payload of a delivery, read or played receipt
+type ReceiptPayload struct {
+	Event       string   `json:"event"`
+	DeviceID    string   `json:"device_id"`
+	DevicePhone string   `json:"device_phone"`
+	MessageIDs  []string `json:"message_ids"`
+	ReceiptType string   `json:"receipt_type"`
+	Chat        string   `json:"chat"`
+	From        string   `json:"from"`
+	Timestamp   int64    `json:"timestamp"`
+	IsGroup     bool     `json:"is_group"`
+	GroupJID    *string  `json:"group_jid"`
+}
+
+// newReceiptPayload builds the receipt webhook payload, or returns nil for
+// receipt types other than delivered, read and played
+func newReceiptPayload(deviceID string, receipt *events.Receipt) *ReceiptPayload {
+	receiptType := receiptStatus(receipt.Type)
+	if receiptType == "" {
+		return nil
+	}
+
+	payload := &ReceiptPayload{
+		Event:       EventReceipt,
+		DeviceID:    deviceID,
+		DevicePhone: devicePhone(deviceID),
+		MessageIDs:  receipt.MessageIDs,
+		ReceiptType: receiptType,
+		Chat:        receipt.Chat.String(),
+		From:        extractPhoneNumber(receipt.Sender),
+		Timestamp:   receipt.Timestamp.Unix(),
+		IsGroup:     receipt.IsGroup,
+	}
+	if receipt.IsGroup {
+		groupJID := extractPhoneNumber(receipt.Chat)
+		payload.GroupJID = &groupJID
+	}
+	return payload
+}
+
+// forwardReceipt sends every delivery, read and played receipt as a receipt
+// webhook when WEBHOOK_RAW_RECEIPTS is set, tracked message or not
+func forwardReceipt(deviceID string, receipt *events.Receipt) {
+	if !rawReceiptsEnabled() {
+		return
+	}
+	if payload := newReceiptPayload(deviceID, receipt); payload != nil {
+		GetWebhookService().forward(deviceID, payload)
+	}
+}
+
+// notifyServerAck sends a message_ack webhook for a message the server accepted
+func notifyServerAck(sent SentMessage) {
+	if !ackEventsEnabled() {
+		return
+	}
+
+	GetWebhookService().SendEvent(sent.DeviceID, EventMessageAck, newDeliveryEvent(sent, StatusServerAck, sent.SentAt))
+}
+
+// notifyReceipt sends message_delivered/message_read webhooks for messages a receipt advanced
+func notifyReceipt(deviceID string, receipt *events.Receipt, updated []SentMessage) {
+	if !receiptEventsEnabled() {
+		return
+	}
+
+	for _, sent := range updated {
+		event := EventMessageRead
+		if sent.Status == StatusDelivered {
+			event = EventMessageDelivered
+		}
+
+		GetWebhookService().SendEvent(deviceID, event, newDeliveryEvent(sent, sent.Status, receipt.Timestamp.Unix()))
+	}
+}
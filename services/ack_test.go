@@ -0,0 +1,113 @@
+package services
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestForwardReceipt(t *testing.T) {
+	received := make(chan ReceiptPayload, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var payload ReceiptPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			t.Errorf("webhook body %s: %v", body, err)
+		}
+		received <- payload
+	}))
+	defer server.Close()
+
+	t.Setenv("WEBHOOK_ENABLED", "true")
+	t.Setenv("WEBHOOK_URL", server.URL)
+	t.Setenv("WEBHOOK_RAW_RECEIPTS", "true")
+	previous := webhookService
+	defer func() { webhookService = previous }()
+	if err := InitWebhookService(AppLogger()); err != nil {
+		t.Fatal(err)
+	}
+
+	sender := types.NewJID("628222222222", types.DefaultUserServer)
+	group := types.NewJID("120363000000000001", types.GroupServer)
+	at := time.Unix(1700000000, 0)
+	groupJID := "120363000000000001"
+
+	tests := []struct {
+		name    string
+		receipt *events.Receipt
+		want    ReceiptPayload
+	}{
+		{
+			name: "delivered",
+			receipt: &events.Receipt{
+				MessageSource: types.MessageSource{Chat: sender, Sender: sender},
+				MessageIDs:    []string{"MSG1", "MSG2"},
+				Timestamp:     at,
+				Type:          types.ReceiptTypeDelivered,
+			},
+			want: ReceiptPayload{Event: EventReceipt, DeviceID: "device001", MessageIDs: []string{"MSG1", "MSG2"}, ReceiptType: StatusDelivered,
+				Chat: "628222222222@s.whatsapp.net", From: "628222222222", Timestamp: at.Unix()},
+		},
+		{
+			name: "read in group",
+			receipt: &events.Receipt{
+				MessageSource: types.MessageSource{Chat: group, Sender: sender, IsGroup: true},
+				MessageIDs:    []string{"MSG3"},
+				Timestamp:     at,
+				Type:          types.ReceiptTypeRead,
+			},
+			want: ReceiptPayload{Event: EventReceipt, DeviceID: "device001", MessageIDs: []string{"MSG3"}, ReceiptType: StatusRead,
+				Chat: "120363000000000001@g.us", From: "628222222222", Timestamp: at.Unix(), IsGroup: true, GroupJID: &groupJID},
+		},
+		{
+			name: "played",
+			receipt: &events.Receipt{
+				MessageSource: types.MessageSource{Chat: sender, Sender: sender},
+				MessageIDs:    []string{"MSG4"},
+				Timestamp:     at,
+				Type:          types.ReceiptTypePlayed,
+			},
+			want: ReceiptPayload{Event: EventReceipt, DeviceID: "device001", MessageIDs: []string{"MSG4"}, ReceiptType: StatusPlayed,
+				Chat: "628222222222@s.whatsapp.net", From: "628222222222", Timestamp: at.Unix()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forwardReceipt("device001", tt.receipt)
+			select {
+			case got := <-received:
+				gotJSON, _ := json.Marshal(got)
+				wantJSON, _ := json.Marshal(tt.want)
+				if string(gotJSON) != string(wantJSON) {
+					t.Fatalf("forwarded %s\nwant      %s", gotJSON, wantJSON)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("receipt was not forwarded")
+			}
+		})
+	}
+
+	t.Run("ignored type", func(t *testing.T) {
+		ignored := &events.Receipt{
+			MessageSource: types.MessageSource{Chat: sender, Sender: sender},
+			MessageIDs:    []string{"MSG5"},
+			Timestamp:     at,
+			Type:          types.ReceiptTypeRetry,
+		}
+		if payload := newReceiptPayload("device001", ignored); payload != nil {
+			t.Fatalf("retry receipt built a payload: %+v", payload)
+		}
+		forwardReceipt("device001", ignored)
+		select {
+		case got := <-received:
+			t.Fatalf("retry receipt was forwarded: %+v", got)
+		case <-time.After(200 * time.Millisecond):
+		}
+	})
+}
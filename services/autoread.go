@@ -0,0 +1,50 @@
+package services
+
+import (
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// DefaultTypingSeconds is how long the typing indicator shows when a device doesn't set one
+const DefaultTypingSeconds = 5
+
+// MaxTypingSeconds caps the typing indicator; WhatsApp clears it on its own after about 25 seconds
+const MaxTypingSeconds = 25
+
+// acknowledgeIncoming marks an incoming message as read and shows the typing
+// indicator in its chat when the device has auto-read enabled, so users see a
+// natural reaction while the webhook consumer works on a reply
+func (dc *DeviceClient) acknowledgeIncoming(evt *events.Message) {
+	config := dc.GetConfig()
+	if !config.AutoRead || evt.Info.IsFromMe || evt.Info.Chat == types.StatusBroadcastJID {
+		return
+	}
+
+	if err := dc.Client.MarkRead([]types.MessageID{evt.Info.ID}, time.Now(), evt.Info.Chat, evt.Info.Sender); err != nil {
+		dc.Client.Log.Warnf("Failed to mark message %s as read: %v", evt.Info.ID, err)
+		return
+	}
+
+	seconds := config.TypingSeconds
+	if seconds == 0 {
+		seconds = DefaultTypingSeconds
+	}
+	if seconds < 0 {
+		return
+	}
+
+	chat := evt.Info.Chat
+	if err := dc.Client.SendChatPresence(chat, types.ChatPresenceComposing, types.ChatPresenceMediaText); err != nil {
+		dc.Client.Log.Warnf("Failed to send typing indicator to %s: %v", chat, err)
+		return
+	}
+
+	// Stop typing after the configured duration; a reply sent earlier clears it anyway
+	time.AfterFunc(time.Duration(seconds)*time.Second, func() {
+		if err := dc.Client.SendChatPresence(chat, types.ChatPresencePaused, types.ChatPresenceMediaText); err != nil {
+			dc.Client.Log.Warnf("Failed to clear typing indicator in %s: %v", chat, err)
+		}
+	})
+}
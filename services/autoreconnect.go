@@ -0,0 +1,152 @@
+package services
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+)
+
+// Auto-reconnect backoff defaults
+const (
+	defaultReconnectInitialDelay = 2 * time.Second
+	defaultReconnectMaxDelay     = 5 * time.Minute
+)
+
+// reconnectState tracks the automatic reconnect attempts of a session
+type reconnectState struct {
+	mu       sync.Mutex
+	running  bool
+	attempts int
+	stop     chan struct{}
+}
+
+// ReconnectStatus is a snapshot of a session's automatic reconnect attempts
+type ReconnectStatus struct {
+	Reconnecting bool `json:"reconnecting"`
+	Attempts     int  `json:"attempts"`
+}
+
+// reconnectBackoff returns RECONNECT_INITIAL_DELAY_SECONDS,
+// RECONNECT_MAX_DELAY_SECONDS and RECONNECT_MAX_ATTEMPTS (0 = unlimited)
+func reconnectBackoff() (initial, max time.Duration, maxAttempts int) {
+	initial, max = defaultReconnectInitialDelay, defaultReconnectMaxDelay
+	if seconds, err := strconv.Atoi(os.Getenv("RECONNECT_INITIAL_DELAY_SECONDS")); err == nil && seconds > 0 {
+		initial = time.Duration(seconds) * time.Second
+	}
+	if seconds, err := strconv.Atoi(os.Getenv("RECONNECT_MAX_DELAY_SECONDS")); err == nil && seconds > 0 {
+		max = time.Duration(seconds) * time.Second
+	}
+	if max < initial {
+		max = initial
+	}
+	if attempts, err := strconv.Atoi(os.Getenv("RECONNECT_MAX_ATTEMPTS")); err == nil && attempts > 0 {
+		maxAttempts = attempts
+	}
+	return initial, max, maxAttempts
+}
+
+// ReconnectStatus returns the session's automatic reconnect attempts
+func (dc *DeviceClient) ReconnectStatus() ReconnectStatus {
+	dc.reconnect.mu.Lock()
+	defer dc.reconnect.mu.Unlock()
+	return ReconnectStatus{Reconnecting: dc.reconnect.running, Attempts: dc.reconnect.attempts}
+}
+
+// startAutoReconnect begins reconnecting a session that dropped unexpectedly,
+// unless it was logged out or a reconnect loop is already running
+func (s *WhatsAppService) startAutoReconnect(dc *DeviceClient) {
+	if dc.Client.Store.ID == nil {
+		return
+	}
+
+	dc.reconnect.mu.Lock()
+	if dc.reconnect.running {
+		dc.reconnect.mu.Unlock()
+		return
+	}
+	dc.reconnect.running = true
+	dc.reconnect.attempts = 0
+	stop := make(chan struct{})
+	dc.reconnect.stop = stop
+	dc.reconnect.mu.Unlock()
+
+	go s.autoReconnect(dc, stop)
+}
+
+// dropDeadSocket tears down a socket whose keepalives have failed for longer
+// than whatsmeow.KeepAliveMaxFailTime and starts the reconnect loop. whatsmeow
+// only does this itself when EnableAutoReconnect is set, which is off so that
+// reconnects go through startAutoReconnect; its Disconnect emits no
+// Disconnected event, so the bookkeeping is done here.
+func (s *WhatsAppService) dropDeadSocket(dc *DeviceClient) {
+	if dc.ReconnectStatus().Reconnecting {
+		return
+	}
+
+	s.logger.Warnf("Keepalives of device %s failing for over %s, reconnecting", dc.DeviceID, whatsmeow.KeepAliveMaxFailTime)
+	dc.Client.Disconnect()
+	dc.markDisconnected()
+	dc.phone.setConnection(ConnectionOffline)
+	go notifyLifecycle(dc, EventDeviceDisconnected, "disconnected", "")
+	s.publishConnection(dc, "disconnected")
+	s.startAutoReconnect(dc)
+}
+
+// stopAutoReconnect cancels a running reconnect loop
+func (dc *DeviceClient) stopAutoReconnect() {
+	dc.reconnect.mu.Lock()
+	defer dc.reconnect.mu.Unlock()
+	if dc.reconnect.running {
+		close(dc.reconnect.stop)
+		dc.reconnect.running = false
+	}
+}
+
+// autoReconnect retries Connect with exponential backoff until the session is
+// back, is logged out or deleted, or RECONNECT_MAX_ATTEMPTS is reached
+func (s *WhatsAppService) autoReconnect(dc *DeviceClient, stop chan struct{}) {
+	initial, max, maxAttempts := reconnectBackoff()
+	delay := initial
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-time.After(delay):
+		}
+
+		// Stop once the session is gone, logged out or connected by other means
+		if current, err := s.GetSession(dc.DeviceID); err != nil || current != dc || dc.Client.Store.ID == nil || dc.Client.IsConnected() {
+			dc.stopAutoReconnect()
+			return
+		}
+
+		dc.reconnect.mu.Lock()
+		dc.reconnect.attempts++
+		attempt := dc.reconnect.attempts
+		dc.reconnect.mu.Unlock()
+
+		s.logger.Infof("Reconnecting device %s (attempt %d)", dc.DeviceID, attempt)
+		err := dc.Client.Connect()
+		if err == nil {
+			s.logger.Infof("Device %s reconnected after %d attempt(s)", dc.DeviceID, attempt)
+			dc.stopAutoReconnect()
+			return
+		}
+		s.logger.Warnf("Reconnect attempt %d for device %s failed: %v", attempt, dc.DeviceID, err)
+
+		if maxAttempts > 0 && attempt >= maxAttempts {
+			s.logger.Errorf("Giving up reconnecting device %s after %d attempts", dc.DeviceID, attempt)
+			dc.stopAutoReconnect()
+			return
+		}
+
+		delay *= 2
+		if delay > max {
+			delay = max
+		}
+	}
+}
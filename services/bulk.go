@@ -0,0 +1,121 @@
+package services
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultBulkMaxRecipients  = 500
+	defaultBulkMaxConcurrency = 5
+	defaultBulkMaxDelay       = time.Minute
+)
+
+// BulkLimits are the server-side bounds on bulk send requests
+type BulkLimits struct {
+	MaxRecipients  int `json:"max_recipients"`
+	MaxConcurrency int `json:"max_concurrency"`
+	MinDelayMs     int `json:"min_delay_ms"`
+	MaxDelayMs     int `json:"max_delay_ms"`
+}
+
+// envInt reads a non-negative integer environment variable, falling back when unset or invalid
+func envInt(name string, fallback int) int {
+	n, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || n < 0 {
+		return fallback
+	}
+	return n
+}
+
+// GetBulkLimits reads the BULK_* limits from the environment
+func GetBulkLimits() BulkLimits {
+	limits := BulkLimits{
+		MaxRecipients:  envInt("BULK_MAX_RECIPIENTS", defaultBulkMaxRecipients),
+		MaxConcurrency: envInt("BULK_MAX_CONCURRENCY", defaultBulkMaxConcurrency),
+		MinDelayMs:     envInt("BULK_MIN_DELAY_MS", 0),
+		MaxDelayMs:     envInt("BULK_MAX_DELAY_MS", int(defaultBulkMaxDelay.Milliseconds())),
+	}
+	if limits.MaxConcurrency == 0 {
+		limits.MaxConcurrency = 1
+	}
+	if limits.MaxDelayMs < limits.MinDelayMs {
+		limits.MaxDelayMs = limits.MinDelayMs
+	}
+	return limits
+}
+
+// BulkRecipient is one message of a bulk send
+type BulkRecipient struct {
+	Phone   string
+	Message string
+}
+
+// BulkOptions controls the pacing of a bulk send
+type BulkOptions struct {
+	// Concurrency is the number of parallel senders
+	Concurrency int
+	// Delay is the pause each sender takes after every message
+	Delay time.Duration
+	Send  SendOptions
+	// OnResult is called with each recipient's result as it completes
+	OnResult func(i int, result BulkResult)
+	// Wait blocks until the rate limits allow another message. The request
+	// already paid for the first one, so it isn't called for that.
+	Wait func()
+}
+
+// BulkResult is the outcome of one bulk send message
+type BulkResult struct {
+	Phone     string `json:"phone"`
+	MessageID string `json:"message_id,omitempty"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// SendBulk sends text messages to many recipients through a pool of
+// opts.Concurrency workers and returns one result per recipient, in order
+func (s *WhatsAppService) SendBulk(deviceID string, recipients []BulkRecipient, opts BulkOptions) []BulkResult {
+	results := make([]BulkResult, len(recipients))
+	next := make(chan int)
+
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				r := recipients[i]
+				if i > 0 && opts.Wait != nil {
+					opts.Wait()
+				}
+				messageID, timestamp, err := s.SendMessage(deviceID, r.Phone, r.Message, opts.Send)
+				results[i] = BulkResult{Phone: r.Phone, MessageID: messageID, Timestamp: timestamp}
+				if err != nil {
+					results[i].Error = err.Error()
+				}
+				if opts.OnResult != nil {
+					opts.OnResult(i, results[i])
+				}
+				if opts.Delay > 0 {
+					time.Sleep(opts.Delay)
+				}
+			}
+		}()
+	}
+
+	for i := range recipients {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	return results
+}
@@ -0,0 +1,27 @@
+package services
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestSendBulkWaitsPerExtraRecipient(t *testing.T) {
+	t.Setenv("AUTO_CREATE_SESSION", "false")
+	s := &WhatsAppService{clients: make(map[string]*DeviceClient), logger: AppLogger()}
+
+	recipients := make([]BulkRecipient, 10)
+	for i := range recipients {
+		recipients[i] = BulkRecipient{Phone: "628123456789", Message: "hi"}
+	}
+
+	var waits atomic.Int32
+	results := s.SendBulk("missing", recipients, BulkOptions{Concurrency: 3, Wait: func() { waits.Add(1) }})
+
+	if len(results) != len(recipients) {
+		t.Fatalf("got %d results, want %d", len(results), len(recipients))
+	}
+	// The request itself paid for the first message
+	if got := waits.Load(); got != int32(len(recipients)-1) {
+		t.Fatalf("Wait called %d times, want %d", got, len(recipients)-1)
+	}
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// ttlCache is a small thread-safe map whose entries expire after a fixed TTL
+type ttlCache[V any] struct {
+	ttl     time.Duration
+	mu      sync.RWMutex
+	entries map[string]ttlCacheEntry[V]
+}
+
+type ttlCacheEntry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// ttlCacheMinSweepInterval bounds how often a cache with a short TTL is swept
+const ttlCacheMinSweepInterval = time.Minute
+
+// newTTLCache creates a cache whose entries live for ttl and starts the
+// periodic sweep of expired entries
+func newTTLCache[V any](ttl time.Duration) *ttlCache[V] {
+	c := &ttlCache[V]{
+		ttl:     ttl,
+		entries: make(map[string]ttlCacheEntry[V]),
+	}
+	go c.sweepLoop(max(ttl, ttlCacheMinSweepInterval))
+	return c
+}
+
+// Get returns the cached value for key if it exists and hasn't expired
+func (c *ttlCache[V]) Get(key string) (V, bool) {
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+
+	if !ok || time.Now().After(entry.expiresAt) {
+		var zero V
+		return zero, false
+	}
+	return entry.value, true
+}
+
+// Set stores value under key
+func (c *ttlCache[V]) Set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = ttlCacheEntry[V]{
+		value:     value,
+		expiresAt: time.Now().Add(c.ttl),
+	}
+}
+
+// sweepLoop drops expired entries every interval; Get already ignores them,
+// so this only bounds memory
+func (c *ttlCache[V]) sweepLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		c.sweep()
+	}
+}
+
+// sweep drops every expired entry
+func (c *ttlCache[V]) sweep() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for key, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// Len returns the number of unexpired entries
+func (c *ttlCache[V]) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	now := time.Now()
+	n := 0
+	for _, entry := range c.entries {
+		if !now.After(entry.expiresAt) {
+			n++
+		}
+	}
+	return n
+}
+
+// Delete removes key from the cache
+func (c *ttlCache[V]) Delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// lruCache is a thread-safe cache bounded by entry count, evicting the least
+// recently used entry when full; entries also expire after a fixed TTL
+type lruCache[V any] struct {
+	ttl      time.Duration
+	capacity int
+	mu       sync.Mutex
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+type lruCacheEntry[V any] struct {
+	key       string
+	value     V
+	expiresAt time.Time
+}
+
+// newLRUCache creates a cache holding at most capacity entries for ttl each
+func newLRUCache[V any](capacity int, ttl time.Duration) *lruCache[V] {
+	return &lruCache[V]{
+		ttl:      ttl,
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Get returns the cached value for key if it exists and hasn't expired, marking it recently used
+func (c *lruCache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	elem, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+
+	entry := elem.Value.(*lruCacheEntry[V])
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return zero, false
+	}
+
+	c.order.MoveToFront(elem)
+	return entry.value, true
+}
+
+// Set stores value under key, evicting the least recently used entry when full
+func (c *lruCache[V]) Set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := time.Now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*lruCacheEntry[V])
+		entry.value = value
+		entry.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruCacheEntry[V]{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruCacheEntry[V]).key)
+	}
+}
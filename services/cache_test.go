@@ -0,0 +1,38 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTTLCacheSweep(t *testing.T) {
+	cache := newTTLCache[int](time.Hour)
+	cache.Set("fresh", 1)
+	cache.Set("stale", 2)
+
+	cache.mu.Lock()
+	stale := cache.entries["stale"]
+	stale.expiresAt = time.Now().Add(-time.Second)
+	cache.entries["stale"] = stale
+	cache.mu.Unlock()
+
+	if _, ok := cache.Get("stale"); ok {
+		t.Fatal("expired entry returned before the sweep")
+	}
+	// Writes no longer walk the map, so the expired entry stays until the sweep
+	cache.Set("another", 3)
+	if len(cache.entries) != 3 {
+		t.Fatalf("cache holds %d entries before the sweep, want 3", len(cache.entries))
+	}
+
+	cache.sweep()
+	if _, ok := cache.entries["stale"]; ok {
+		t.Fatal("sweep kept the expired entry")
+	}
+	if v, ok := cache.Get("fresh"); !ok || v != 1 {
+		t.Fatalf("Get(fresh) = %d, %v after the sweep", v, ok)
+	}
+	if cache.Len() != 2 {
+		t.Fatalf("Len = %d, want 2", cache.Len())
+	}
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultChatBufferSize is how many recent messages are kept per chat when CHAT_BUFFER_SIZE is unset
+	defaultChatBufferSize = 50
+	// chatBufferChats caps how many chats have a buffer; the least recently active are dropped
+	chatBufferChats = 1000
+	// chatBufferTTL drops the buffer of a chat without activity for this long
+	chatBufferTTL = 24 * time.Hour
+)
+
+// chatBuffer keeps the most recent messages of each chat in memory, so chat
+// history works without the message store
+type chatBuffer struct {
+	mu    sync.Mutex
+	size  int
+	chats *lruCache[[]StoredMessage]
+}
+
+func newChatBuffer(size int) *chatBuffer {
+	return &chatBuffer{size: size, chats: newLRUCache[[]StoredMessage](chatBufferChats, chatBufferTTL)}
+}
+
+// chatBufferSize reads CHAT_BUFFER_SIZE, the number of messages kept per chat; 0 disables the buffer
+func chatBufferSize() int {
+	return envInt("CHAT_BUFFER_SIZE", defaultChatBufferSize)
+}
+
+func chatBufferKey(deviceID, chatJID string) string {
+	return deviceID + "|" + chatJID
+}
+
+// add merges messages into their chats' buffers, keeping the newest of each chat
+func (b *chatBuffer) add(messages ...StoredMessage) {
+	if b.size <= 0 || len(messages) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	byChat := make(map[string][]StoredMessage)
+	for _, msg := range messages {
+		key := chatBufferKey(msg.DeviceID, msg.ChatJID)
+		byChat[key] = append(byChat[key], msg)
+	}
+
+	for key, incoming := range byChat {
+		buffered, _ := b.chats.Get(key)
+
+		seen := make(map[string]bool, len(buffered)+len(incoming))
+		merged := make([]StoredMessage, 0, len(buffered)+len(incoming))
+		for _, msg := range append(buffered, incoming...) {
+			if !seen[msg.MessageID] {
+				seen[msg.MessageID] = true
+				merged = append(merged, msg)
+			}
+		}
+
+		sort.SliceStable(merged, func(i, j int) bool { return merged[i].Timestamp < merged[j].Timestamp })
+		if len(merged) > b.size {
+			merged = merged[len(merged)-b.size:]
+		}
+		b.chats.Set(key, merged)
+	}
+}
+
+// get returns a chat's buffered messages, oldest first
+func (b *chatBuffer) get(deviceID, chatJID string) []StoredMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	buffered, _ := b.chats.Get(chatBufferKey(deviceID, chatJID))
+	return append([]StoredMessage(nil), buffered...)
+}
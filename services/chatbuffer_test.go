@@ -0,0 +1,53 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+)
+
+func BenchmarkChatBuffer(b *testing.B) {
+	const chats = 200
+	message := func(i int) StoredMessage {
+		return StoredMessage{
+			DeviceID:    "bench",
+			MessageID:   fmt.Sprintf("MSG%08d", i),
+			ChatJID:     fmt.Sprintf("62812%07d@s.whatsapp.net", i%chats),
+			Timestamp:   int64(i),
+			MessageType: "text",
+			Text:        "hello from the benchmark",
+		}
+	}
+
+	b.Run("add", func(b *testing.B) {
+		buffer := newChatBuffer(defaultChatBufferSize)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buffer.add(message(i))
+		}
+	})
+
+	b.Run("add-history-sync", func(b *testing.B) {
+		buffer := newChatBuffer(defaultChatBufferSize)
+		batch := make([]StoredMessage, 1000)
+		for i := range batch {
+			batch[i] = message(i)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			buffer.add(batch...)
+		}
+	})
+
+	b.Run("get", func(b *testing.B) {
+		buffer := newChatBuffer(defaultChatBufferSize)
+		for i := 0; i < chats*defaultChatBufferSize; i++ {
+			buffer.add(message(i))
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			buffer.get("bench", message(i).ChatJID)
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// DeviceConfig holds per-device settings persisted in the session directory
+type DeviceConfig struct {
+	// DisplayName is a human-friendly label for the device
+	DisplayName string `json:"display_name,omitempty"`
+	// Footer is appended to outgoing text messages and media captions
+	Footer string `json:"footer,omitempty"`
+	// WebhookURLs routes this device's webhooks to these targets instead of WEBHOOK_URL
+	WebhookURLs []string `json:"webhook_urls,omitempty"`
+	// WebhookHeaders are extra HTTP headers sent with this device's webhooks
+	WebhookHeaders map[string]string `json:"webhook_headers,omitempty"`
+	// MessageRetentionDays overrides MESSAGE_RETENTION_DAYS for this device; 0 keeps messages forever
+	MessageRetentionDays *int `json:"message_retention_days,omitempty"`
+	// AutoRead marks incoming messages as read and shows typing before they reach the webhook
+	AutoRead bool `json:"auto_read,omitempty"`
+	// TypingSeconds is how long the auto-read typing indicator lasts; 0 uses the default, -1 disables it
+	TypingSeconds int `json:"typing_seconds,omitempty"`
+}
+
+// DeviceConfigView is a DeviceConfig as the API echoes it. Webhook header
+// values may be credentials, so only their names are shown.
+type DeviceConfigView struct {
+	DeviceConfig
+	WebhookHeaders []string `json:"webhook_headers,omitempty"`
+}
+
+// View returns the config with the webhook header values left out
+func (c DeviceConfig) View() DeviceConfigView {
+	names := make([]string, 0, len(c.WebhookHeaders))
+	for name := range c.WebhookHeaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return DeviceConfigView{DeviceConfig: c, WebhookHeaders: names}
+}
+
+// MaxDisplayNameLength is the longest device display name accepted
+const MaxDisplayNameLength = 64
+
+// deviceConfigPath returns the metadata file path for a device
+func deviceConfigPath(deviceID string) string {
+	return filepath.Join(os.Getenv("SESSION_DIR"), deviceID, "meta.json")
+}
+
+// loadDeviceConfig reads the device metadata file, returning an empty config when it doesn't exist
+func loadDeviceConfig(deviceID string) (DeviceConfig, error) {
+	var config DeviceConfig
+
+	data, err := os.ReadFile(deviceConfigPath(deviceID))
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return config, fmt.Errorf("failed to read device config: %v", err)
+	}
+
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("failed to parse device config: %v", err)
+	}
+
+	return config, nil
+}
+
+// saveDeviceConfig writes the device metadata file atomically
+func saveDeviceConfig(deviceID string, config DeviceConfig) error {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode device config: %v", err)
+	}
+
+	path := deviceConfigPath(deviceID)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write device config: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to save device config: %v", err)
+	}
+
+	return nil
+}
+
+// GetConfig returns a copy of the device's current configuration
+func (dc *DeviceClient) GetConfig() DeviceConfig {
+	dc.configMu.RLock()
+	defer dc.configMu.RUnlock()
+	return dc.config
+}
+
+// UpdateDeviceConfig applies a change to a device's configuration and persists it
+func (s *WhatsAppService) UpdateDeviceConfig(deviceID string, update func(*DeviceConfig) error) (DeviceConfig, error) {
+	client, err := s.GetSession(deviceID)
+	if err != nil {
+		return DeviceConfig{}, err
+	}
+
+	client.configMu.Lock()
+	defer client.configMu.Unlock()
+
+	config := client.config
+	if err := update(&config); err != nil {
+		return client.config, err
+	}
+
+	if err := saveDeviceConfig(deviceID, config); err != nil {
+		return client.config, err
+	}
+
+	client.config = config
+	return config, nil
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeviceConfigRoundTripRedactsHeaders(t *testing.T) {
+	sessionDir := t.TempDir()
+	t.Setenv("SESSION_DIR", sessionDir)
+	if err := os.MkdirAll(filepath.Join(sessionDir, "dev"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &WhatsAppService{clients: map[string]*DeviceClient{"dev": {DeviceID: "dev"}}}
+	_, err := s.UpdateDeviceConfig("dev", func(config *DeviceConfig) error {
+		config.DisplayName = "Support"
+		config.WebhookHeaders = map[string]string{"Authorization": "Bearer s3cret", "X-Tenant": "acme"}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UpdateDeviceConfig: %v", err)
+	}
+
+	loaded, err := loadDeviceConfig("dev")
+	if err != nil {
+		t.Fatalf("loadDeviceConfig: %v", err)
+	}
+	if loaded.WebhookHeaders["Authorization"] != "Bearer s3cret" {
+		t.Fatalf("persisted headers = %v, want the values kept", loaded.WebhookHeaders)
+	}
+
+	data, err := json.Marshal(loaded.View())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "s3cret") || strings.Contains(string(data), "acme") {
+		t.Fatalf("view leaks header values: %s", data)
+	}
+
+	var echoed struct {
+		DisplayName    string   `json:"display_name"`
+		WebhookHeaders []string `json:"webhook_headers"`
+	}
+	if err := json.Unmarshal(data, &echoed); err != nil {
+		t.Fatal(err)
+	}
+	if echoed.DisplayName != "Support" || strings.Join(echoed.WebhookHeaders, ",") != "Authorization,X-Tenant" {
+		t.Fatalf("view = %s, want display name and sorted header names", data)
+	}
+}
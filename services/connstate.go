@@ -0,0 +1,42 @@
+package services
+
+import "time"
+
+// IsConnected reports whether the session is connected and logged in
+func (dc *DeviceClient) IsConnected() bool {
+	dc.stateMu.RLock()
+	defer dc.stateMu.RUnlock()
+	return dc.connected
+}
+
+// GetPhone returns the phone number the session is logged in as
+func (dc *DeviceClient) GetPhone() string {
+	dc.stateMu.RLock()
+	defer dc.stateMu.RUnlock()
+	return dc.phoneNumber
+}
+
+// GetConnectedAt returns when the session last connected
+func (dc *DeviceClient) GetConnectedAt() time.Time {
+	dc.stateMu.RLock()
+	defer dc.stateMu.RUnlock()
+	return dc.connectedAt
+}
+
+// MarkConnected records that the session connected, as phone when it is known
+func (dc *DeviceClient) MarkConnected(phone string) {
+	dc.stateMu.Lock()
+	defer dc.stateMu.Unlock()
+	dc.connected = true
+	dc.connectedAt = time.Now()
+	if phone != "" {
+		dc.phoneNumber = phone
+	}
+}
+
+// markDisconnected records that the session lost its connection
+func (dc *DeviceClient) markDisconnected() {
+	dc.stateMu.Lock()
+	defer dc.stateMu.Unlock()
+	dc.connected = false
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestConnStateConcurrentAccess exercises the connection state from the event
+// handler and API sides at once; run it with -race to check the locking.
+func TestConnStateConcurrentAccess(t *testing.T) {
+	dc := &DeviceClient{DeviceID: "dev"}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				dc.MarkConnected("628123456789")
+				dc.markDisconnected()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				_ = dc.IsConnected()
+				_ = dc.GetPhone()
+				_ = dc.GetConnectedAt()
+			}
+		}()
+	}
+	wg.Wait()
+
+	dc.MarkConnected("")
+	if !dc.IsConnected() || dc.GetPhone() != "628123456789" || dc.GetConnectedAt().IsZero() {
+		t.Fatalf("state after reconnect without a phone = %v %q %v", dc.IsConnected(), dc.GetPhone(), dc.GetConnectedAt())
+	}
+}
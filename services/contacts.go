@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"go.mau.fi/whatsmeow/appstate"
+)
+
+// contactSyncTimeout bounds a single contact app-state fetch
+const contactSyncTimeout = 60 * time.Second
+
+// contactSyncOnConnect reports whether contacts should be synced after each connect
+func contactSyncOnConnect() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("CONTACT_SYNC_ON_CONNECT"))
+	return enabled
+}
+
+// syncContacts fetches the contact list app-state patch, which lives in
+// critical_unblock_low, and returns the number of contacts in the store
+// afterwards. fullSync re-downloads the whole snapshot instead of new patches.
+func (s *WhatsAppService) syncContacts(ctx context.Context, dc *DeviceClient, fullSync bool) (int, error) {
+	if err := dc.Client.FetchAppState(ctx, appstate.WAPatchCriticalUnblockLow, fullSync, false); err != nil {
+		return 0, fmt.Errorf("failed to sync contacts: %w", err)
+	}
+	return countContacts(ctx, dc)
+}
+
+// countContacts returns the number of contacts in a session's store
+func countContacts(ctx context.Context, dc *DeviceClient) (int, error) {
+	contacts, err := dc.Client.Store.Contacts.GetAllContacts(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count contacts: %v", err)
+	}
+	return len(contacts), nil
+}
+
+// SyncContacts forces a full contact sync, waiting up to timeout. When the
+// sync doesn't finish in time the contacts stored so far are counted and
+// completed is false.
+func (s *WhatsAppService) SyncContacts(deviceID string, timeout time.Duration) (int, bool, error) {
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return 0, false, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	count, err := s.syncContacts(ctx, client, true)
+	if err == nil {
+		return count, true, nil
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return 0, false, err
+	}
+
+	count, err = countContacts(context.Background(), client)
+	if err != nil {
+		return 0, false, err
+	}
+	return count, false, nil
+}
+
+// syncContactsOnConnect proactively syncs contacts after a connect and
+// notifies the webhook when done
+func (s *WhatsAppService) syncContactsOnConnect(dc *DeviceClient) {
+	ctx, cancel := context.WithTimeout(context.Background(), contactSyncTimeout)
+	defer cancel()
+
+	started := time.Now()
+	count, err := s.syncContacts(ctx, dc, false)
+	if err != nil {
+		s.logger.Errorf("Contact sync for device %s failed: %v", dc.DeviceID, err)
+		return
+	}
+
+	s.logger.Infof("Contact sync for device %s completed: %d contacts in %v", dc.DeviceID, count, time.Since(started))
+	GetWebhookService().SendEvent(dc.DeviceID, "contacts_synced", map[string]interface{}{
+		"total": count,
+	})
+}
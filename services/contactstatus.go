@@ -0,0 +1,102 @@
+package services
+
+import (
+	"fmt"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// ContactStatus is a contact's about text and business details
+type ContactStatus struct {
+	Phone string `json:"phone"`
+	JID   string `json:"jid"`
+	// About is nil when the contact has none or hides it from this account.
+	// WhatsApp doesn't say which, and whatsmeow doesn't expose when it was set.
+	About        *string          `json:"about"`
+	PictureID    string           `json:"picture_id,omitempty"`
+	IsBusiness   bool             `json:"is_business"`
+	VerifiedName string           `json:"verified_name,omitempty"`
+	Business     *BusinessProfile `json:"business,omitempty"`
+}
+
+// BusinessProfile is the public profile of a WhatsApp Business account
+type BusinessProfile struct {
+	Address       string            `json:"address,omitempty"`
+	Email         string            `json:"email,omitempty"`
+	Categories    []string          `json:"categories,omitempty"`
+	Options       map[string]string `json:"options,omitempty"`
+	HoursTimeZone string            `json:"hours_time_zone,omitempty"`
+	Hours         []BusinessHours   `json:"hours,omitempty"`
+}
+
+// BusinessHours is a business's opening hours on one day
+type BusinessHours struct {
+	Day   string `json:"day"`
+	Mode  string `json:"mode"`
+	Open  string `json:"open,omitempty"`
+	Close string `json:"close,omitempty"`
+}
+
+// newBusinessProfile converts whatsmeow's business profile for the API
+func newBusinessProfile(profile *types.BusinessProfile) *BusinessProfile {
+	business := &BusinessProfile{
+		Address:       profile.Address,
+		Email:         profile.Email,
+		Options:       profile.ProfileOptions,
+		HoursTimeZone: profile.BusinessHoursTimeZone,
+	}
+	for _, category := range profile.Categories {
+		business.Categories = append(business.Categories, category.Name)
+	}
+	for _, hours := range profile.BusinessHours {
+		business.Hours = append(business.Hours, BusinessHours{
+			Day:   hours.DayOfWeek,
+			Mode:  hours.Mode,
+			Open:  hours.OpenTime,
+			Close: hours.CloseTime,
+		})
+	}
+	return business
+}
+
+// GetContactStatus fetches a contact's about text, plus its business profile
+// when it is a business account
+func (s *WhatsAppService) GetContactStatus(deviceID, phone string) (*ContactStatus, error) {
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	jid := types.NewJID(phone, types.DefaultUserServer)
+	infos, err := client.Client.GetUserInfo([]types.JID{jid})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user info: %v", err)
+	}
+	info, ok := infos[jid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotOnWhatsApp, phone)
+	}
+
+	status := &ContactStatus{
+		Phone:     phone,
+		JID:       jid.String(),
+		PictureID: info.PictureID,
+	}
+	if info.Status != "" {
+		status.About = &info.Status
+	}
+
+	if info.VerifiedName != nil {
+		status.IsBusiness = true
+		status.VerifiedName = info.VerifiedName.Details.GetVerifiedName()
+
+		// The about text is still useful without the profile, so don't fail on it
+		profile, err := client.Client.GetBusinessProfile(jid)
+		if err != nil {
+			s.logger.Warnf("Failed to get business profile of %s: %v", jid, err)
+		} else if profile != nil {
+			status.Business = newBusinessProfile(profile)
+		}
+	}
+	return status, nil
+}
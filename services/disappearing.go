@@ -0,0 +1,162 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// chatTimerCacheTTL is how long a disappearing timer seen in a personal chat is trusted
+const chatTimerCacheTTL = time.Hour
+
+// MaxDisappearingSeconds is the longest disappearing timer WhatsApp offers (90 days)
+const MaxDisappearingSeconds = 90 * 24 * 60 * 60
+
+// ErrInvalidDisappearingTimer is returned for a timer WhatsApp doesn't offer
+var ErrInvalidDisappearingTimer = errors.New("invalid disappearing timer")
+
+// DisappearingTimers are the chat timers WhatsApp allows, by the name the API accepts
+var DisappearingTimers = map[string]time.Duration{
+	"off": whatsmeow.DisappearingTimerOff,
+	"24h": whatsmeow.DisappearingTimer24Hours,
+	"7d":  whatsmeow.DisappearingTimer7Days,
+	"90d": whatsmeow.DisappearingTimer90Days,
+}
+
+// SetChatDisappearing sets the disappearing timer of a personal chat or group
+// and returns it in seconds. Changing a group's timer requires admin rights.
+func (s *WhatsAppService) SetChatDisappearing(deviceID, chatJID, duration string) (uint32, error) {
+	timer, ok := DisappearingTimers[duration]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q, use off, 24h, 7d or 90d", ErrInvalidDisappearingTimer, duration)
+	}
+
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return 0, err
+	}
+
+	chat, err := parseChatJID(chatJID)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidDisappearingTimer, err)
+	}
+	if chat.Server == types.GroupServer {
+		if chat, err = s.managedGroup(client, chat.String()); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := client.Client.SetDisappearingTimer(chat, timer, time.Time{}); err != nil {
+		return 0, fmt.Errorf("failed to set disappearing timer: %v", err)
+	}
+
+	seconds := uint32(timer / time.Second)
+	if chat.Server == types.GroupServer {
+		s.groupCache.Delete(client.DeviceID + "|" + chat.String())
+	} else {
+		s.chatTimers.Set(chatTimerKey(client.DeviceID, chat), seconds)
+	}
+	return seconds, nil
+}
+
+// contextInfoOf returns the context info of a message's content, or nil when it has none
+func contextInfoOf(msg *waProto.Message) *waProto.ContextInfo {
+	switch {
+	case msg.GetExtendedTextMessage() != nil:
+		return msg.GetExtendedTextMessage().GetContextInfo()
+	case msg.GetImageMessage() != nil:
+		return msg.GetImageMessage().GetContextInfo()
+	case msg.GetVideoMessage() != nil:
+		return msg.GetVideoMessage().GetContextInfo()
+	case msg.GetAudioMessage() != nil:
+		return msg.GetAudioMessage().GetContextInfo()
+	case msg.GetDocumentMessage() != nil:
+		return msg.GetDocumentMessage().GetContextInfo()
+	case msg.GetStickerMessage() != nil:
+		return msg.GetStickerMessage().GetContextInfo()
+	default:
+		return nil
+	}
+}
+
+// chatTimerKey scopes a chat's disappearing timer to its device
+func chatTimerKey(deviceID string, chat types.JID) string {
+	return deviceID + "|" + chat.ToNonAD().String()
+}
+
+// observeChatTimer learns a personal chat's disappearing timer from its messages,
+// since WhatsApp has no query for it outside groups
+func (s *WhatsAppService) observeChatTimer(deviceID string, evt *events.Message) {
+	if evt.Info.Chat.Server == types.GroupServer {
+		return
+	}
+
+	key := chatTimerKey(deviceID, evt.Info.Chat)
+	if protocol := evt.Message.GetProtocolMessage(); protocol.GetType() == waProto.ProtocolMessage_EPHEMERAL_SETTING {
+		s.chatTimers.Set(key, protocol.GetEphemeralExpiration())
+		return
+	}
+	if info := contextInfoOf(evt.Message); info != nil {
+		s.chatTimers.Set(key, info.GetExpiration())
+	}
+}
+
+// chatTimer returns the disappearing timer in seconds for a chat, 0 when off or unknown
+func (s *WhatsAppService) chatTimer(client *DeviceClient, chat types.JID) uint32 {
+	if chat.Server == types.GroupServer {
+		info, err := s.fetchGroupInfo(client, chat)
+		if err != nil || !info.IsEphemeral {
+			return 0
+		}
+		return info.DisappearingTimer
+	}
+
+	timer, _ := s.chatTimers.Get(chatTimerKey(client.DeviceID, chat))
+	return timer
+}
+
+// applyDisappearing makes an outgoing message follow the chat's disappearing
+// timer, or the explicit override in opts
+func (s *WhatsAppService) applyDisappearing(client *DeviceClient, chat types.JID, msg *waProto.Message, opts SendOptions) {
+	var timer uint32
+	if opts.Disappearing != nil {
+		timer = *opts.Disappearing
+	} else {
+		timer = s.chatTimer(client, chat)
+	}
+	if timer == 0 {
+		return
+	}
+
+	// Plain text can't carry context info, so it becomes an extended text message
+	if msg.Conversation != nil {
+		msg.ExtendedTextMessage = &waProto.ExtendedTextMessage{Text: msg.Conversation}
+		msg.Conversation = nil
+	}
+
+	info := contextInfoOf(msg)
+	if info == nil {
+		info = &waProto.ContextInfo{}
+		switch {
+		case msg.ExtendedTextMessage != nil:
+			msg.ExtendedTextMessage.ContextInfo = info
+		case msg.ImageMessage != nil:
+			msg.ImageMessage.ContextInfo = info
+		case msg.VideoMessage != nil:
+			msg.VideoMessage.ContextInfo = info
+		case msg.AudioMessage != nil:
+			msg.AudioMessage.ContextInfo = info
+		case msg.DocumentMessage != nil:
+			msg.DocumentMessage.ContextInfo = info
+		default:
+			return
+		}
+	}
+	info.Expiration = proto.Uint32(timer)
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrEditWindowExpired is returned when a message is too old to be edited
+var ErrEditWindowExpired = fmt.Errorf("messages can only be edited within %s of sending", whatsmeow.EditWindow)
+
+// EditMessage replaces the text of a message this device sent earlier
+func (s *WhatsAppService) EditMessage(deviceID, phone, groupJID, messageID, text string) (string, int64, error) {
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	jid, err := resolveRecipient(phone, groupJID)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := s.ensureGroupMember(client, jid); err != nil {
+		return "", 0, err
+	}
+
+	// Messages sent through this server are checked up front; others are left to WhatsApp
+	if sent, ok := s.tracker.get(deviceID, messageID); ok && time.Since(time.Unix(sent.SentAt, 0)) > whatsmeow.EditWindow {
+		return "", 0, ErrEditWindowExpired
+	}
+
+	// A server error can't be told apart from other rejections, so it isn't reported as an expired window
+	resp, err := client.Client.SendMessage(context.Background(), jid, buildEdit(client.Client, jid, messageID, text))
+	if errors.Is(err, whatsmeow.ErrServerReturnedError) {
+		return "", 0, fmt.Errorf("%w: edit refused (the message may not exist, not be from this device or be older than %s): %v", ErrMessageRejected, whatsmeow.EditWindow, err)
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to edit message: %v", err)
+	}
+
+	s.logger.Infof("Edited message %s on device %s", messageID, deviceID)
+	return resp.ID, resp.Timestamp.Unix(), nil
+}
+
+// buildEdit builds the protocol message replacing the text of messageID
+func buildEdit(cli *whatsmeow.Client, chat types.JID, messageID, text string) *waProto.Message {
+	return cli.BuildEdit(chat, messageID, &waProto.Message{Conversation: proto.String(text)})
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"testing"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestBuildEdit(t *testing.T) {
+	chat := types.NewJID("628123456789", types.DefaultUserServer)
+	msg := buildEdit(&whatsmeow.Client{}, chat, "3EB0ABCDEF", "fixed typo")
+
+	protocol := msg.GetEditedMessage().GetMessage().GetProtocolMessage()
+	if protocol == nil {
+		t.Fatalf("edit has no protocol message: %v", msg)
+	}
+	if protocol.GetType() != waProto.ProtocolMessage_MESSAGE_EDIT {
+		t.Errorf("type = %v, want MESSAGE_EDIT", protocol.GetType())
+	}
+
+	key := protocol.GetKey()
+	if key.GetID() != "3EB0ABCDEF" || key.GetRemoteJID() != chat.String() || !key.GetFromMe() {
+		t.Errorf("key = %v, want own message 3EB0ABCDEF in %s", key, chat)
+	}
+	if got := protocol.GetEditedMessage().GetConversation(); got != "fixed typo" {
+		t.Errorf("edited text = %q, want %q", got, "fixed typo")
+	}
+	if protocol.GetTimestampMS() == 0 {
+		t.Error("edit has no timestamp")
+	}
+}
@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// Webhook events for messages revoked by the server on a timer
+const (
+	EventMessageExpired = "message_expired"
+	EventMessageRevoked = "message_revoked"
+)
+
+// StatusRevoked is reported when a read message is revoked after its delay
+const StatusRevoked = "revoked"
+
+// MaxExpireAfter is the longest TTL accepted for expiring messages; tracked
+// delivery state isn't kept longer than this
+const MaxExpireAfter = sentMessageTTL
+
+// scheduleExpiry revokes a sent message if no delivered receipt arrives within ttl
+func (s *WhatsAppService) scheduleExpiry(client *DeviceClient, chat types.JID, messageID string, ttl time.Duration) {
+	time.AfterFunc(ttl, func() {
+		// The session may have been renamed since the message was sent
+		client := client.current()
+		sent, ok := s.tracker.expire(client.DeviceID, messageID)
+		if !ok {
+			return
+		}
+
+		if err := s.revoke(client, chat, messageID); err != nil {
+			s.logger.Errorf("Failed to revoke expired message %s on device %s: %v", messageID, client.DeviceID, err)
+			return
+		}
+
+		s.logger.Infof("Revoked message %s on device %s: not delivered within %s", messageID, client.DeviceID, ttl)
+		GetWebhookService().SendEvent(client.DeviceID, EventMessageExpired, newDeliveryEvent(sent, StatusExpired, time.Now().Unix()))
+	})
+}
+
+// scheduleReadRevokes starts the revoke timer of read messages sent with RevokeAfterRead
+func (s *WhatsAppService) scheduleReadRevokes(client *DeviceClient, updated []SentMessage) {
+	for _, sent := range updated {
+		if sent.Status != StatusRead && sent.Status != StatusPlayed {
+			continue
+		}
+
+		delay, ok := s.tracker.takeReadRevoke(client.DeviceID, sent.MessageID)
+		if !ok {
+			continue
+		}
+
+		chat, err := types.ParseJID(sent.Chat)
+		if err != nil {
+			continue
+		}
+
+		sent := sent
+		time.AfterFunc(delay, func() {
+			client := client.current()
+			if err := s.revoke(client, chat, sent.MessageID); err != nil {
+				s.logger.Errorf("Failed to revoke read message %s on device %s: %v", sent.MessageID, client.DeviceID, err)
+				return
+			}
+
+			s.logger.Infof("Revoked message %s on device %s %s after it was read", sent.MessageID, client.DeviceID, delay)
+			GetWebhookService().SendEvent(client.DeviceID, EventMessageRevoked, newDeliveryEvent(sent, StatusRevoked, time.Now().Unix()))
+		})
+	}
+}
+
+// revoke deletes a sent message for everyone
+func (s *WhatsAppService) revoke(client *DeviceClient, chat types.JID, messageID string) error {
+	_, err := client.Client.SendMessage(context.Background(), chat, client.Client.BuildRevoke(chat, types.EmptyJID, messageID))
+	return err
+}
@@ -0,0 +1,52 @@
+package services
+
+import "os"
+
+// feature is an optional capability reported by /capabilities and the admin report
+type feature struct {
+	name    string
+	enabled func() bool
+}
+
+func always() bool { return true }
+
+// features is the single list of optional capabilities. Add an entry here
+// with every new endpoint or option a client may need to detect.
+var features = []feature{
+	{"cta_buttons", always},
+	{"location_requests", always},
+	{"locations", always},
+	{"contacts", always},
+	{"polls", always},
+	{"voice_notes", always},
+	{"voice_note_conversion", func() bool { return os.Getenv("FFMPEG_PATH") != "" }},
+	{"view_once", always},
+	{"link_previews", always},
+	{"auto_link_previews", linkPreviewsEnabled},
+	{"edit", always},
+	{"revoke", always},
+	{"presence", always},
+	{"group_management", always},
+	{"bulk_send", always},
+	{"media_download", always},
+	{"chat_history", always},
+	{"disappearing_messages", always},
+	{"send_queue", sendQueueEnabled},
+	{"contact_sync", contactSyncOnConnect},
+	{"message_store", storeMessagesEnabled},
+	{"ack_webhooks", ackEventsEnabled},
+	{"receipt_webhooks", receiptEventsEnabled},
+	{"raw_receipt_webhooks", rawReceiptsEnabled},
+	{"lifecycle_webhooks", lifecycleEventsEnabled},
+	{"metrics", MetricsEnabled},
+	{"reactions", func() bool { return false }},
+}
+
+// Features reports which optional features are available in this build and configuration
+func Features() map[string]bool {
+	enabled := make(map[string]bool, len(features))
+	for _, f := range features {
+		enabled[f.name] = f.enabled()
+	}
+	return enabled
+}
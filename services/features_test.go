@@ -0,0 +1,16 @@
+package services
+
+import "testing"
+
+func TestFeaturesAreListedOnce(t *testing.T) {
+	seen := make(map[string]bool, len(features))
+	for _, f := range features {
+		if seen[f.name] {
+			t.Errorf("feature %q is listed twice", f.name)
+		}
+		seen[f.name] = true
+	}
+	if got := len(Features()); got != len(features) {
+		t.Fatalf("Features() reports %d features, want %d", got, len(features))
+	}
+}
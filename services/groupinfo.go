@@ -0,0 +1,120 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// ErrGroupNotFound is returned when a group doesn't exist
+var ErrGroupNotFound = errors.New("group not found")
+
+// GroupMember is a group participant with its role
+type GroupMember struct {
+	JID   string `json:"jid"`
+	Phone string `json:"phone,omitempty"`
+	LID   string `json:"lid,omitempty"`
+	// Role is member, admin or superadmin (the group's creator)
+	Role    string `json:"role"`
+	IsAdmin bool   `json:"is_admin"`
+}
+
+// GroupDetails is the full metadata of a group
+type GroupDetails struct {
+	JID         string `json:"group_jid"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Owner       string `json:"owner,omitempty"`
+	CreatedAt   int64  `json:"created_at"`
+	// Announce means only admins can send messages
+	Announce bool `json:"announce"`
+	// Locked means only admins can edit the group info
+	Locked              bool          `json:"locked"`
+	DisappearingSeconds uint32        `json:"disappearing_seconds"`
+	IsAdmin             bool          `json:"is_admin"`
+	ParticipantCount    int           `json:"participant_count"`
+	Participants        []GroupMember `json:"participants"`
+}
+
+// participantRole names a participant's role in a group
+func participantRole(participant types.GroupParticipant) string {
+	switch {
+	case participant.IsSuperAdmin:
+		return "superadmin"
+	case participant.IsAdmin:
+		return "admin"
+	default:
+		return "member"
+	}
+}
+
+// GetGroupDetails fetches a group's current metadata and participants from WhatsApp
+func (s *WhatsAppService) GetGroupDetails(deviceID, groupJID string) (*GroupDetails, error) {
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	jid, err := types.ParseJID(groupJID)
+	if err != nil || jid.Server != types.GroupServer {
+		return nil, fmt.Errorf("%w: invalid group JID %s", ErrInvalidGroup, groupJID)
+	}
+
+	info, err := client.Client.GetGroupInfo(jid)
+	switch {
+	case errors.Is(err, whatsmeow.ErrGroupNotFound):
+		return nil, fmt.Errorf("%w: %s", ErrGroupNotFound, jid)
+	case errors.Is(err, whatsmeow.ErrNotInGroup):
+		return nil, fmt.Errorf("%w: %s", ErrNotGroupMember, jid)
+	case err != nil:
+		return nil, fmt.Errorf("failed to get group info: %v", err)
+	}
+	s.groupCache.Set(client.DeviceID+"|"+jid.String(), info)
+
+	details := &GroupDetails{
+		JID:              info.JID.String(),
+		Name:             info.Name,
+		Description:      info.Topic,
+		CreatedAt:        info.GroupCreated.Unix(),
+		Announce:         info.IsAnnounce,
+		Locked:           info.IsLocked,
+		ParticipantCount: len(info.Participants),
+		Participants:     make([]GroupMember, 0, len(info.Participants)),
+	}
+	if !info.OwnerPN.IsEmpty() {
+		details.Owner = info.OwnerPN.String()
+	} else if !info.OwnerJID.IsEmpty() {
+		details.Owner = info.OwnerJID.String()
+	}
+	if info.IsEphemeral {
+		details.DisappearingSeconds = info.DisappearingTimer
+	}
+
+	for _, participant := range info.Participants {
+		member := groupMember(participant)
+		details.Participants = append(details.Participants, member)
+
+		if member.IsAdmin && (isOwnJID(client, participant.JID) || isOwnJID(client, participant.PhoneNumber) || isOwnJID(client, participant.LID)) {
+			details.IsAdmin = true
+		}
+	}
+	return details, nil
+}
+
+// groupMember describes a group participant with its role
+func groupMember(participant types.GroupParticipant) GroupMember {
+	member := GroupMember{
+		JID:     participant.JID.String(),
+		Role:    participantRole(participant),
+		IsAdmin: participant.IsAdmin || participant.IsSuperAdmin,
+	}
+	if !participant.PhoneNumber.IsEmpty() {
+		member.Phone = participant.PhoneNumber.User
+	}
+	if !participant.LID.IsEmpty() {
+		member.LID = participant.LID.String()
+	}
+	return member
+}
@@ -0,0 +1,388 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+)
+
+const (
+	// MaxGroupNameLength is the longest group name WhatsApp accepts
+	MaxGroupNameLength = 25
+	// MaxGroupDescriptionLength is the longest group description WhatsApp accepts
+	MaxGroupDescriptionLength = 2048
+)
+
+var (
+	// ErrInvalidGroup is returned when a group management request is malformed
+	ErrInvalidGroup = errors.New("invalid group request")
+	// ErrNotGroupAdmin is returned when a group change requires admin rights the session lacks
+	ErrNotGroupAdmin = errors.New("session is not an admin of this group")
+)
+
+// Per-participant outcomes of a group change
+const (
+	ParticipantSuccess        = "success"
+	ParticipantAlreadyMember  = "already_member"
+	ParticipantNotInGroup     = "not_in_group"
+	ParticipantNotOnWhatsApp  = "not_on_whatsapp"
+	ParticipantInviteRequired = "invite_required"
+	ParticipantRecentlyLeft   = "recently_left"
+	ParticipantFailed         = "failed"
+)
+
+// GroupParticipantResult is the outcome for one participant of a group change
+type GroupParticipantResult struct {
+	Phone string `json:"phone"`
+	JID   string `json:"jid,omitempty"`
+	OK    bool   `json:"ok"`
+	// Status is one of the Participant* outcomes
+	Status string `json:"status"`
+	// Role is the participant's role after a successful promote or demote
+	Role string `json:"role,omitempty"`
+	// Error is WhatsApp's error code for a participant that couldn't be changed
+	Error int `json:"error,omitempty"`
+	// Reason explains why the participant was skipped before reaching WhatsApp
+	Reason string `json:"reason,omitempty"`
+}
+
+// CreatedGroup is a newly created group
+type CreatedGroup struct {
+	JID          string                   `json:"group_jid"`
+	Name         string                   `json:"name"`
+	Participants []GroupParticipantResult `json:"participants"`
+}
+
+// resolveParticipants looks up phone numbers on WhatsApp, returning the JIDs of
+// registered numbers and a skipped result for every other number
+func (s *WhatsAppService) resolveParticipants(deviceID string, phones []string) ([]types.JID, map[string]string, []GroupParticipantResult, error) {
+	seen := make(map[string]bool, len(phones))
+	unique := make([]string, 0, len(phones))
+	for _, phone := range phones {
+		phone = normalizePhone(phone)
+		if phone != "" && !seen[phone] {
+			seen[phone] = true
+			unique = append(unique, phone)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, nil, nil, fmt.Errorf("%w: at least one participant is required", ErrInvalidGroup)
+	}
+
+	checks, err := s.CheckNumbers(deviceID, unique, false)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var jids []types.JID
+	phoneByJID := make(map[string]string)
+	var skipped []GroupParticipantResult
+	for _, check := range checks {
+		jid, err := types.ParseJID(check.JID)
+		if !check.OnWhatsApp || err != nil {
+			skipped = append(skipped, GroupParticipantResult{Phone: check.Phone, Status: ParticipantNotOnWhatsApp, Reason: "not registered on WhatsApp"})
+			continue
+		}
+		jids = append(jids, jid)
+		phoneByJID[jid.User] = check.Phone
+	}
+	return jids, phoneByJID, skipped, nil
+}
+
+// participantStatus names WhatsApp's error code for one participant of a change
+func participantStatus(participant types.GroupParticipant, action whatsmeow.ParticipantChange) string {
+	switch participant.Error {
+	case 0:
+		return ParticipantSuccess
+	case 403:
+		if participant.AddRequest != nil {
+			return ParticipantInviteRequired
+		}
+	case 404:
+		if action == whatsmeow.ParticipantChangeAdd {
+			return ParticipantNotOnWhatsApp
+		}
+		return ParticipantNotInGroup
+	case 408:
+		return ParticipantRecentlyLeft
+	case 409:
+		if action == whatsmeow.ParticipantChangeAdd {
+			return ParticipantAlreadyMember
+		}
+	}
+	return ParticipantFailed
+}
+
+// participantResults reports WhatsApp's per-participant outcome of a group change
+func participantResults(participants []types.GroupParticipant, phoneByJID map[string]string, action whatsmeow.ParticipantChange) []GroupParticipantResult {
+	results := make([]GroupParticipantResult, 0, len(participants))
+	for _, participant := range participants {
+		phone := phoneByJID[participant.JID.User]
+		if phone == "" && !participant.PhoneNumber.IsEmpty() {
+			phone = phoneByJID[participant.PhoneNumber.User]
+		}
+		if phone == "" {
+			// The creator and anyone else the request didn't name
+			continue
+		}
+		results = append(results, GroupParticipantResult{
+			Phone:  phone,
+			JID:    participant.JID.String(),
+			OK:     participant.Error == 0,
+			Status: participantStatus(participant, action),
+			Error:  participant.Error,
+		})
+	}
+	return results
+}
+
+// CreateGroup creates a group with the registered numbers among phones
+func (s *WhatsAppService) CreateGroup(deviceID, name string, phones []string) (*CreatedGroup, error) {
+	name = strings.TrimSpace(name)
+	if err := validateGroupName(name); err != nil {
+		return nil, err
+	}
+
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	jids, phoneByJID, skipped, err := s.resolveParticipants(deviceID, phones)
+	if err != nil {
+		return nil, err
+	}
+	if len(jids) == 0 {
+		return nil, fmt.Errorf("%w: none of the participants is registered on WhatsApp", ErrInvalidGroup)
+	}
+
+	info, err := client.Client.CreateGroup(context.Background(), whatsmeow.ReqCreateGroup{
+		Name:         name,
+		Participants: jids,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create group: %v", err)
+	}
+
+	// The new group must pass the send membership check straight away
+	s.membership.Delete(deviceID)
+
+	return &CreatedGroup{
+		JID:          info.JID.String(),
+		Name:         info.Name,
+		Participants: append(participantResults(info.Participants, phoneByJID, whatsmeow.ParticipantChangeAdd), skipped...),
+	}, nil
+}
+
+// managedGroup parses a group JID and checks the session administers it
+func (s *WhatsAppService) managedGroup(client *DeviceClient, groupJID string) (types.JID, error) {
+	jid, err := types.ParseJID(groupJID)
+	if err != nil || jid.Server != types.GroupServer {
+		return types.JID{}, fmt.Errorf("%w: invalid group JID %s", ErrInvalidGroup, groupJID)
+	}
+	if err := s.ensureGroupMember(client, jid); err != nil {
+		return types.JID{}, err
+	}
+
+	admin, err := s.isGroupAdmin(client, jid)
+	if err != nil {
+		return types.JID{}, fmt.Errorf("failed to get group info: %v", err)
+	}
+	if !admin {
+		return types.JID{}, fmt.Errorf("%w: %s", ErrNotGroupAdmin, jid)
+	}
+	return jid, nil
+}
+
+// UpdateGroupParticipants adds, removes, promotes or demotes group participants
+// by phone number, reporting the outcome for each number
+func (s *WhatsAppService) UpdateGroupParticipants(deviceID, groupJID string, phones []string, action whatsmeow.ParticipantChange) ([]GroupParticipantResult, error) {
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return nil, err
+	}
+	jid, err := s.managedGroup(client, groupJID)
+	if err != nil {
+		return nil, err
+	}
+
+	jids, phoneByJID, skipped, err := s.resolveParticipants(deviceID, phones)
+	if err != nil {
+		return nil, err
+	}
+	if action == whatsmeow.ParticipantChangePromote || action == whatsmeow.ParticipantChangeDemote {
+		var outsiders []GroupParticipantResult
+		jids, outsiders, err = s.groupMembersOnly(client, jid, jids, phoneByJID)
+		if err != nil {
+			return nil, err
+		}
+		skipped = append(skipped, outsiders...)
+	}
+	if len(jids) == 0 {
+		return skipped, nil
+	}
+
+	participants, err := client.Client.UpdateGroupParticipants(jid, jids, action)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update group participants: %v", err)
+	}
+	s.groupCache.Delete(client.DeviceID + "|" + jid.String())
+
+	results := participantResults(participants, phoneByJID, action)
+	for i := range results {
+		if !results[i].OK {
+			continue
+		}
+		switch action {
+		case whatsmeow.ParticipantChangePromote:
+			results[i].Role = "admin"
+		case whatsmeow.ParticipantChangeDemote:
+			results[i].Role = "member"
+		}
+	}
+	return append(results, skipped...), nil
+}
+
+// groupMembersOnly splits jids into current group members and not_in_group
+// results, since only members can be promoted or demoted
+func (s *WhatsAppService) groupMembersOnly(client *DeviceClient, group types.JID, jids []types.JID, phoneByJID map[string]string) ([]types.JID, []GroupParticipantResult, error) {
+	info, err := s.fetchGroupInfo(client, group)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get group info: %v", err)
+	}
+	members := make(map[string]bool, len(info.Participants)*2)
+	for _, participant := range info.Participants {
+		members[participant.JID.User] = true
+		members[participant.PhoneNumber.User] = true
+		members[participant.LID.User] = true
+	}
+
+	var inGroup []types.JID
+	var outsiders []GroupParticipantResult
+	for _, jid := range jids {
+		if members[jid.User] {
+			inGroup = append(inGroup, jid)
+			continue
+		}
+		outsiders = append(outsiders, GroupParticipantResult{
+			Phone:  phoneByJID[jid.User],
+			JID:    jid.String(),
+			Status: ParticipantNotInGroup,
+			Reason: "not a member of this group",
+		})
+	}
+	return inGroup, outsiders, nil
+}
+
+// GroupInvite is a group invite link and its code
+type GroupInvite struct {
+	Link string `json:"invite_link"`
+	Code string `json:"invite_code"`
+}
+
+// newGroupInvite splits an invite link into its full URL and code
+func newGroupInvite(link string) *GroupInvite {
+	return &GroupInvite{Link: link, Code: strings.TrimPrefix(link, whatsmeow.InviteLinkPrefix)}
+}
+
+// GetGroupInviteLink returns a group's invite link, revoking the current one
+// and generating a new one when reset is set
+func (s *WhatsAppService) GetGroupInviteLink(deviceID, groupJID string, reset bool) (*GroupInvite, error) {
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return nil, err
+	}
+	jid, err := s.managedGroup(client, groupJID)
+	if err != nil {
+		return nil, err
+	}
+
+	link, err := client.Client.GetGroupInviteLink(jid, reset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invite link: %v", err)
+	}
+	return newGroupInvite(link), nil
+}
+
+// JoinGroup joins a group with an invite link or code, returning the group's
+// JID; for groups that approve new members this is a pending join request
+func (s *WhatsAppService) JoinGroup(deviceID, invite string) (types.JID, error) {
+	code := strings.TrimPrefix(strings.TrimSpace(invite), whatsmeow.InviteLinkPrefix)
+	if code == "" || strings.ContainsAny(code, "/ ") {
+		return types.EmptyJID, fmt.Errorf("%w: invalid invite link or code", ErrInvalidGroup)
+	}
+
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return types.EmptyJID, err
+	}
+
+	jid, err := client.Client.JoinGroupWithLink(code)
+	if err != nil {
+		return types.EmptyJID, fmt.Errorf("failed to join group: %w", err)
+	}
+	s.membership.Delete(deviceID)
+	return jid, nil
+}
+
+// validateGroupName checks a group name against WhatsApp's limits
+func validateGroupName(name string) error {
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidGroup)
+	}
+	if utf8.RuneCountInString(name) > MaxGroupNameLength {
+		return fmt.Errorf("%w: name exceeds %d characters", ErrInvalidGroup, MaxGroupNameLength)
+	}
+	return nil
+}
+
+// SetGroupSubject renames a group, returning the new name
+func (s *WhatsAppService) SetGroupSubject(deviceID, groupJID, subject string) (string, error) {
+	subject = strings.TrimSpace(subject)
+	if err := validateGroupName(subject); err != nil {
+		return "", err
+	}
+
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return "", err
+	}
+	jid, err := s.managedGroup(client, groupJID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := client.Client.SetGroupName(jid, subject); err != nil {
+		return "", fmt.Errorf("failed to set group subject: %v", err)
+	}
+	s.groupCache.Delete(client.DeviceID + "|" + jid.String())
+	return subject, nil
+}
+
+// SetGroupDescription replaces a group's description; an empty description clears it
+func (s *WhatsAppService) SetGroupDescription(deviceID, groupJID, description string) (string, error) {
+	if utf8.RuneCountInString(description) > MaxGroupDescriptionLength {
+		return "", fmt.Errorf("%w: description exceeds %d characters", ErrInvalidGroup, MaxGroupDescriptionLength)
+	}
+
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return "", err
+	}
+	jid, err := s.managedGroup(client, groupJID)
+	if err != nil {
+		return "", err
+	}
+
+	// Leaving the previous topic ID empty makes whatsmeow fetch the current one
+	if err := client.Client.SetGroupTopic(jid, "", "", description); err != nil {
+		return "", fmt.Errorf("failed to set group description: %v", err)
+	}
+	s.groupCache.Delete(client.DeviceID + "|" + jid.String())
+	return description, nil
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestParticipantResultsPartialSuccess(t *testing.T) {
+	user := func(phone string) types.JID { return types.NewJID(phone, types.DefaultUserServer) }
+	phoneByJID := map[string]string{
+		"628111": "628111",
+		"628222": "628222",
+		"628333": "628333",
+		"628444": "628444",
+		"628555": "628555",
+	}
+	participants := []types.GroupParticipant{
+		{JID: user("628111")},
+		{JID: user("628222"), Error: 409},
+		{JID: user("628333"), Error: 403, AddRequest: &types.GroupParticipantAddRequest{Code: "invite"}},
+		// Answered by LID; the phone is matched through PhoneNumber
+		{JID: types.NewJID("9876", types.HiddenUserServer), PhoneNumber: user("628444"), Error: 408},
+		{JID: user("628555"), Error: 500},
+		// The group creator, which the request didn't name
+		{JID: user("628999")},
+	}
+
+	got := participantResults(participants, phoneByJID, whatsmeow.ParticipantChangeAdd)
+	want := []GroupParticipantResult{
+		{Phone: "628111", JID: "628111@s.whatsapp.net", OK: true, Status: ParticipantSuccess},
+		{Phone: "628222", JID: "628222@s.whatsapp.net", Status: ParticipantAlreadyMember, Error: 409},
+		{Phone: "628333", JID: "628333@s.whatsapp.net", Status: ParticipantInviteRequired, Error: 403},
+		{Phone: "628444", JID: "9876@lid", Status: ParticipantRecentlyLeft, Error: 408},
+		{Phone: "628555", JID: "628555@s.whatsapp.net", Status: ParticipantFailed, Error: 500},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("participantResults =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParticipantStatusDependsOnAction(t *testing.T) {
+	tests := []struct {
+		code   int
+		action whatsmeow.ParticipantChange
+		want   string
+	}{
+		{404, whatsmeow.ParticipantChangeAdd, ParticipantNotOnWhatsApp},
+		{404, whatsmeow.ParticipantChangeRemove, ParticipantNotInGroup},
+		{409, whatsmeow.ParticipantChangeAdd, ParticipantAlreadyMember},
+		{409, whatsmeow.ParticipantChangeRemove, ParticipantFailed},
+		{403, whatsmeow.ParticipantChangeAdd, ParticipantFailed},
+	}
+	for _, tt := range tests {
+		participant := types.GroupParticipant{JID: types.NewJID("628111", types.DefaultUserServer), Error: tt.code}
+		if got := participantStatus(participant, tt.action); got != tt.want {
+			t.Errorf("participantStatus(%d, %s) = %q, want %q", tt.code, tt.action, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+// groupInfoCacheTTL is how long fetched group info is reused
+const groupInfoCacheTTL = 5 * time.Minute
+
+// GroupList is a page of the groups a device has joined
+type GroupList struct {
+	Groups []map[string]interface{}
+	Total  int
+}
+
+// pageGroups applies offset and limit to the joined group list; limit 0 means no limit
+func pageGroups(groups []*types.GroupInfo, limit, offset int) []*types.GroupInfo {
+	if offset >= len(groups) {
+		return nil
+	}
+	groups = groups[offset:]
+	if limit > 0 && limit < len(groups) {
+		groups = groups[:limit]
+	}
+	return groups
+}
+
+// groupEntries formats a page of joined groups as seen by self. Every group
+// is listed, even one whose info came back without a name or participants.
+func groupEntries(self types.JID, groups []*types.GroupInfo, includeParticipants bool) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(groups))
+	for _, group := range groups {
+		entry := map[string]interface{}{
+			"jid":          group.JID.String(),
+			"name":         group.Name,
+			"participants": len(group.Participants),
+			"is_admin":     isGroupAdmin(self, group),
+		}
+		if includeParticipants {
+			members := make([]GroupMember, 0, len(group.Participants))
+			for _, participant := range group.Participants {
+				members = append(members, groupMember(participant))
+			}
+			entry["participant_list"] = members
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+// groupLister fetches the groups a device has joined; *whatsmeow.Client implements it
+type groupLister interface {
+	GetJoinedGroups(ctx context.Context) ([]*types.GroupInfo, error)
+}
+
+// listGroups fetches a device's joined groups in one query, abandoned when ctx
+// is done, and returns the requested page as seen by self
+func (s *WhatsAppService) listGroups(ctx context.Context, lister groupLister, deviceID string, self types.JID, limit, offset int, includeParticipants bool) (*GroupList, error) {
+	groups, err := lister.GetJoinedGroups(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get groups: %w", err)
+	}
+	s.cacheMembership(deviceID, groups)
+
+	// The joined-groups response already carries each group's full info, so no
+	// per-group lookups are needed; keep it for later admin and timer checks
+	for _, group := range groups {
+		s.groupCache.Set(deviceID+"|"+group.JID.String(), group)
+	}
+
+	result := groupEntries(self, pageGroups(groups, limit, offset), includeParticipants)
+	return &GroupList{Groups: result, Total: len(groups)}, nil
+}
+
+// fetchGroupInfo returns group info from the cache or the server
+func (s *WhatsAppService) fetchGroupInfo(client *DeviceClient, jid types.JID) (*types.GroupInfo, error) {
+	cacheKey := client.DeviceID + "|" + jid.String()
+	if info, ok := s.groupCache.Get(cacheKey); ok {
+		return info, nil
+	}
+
+	info, err := client.Client.GetGroupInfo(jid)
+	if err != nil {
+		return nil, err
+	}
+
+	s.groupCache.Set(cacheKey, info)
+	return info, nil
+}
+
+// groupMembershipCacheTTL is how long a device's joined-group set is reused for send pre-checks
+const groupMembershipCacheTTL = time.Minute
+
+// ErrNotGroupMember is returned when sending to a group the device hasn't joined
+var ErrNotGroupMember = errors.New("not a member of this group")
+
+// joinedGroups returns the set of group JIDs a device belongs to, cached briefly
+func (s *WhatsAppService) joinedGroups(client *DeviceClient, refresh bool) (map[string]bool, error) {
+	if !refresh {
+		if joined, ok := s.membership.Get(client.DeviceID); ok {
+			return joined, nil
+		}
+	}
+
+	groups, err := client.Client.GetJoinedGroups(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get groups: %v", err)
+	}
+	return s.cacheMembership(client.DeviceID, groups), nil
+}
+
+// cacheMembership records and returns the set of groups a device has joined
+func (s *WhatsAppService) cacheMembership(deviceID string, groups []*types.GroupInfo) map[string]bool {
+	joined := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		joined[group.JID.String()] = true
+	}
+	s.membership.Set(deviceID, joined)
+	return joined
+}
+
+// ensureGroupMember fails with ErrNotGroupMember before a send to a group the
+// device isn't in. A stale cache is refreshed once before refusing, and a failed
+// lookup lets the send go ahead so WhatsApp can decide.
+func (s *WhatsAppService) ensureGroupMember(client *DeviceClient, jid types.JID) error {
+	if jid.Server != types.GroupServer {
+		return nil
+	}
+
+	joined, err := s.joinedGroups(client, false)
+	if err == nil && !joined[jid.String()] {
+		joined, err = s.joinedGroups(client, true)
+	}
+	if err != nil {
+		s.logger.Warnf("Skipping group membership check for %s: %v", jid, err)
+		return nil
+	}
+	if !joined[jid.String()] {
+		return fmt.Errorf("%w: %s", ErrNotGroupMember, jid)
+	}
+	return nil
+}
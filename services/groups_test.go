@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+func TestGroupEntriesListsIncompleteGroups(t *testing.T) {
+	self := types.NewJID("628111111111", types.DefaultUserServer)
+	member := func(user string, admin bool) types.GroupParticipant {
+		return types.GroupParticipant{JID: types.NewJID(user, types.DefaultUserServer), IsAdmin: admin}
+	}
+
+	groups := []*types.GroupInfo{
+		{
+			JID:          types.NewJID("120363000000000001", types.GroupServer),
+			GroupName:    types.GroupName{Name: "Support"},
+			Participants: []types.GroupParticipant{member("628111111111", true), member("628222222222", false)},
+		},
+		// Info for this group came back without a name or participant list
+		{JID: types.NewJID("120363000000000002", types.GroupServer)},
+		{
+			JID:          types.NewJID("120363000000000003", types.GroupServer),
+			GroupName:    types.GroupName{Name: "Sales"},
+			Participants: []types.GroupParticipant{member("628222222222", true)},
+		},
+	}
+
+	entries := groupEntries(self, groups, true)
+	if len(entries) != len(groups) {
+		t.Fatalf("listed %d groups, want all %d", len(entries), len(groups))
+	}
+
+	if entries[0]["name"] != "Support" || entries[0]["participants"] != 2 || entries[0]["is_admin"] != true {
+		t.Errorf("complete group = %v", entries[0])
+	}
+	incomplete := entries[1]
+	if incomplete["jid"] != "120363000000000002@g.us" || incomplete["name"] != "" || incomplete["participants"] != 0 || incomplete["is_admin"] != false {
+		t.Errorf("incomplete group = %v", incomplete)
+	}
+	if members, ok := incomplete["participant_list"].([]GroupMember); !ok || members == nil || len(members) != 0 {
+		t.Errorf("incomplete group participant_list = %#v, want an empty list", incomplete["participant_list"])
+	}
+	if entries[2]["is_admin"] != false {
+		t.Errorf("group where self isn't a member = %v", entries[2])
+	}
+
+	if _, ok := groupEntries(self, groups, false)[0]["participant_list"]; ok {
+		t.Error("participant_list included without being asked for")
+	}
+}
+
+// benchmarkRoundTrip stands in for one request to WhatsApp when comparing group listing strategies
+const benchmarkRoundTrip = 100 * time.Microsecond
+
+func BenchmarkGroupListing(b *testing.B) {
+	self := types.NewJID("628111111111", types.DefaultUserServer)
+	groups := make([]*types.GroupInfo, 40)
+	for i := range groups {
+		participants := make([]types.GroupParticipant, 50)
+		for j := range participants {
+			participants[j] = types.GroupParticipant{JID: types.NewJID(fmt.Sprintf("62812%07d", j), types.DefaultUserServer)}
+		}
+		groups[i] = &types.GroupInfo{
+			JID:          types.NewJID(fmt.Sprintf("1203630000000%05d", i), types.GroupServer),
+			GroupName:    types.GroupName{Name: fmt.Sprintf("Group %d", i)},
+			Participants: participants,
+		}
+	}
+
+	// The old listing followed GetJoinedGroups with a GetGroupInfo per group
+	b.Run("per-group-lookup", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			time.Sleep(benchmarkRoundTrip)
+			fetched := make([]*types.GroupInfo, 0, len(groups))
+			for _, group := range groups {
+				time.Sleep(benchmarkRoundTrip)
+				fetched = append(fetched, group)
+			}
+			groupEntries(self, fetched, true)
+		}
+	})
+
+	b.Run("joined-groups-only", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			time.Sleep(benchmarkRoundTrip)
+			groupEntries(self, groups, true)
+		}
+	})
+}
+
+// blockingLister answers the joined-groups query only once ctx is done
+type blockingLister struct{}
+
+func (blockingLister) GetJoinedGroups(ctx context.Context) ([]*types.GroupInfo, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestListGroupsStopsWhenRequestIsCancelled(t *testing.T) {
+	s := &WhatsAppService{
+		groupCache: newTTLCache[*types.GroupInfo](groupInfoCacheTTL),
+		membership: newTTLCache[map[string]bool](groupMembershipCacheTTL),
+	}
+	self := types.NewJID("628111111111", types.DefaultUserServer)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := s.listGroups(ctx, blockingLister{}, "device001", self, 0, 0, false)
+		done <- err
+	}()
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("listGroups after cancel: err = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("listGroups kept waiting after the request was cancelled")
+	}
+	if _, ok := s.membership.Get("device001"); ok {
+		t.Error("membership cached from a cancelled query")
+	}
+}
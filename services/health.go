@@ -0,0 +1,58 @@
+package services
+
+import (
+	"sort"
+	"time"
+)
+
+// DeviceReadiness is one session's connection state in the readiness report
+type DeviceReadiness struct {
+	DeviceID  string `json:"device_id"`
+	Status    string `json:"status"`
+	Connected bool   `json:"connected"`
+}
+
+// Readiness reports whether the server can serve traffic for its sessions
+type Readiness struct {
+	Ready          bool              `json:"ready"`
+	UptimeSeconds  int64             `json:"uptime_seconds"`
+	Sessions       int               `json:"sessions"`
+	ConnectedCount int               `json:"connected"`
+	Devices        []DeviceReadiness `json:"devices"`
+}
+
+// Uptime returns how long the server has been running
+func Uptime() time.Duration {
+	return time.Since(processStart)
+}
+
+// GetReadiness lists every session's connection state. The server is ready
+// unless it has sessions and none of them is connected.
+func (s *WhatsAppService) GetReadiness() Readiness {
+	readiness := Readiness{
+		UptimeSeconds: int64(Uptime().Seconds()),
+		Devices:       make([]DeviceReadiness, 0),
+	}
+
+	for _, client := range s.GetAllSessions() {
+		device := DeviceReadiness{
+			DeviceID:  client.DeviceID,
+			Status:    "disconnected",
+			Connected: client.IsConnected(),
+		}
+		if device.Connected {
+			device.Status = "connected"
+			readiness.ConnectedCount++
+		} else if client.Client.Store.ID == nil {
+			device.Status = "waiting_for_qr_scan"
+		}
+		readiness.Devices = append(readiness.Devices, device)
+	}
+	sort.Slice(readiness.Devices, func(i, j int) bool {
+		return readiness.Devices[i].DeviceID < readiness.Devices[j].DeviceID
+	})
+
+	readiness.Sessions = len(readiness.Devices)
+	readiness.Ready = readiness.Sessions == 0 || readiness.ConnectedCount > 0
+	return readiness
+}
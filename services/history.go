@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waHistorySync"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// ErrNoHistoryAnchor is returned when a chat has no stored message to request older history from
+var ErrNoHistoryAnchor = errors.New("no stored messages for this chat to request history before")
+
+// historyResult is what an on-demand history sync retrieved for a chat
+type historyResult struct {
+	messages []StoredMessage
+	// inserted is how many of the messages were new to the message store
+	inserted int
+}
+
+// historyWaiters delivers on-demand history sync results to the requests waiting for them
+type historyWaiters struct {
+	mu      sync.Mutex
+	waiters map[string]chan historyResult
+}
+
+func newHistoryWaiters() *historyWaiters {
+	return &historyWaiters{waiters: make(map[string]chan historyResult)}
+}
+
+// add registers a waiter for a chat's on-demand sync
+func (h *historyWaiters) add(deviceID, chatJID string) chan historyResult {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan historyResult, 1)
+	h.waiters[deviceID+"|"+chatJID] = ch
+	return ch
+}
+
+// remove drops a waiter that gave up
+func (h *historyWaiters) remove(deviceID, chatJID string, ch chan historyResult) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.waiters[deviceID+"|"+chatJID] == ch {
+		delete(h.waiters, deviceID+"|"+chatJID)
+	}
+}
+
+// notify hands the retrieved messages to the chat's waiter, if any
+func (h *historyWaiters) notify(deviceID, chatJID string, result historyResult) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := deviceID + "|" + chatJID
+	if ch, ok := h.waiters[key]; ok {
+		ch <- result
+		delete(h.waiters, key)
+	}
+}
+
+// handleHistorySync buffers and stores the messages of a history sync blob
+func (s *WhatsAppService) handleHistorySync(dc *DeviceClient, evt *events.HistorySync) {
+	onDemand := evt.Data.GetSyncType() == waHistorySync.HistorySync_ON_DEMAND
+	for _, conv := range evt.Data.GetConversations() {
+		chat, err := types.ParseJID(conv.GetID())
+		if err != nil {
+			continue
+		}
+
+		messages := make([]StoredMessage, 0, len(conv.GetMessages()))
+		for _, historyMsg := range conv.GetMessages() {
+			msg, err := dc.Client.ParseWebMessage(chat, historyMsg.GetMessage())
+			if err != nil {
+				continue
+			}
+			messages = append(messages, storedFromEvent(dc.DeviceID, msg))
+		}
+
+		s.chatBuffer.add(messages...)
+
+		result := historyResult{messages: messages}
+		if s.store != nil {
+			result.inserted, err = s.store.save(messages...)
+			if err != nil {
+				s.logger.Errorf("Failed to store history for %s on device %s: %v", chat, dc.DeviceID, err)
+				continue
+			}
+		}
+
+		if onDemand {
+			s.history.notify(dc.DeviceID, chat.String(), result)
+		}
+	}
+}
+
+// FetchChatHistory asks the primary device for messages older than the oldest
+// stored message of a chat and waits up to wait for them to arrive. It returns
+// the number of new messages stored and whether the sync completed in time;
+// messages arriving later are still stored.
+func (s *WhatsAppService) FetchChatHistory(deviceID, chatJID string, count int, wait time.Duration) (int, bool, error) {
+	if s.store == nil {
+		return 0, false, ErrStoreDisabled
+	}
+
+	chat, err := parseChatJID(chatJID)
+	if err != nil {
+		return 0, false, err
+	}
+
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return 0, false, err
+	}
+
+	oldest, err := s.store.oldest(deviceID, chat.String())
+	if err != nil {
+		return 0, false, err
+	}
+	if oldest == nil {
+		return 0, false, ErrNoHistoryAnchor
+	}
+
+	result, completed, err := s.requestHistory(client, chat, oldest, count, wait)
+	return result.inserted, completed, err
+}
+
+// ChatHistory asks the primary device for up to count messages older than the
+// oldest known message of a chat and waits up to wait for them. The oldest
+// known message comes from the in-memory chat buffer, falling back to the
+// message store, so this works with STORE_MESSAGES disabled. It returns the
+// retrieved messages, oldest first, and whether the sync completed in time.
+func (s *WhatsAppService) ChatHistory(deviceID, chatJID string, count int, wait time.Duration) ([]StoredMessage, bool, error) {
+	chat, err := parseChatJID(chatJID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var oldest *StoredMessage
+	if buffered := s.chatBuffer.get(deviceID, chat.String()); len(buffered) > 0 {
+		oldest = &buffered[0]
+	} else if s.store != nil {
+		if oldest, err = s.store.oldest(deviceID, chat.String()); err != nil {
+			return nil, false, err
+		}
+	}
+	if oldest == nil {
+		return nil, false, ErrNoHistoryAnchor
+	}
+
+	result, completed, err := s.requestHistory(client, chat, oldest, count, wait)
+	if err != nil || !completed {
+		return nil, completed, err
+	}
+
+	messages := result.messages
+	sort.SliceStable(messages, func(i, j int) bool { return messages[i].Timestamp < messages[j].Timestamp })
+	if len(messages) > count {
+		messages = messages[len(messages)-count:]
+	}
+	return messages, true, nil
+}
+
+// requestHistory sends an on-demand history sync request anchored on oldest
+// and waits up to wait for the phone to answer
+func (s *WhatsAppService) requestHistory(client *DeviceClient, chat types.JID, oldest *StoredMessage, count int, wait time.Duration) (historyResult, bool, error) {
+	anchor := &types.MessageInfo{
+		MessageSource: types.MessageSource{Chat: chat, IsFromMe: oldest.FromMe},
+		ID:            oldest.MessageID,
+		Timestamp:     time.Unix(oldest.Timestamp, 0),
+	}
+
+	done := s.history.add(client.DeviceID, chat.String())
+	request := client.Client.BuildHistorySyncRequest(anchor, count)
+	if _, err := client.Client.SendMessage(context.Background(), client.Client.Store.ID.ToNonAD(), request, whatsmeow.SendRequestExtra{Peer: true}); err != nil {
+		s.history.remove(client.DeviceID, chat.String(), done)
+		return historyResult{}, false, fmt.Errorf("failed to request history: %v", err)
+	}
+
+	select {
+	case result := <-done:
+		return result, true, nil
+	case <-time.After(wait):
+		s.history.remove(client.DeviceID, chat.String(), done)
+		return historyResult{}, false, nil
+	}
+}
+
+// parseChatJID accepts a full JID or a bare phone number
+func parseChatJID(raw string) (types.JID, error) {
+	if !strings.Contains(raw, "@") {
+		return resolveRecipient(raw, "")
+	}
+
+	jid, err := types.ParseJID(raw)
+	if err != nil {
+		return types.JID{}, fmt.Errorf("invalid chat JID: %v", err)
+	}
+	return jid, nil
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// jobRetention is how long a bulk job's progress can be polled after it starts
+const jobRetention = 24 * time.Hour
+
+// defaultBulkSyncLimit is the largest bulk send answered inline instead of as a job
+const defaultBulkSyncLimit = 50
+
+// Bulk job states
+const (
+	JobRunning   = "running"
+	JobCompleted = "completed"
+)
+
+// BulkJob tracks a bulk send running in the background
+type BulkJob struct {
+	mu sync.Mutex
+
+	id          string
+	deviceID    string
+	status      string
+	createdAt   time.Time
+	completedAt time.Time
+	processed   int
+	sent        int
+	results     []BulkResult
+}
+
+// BulkJobStatus is a snapshot of a bulk job's progress
+type BulkJobStatus struct {
+	ID          string       `json:"job_id"`
+	DeviceID    string       `json:"device_id"`
+	Status      string       `json:"status"`
+	Total       int          `json:"total"`
+	Processed   int          `json:"processed"`
+	Sent        int          `json:"sent"`
+	Failed      int          `json:"failed"`
+	CreatedAt   time.Time    `json:"created_at"`
+	CompletedAt *time.Time   `json:"completed_at,omitempty"`
+	Results     []BulkResult `json:"results"`
+}
+
+// BulkSyncLimit returns BULK_SYNC_LIMIT, the largest bulk send answered inline
+func BulkSyncLimit() int {
+	return envInt("BULK_SYNC_LIMIT", defaultBulkSyncLimit)
+}
+
+// newJobID returns a random job ID
+func newJobID() string {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
+
+// record stores the result of recipient i
+func (j *BulkJob) record(i int, result BulkResult) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.results[i] = result
+	j.processed++
+	if result.Error == "" {
+		j.sent++
+	}
+}
+
+// finish marks the job completed
+func (j *BulkJob) finish() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.status = JobCompleted
+	j.completedAt = time.Now()
+}
+
+// Status returns a snapshot of the job. Results of recipients not processed
+// yet are left out.
+func (j *BulkJob) Status() BulkJobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	status := BulkJobStatus{
+		ID:        j.id,
+		DeviceID:  j.deviceID,
+		Status:    j.status,
+		Total:     len(j.results),
+		Processed: j.processed,
+		Sent:      j.sent,
+		Failed:    j.processed - j.sent,
+		CreatedAt: j.createdAt,
+		Results:   make([]BulkResult, 0, j.processed),
+	}
+	if !j.completedAt.IsZero() {
+		completedAt := j.completedAt
+		status.CompletedAt = &completedAt
+	}
+	for _, result := range j.results {
+		if result.Phone != "" {
+			status.Results = append(status.Results, result)
+		}
+	}
+	return status
+}
+
+// DeviceID returns the device the job sends from
+func (j *BulkJob) DeviceID() string {
+	return j.deviceID
+}
+
+// StartBulkJob runs a bulk send in the background and returns its job
+func (s *WhatsAppService) StartBulkJob(deviceID string, recipients []BulkRecipient, opts BulkOptions) *BulkJob {
+	job := &BulkJob{
+		id:        newJobID(),
+		deviceID:  deviceID,
+		status:    JobRunning,
+		createdAt: time.Now(),
+		results:   make([]BulkResult, len(recipients)),
+	}
+	s.jobs.Set(job.id, job)
+
+	opts.OnResult = job.record
+	go func() {
+		s.SendBulk(deviceID, recipients, opts)
+		job.finish()
+		s.logger.Infof("Bulk job %s for device %s completed", job.id, deviceID)
+	}()
+	return job
+}
+
+// GetJob returns a bulk job by ID
+func (s *WhatsAppService) GetJob(id string) (*BulkJob, bool) {
+	return s.jobs.Get(id)
+}
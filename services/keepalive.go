@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+)
+
+// configureKeepAlive applies KEEPALIVE_INTERVAL_MIN_SECONDS and
+// KEEPALIVE_INTERVAL_MAX_SECONDS to every WhatsApp socket
+func (s *WhatsAppService) configureKeepAlive() {
+	minInterval := whatsmeow.KeepAliveIntervalMin
+	maxInterval := whatsmeow.KeepAliveIntervalMax
+	if seconds, err := strconv.Atoi(os.Getenv("KEEPALIVE_INTERVAL_MIN_SECONDS")); err == nil && seconds > 0 {
+		minInterval = time.Duration(seconds) * time.Second
+	}
+	if seconds, err := strconv.Atoi(os.Getenv("KEEPALIVE_INTERVAL_MAX_SECONDS")); err == nil && seconds > 0 {
+		maxInterval = time.Duration(seconds) * time.Second
+	}
+
+	if minInterval >= maxInterval {
+		s.logger.Warnf("Ignoring keepalive interval %s-%s: minimum must be below maximum", minInterval, maxInterval)
+		return
+	}
+
+	whatsmeow.KeepAliveIntervalMin = minInterval
+	whatsmeow.KeepAliveIntervalMax = maxInterval
+}
+
+// markKeepAlive records a successful keepalive or connection at t
+func (dc *DeviceClient) markKeepAlive(t time.Time) {
+	dc.lastKeepAlive.Store(t.Unix())
+}
+
+// LastKeepAlive returns the last time the socket was known to be alive, or
+// zero when unknown. whatsmeow only reports automatic pings after a failure,
+// so this reflects connects, recoveries and manual pings.
+func (dc *DeviceClient) LastKeepAlive() time.Time {
+	unix := dc.lastKeepAlive.Load()
+	if unix == 0 {
+		return time.Time{}
+	}
+	return time.Unix(unix, 0)
+}
+
+// Ping sends a keepalive on the session's socket and returns the round-trip time
+func (s *WhatsAppService) Ping(deviceID string) (time.Duration, error) {
+	client, err := s.GetSession(deviceID)
+	if err != nil {
+		return 0, err
+	}
+	if !client.Client.IsConnected() {
+		return 0, fmt.Errorf("%w: socket is closed", ErrSessionNotConnected)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), whatsmeow.KeepAliveResponseDeadline)
+	defer cancel()
+
+	start := time.Now()
+	ok, _ := client.Client.DangerousInternals().SendKeepAlive(ctx)
+	if !ok {
+		return 0, fmt.Errorf("keepalive got no response within %s", whatsmeow.KeepAliveResponseDeadline)
+	}
+
+	rtt := time.Since(start)
+	client.markKeepAlive(time.Now())
+	return rtt, nil
+}
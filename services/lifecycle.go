@@ -0,0 +1,43 @@
+package services
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// Connection lifecycle webhook events
+const (
+	EventDeviceConnected    = "device_connected"
+	EventDeviceDisconnected = "device_disconnected"
+	EventDeviceLoggedOut    = "device_logged_out"
+)
+
+// LifecycleEvent is the data of a connection lifecycle webhook
+type LifecycleEvent struct {
+	Status    string `json:"status"`
+	Phone     string `json:"phone,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+	// Reason explains a logout, e.g. when the device was unlinked from the phone
+	Reason string `json:"reason,omitempty"`
+}
+
+// lifecycleEventsEnabled reports whether WEBHOOK_LIFECYCLE_EVENTS is set
+func lifecycleEventsEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("WEBHOOK_LIFECYCLE_EVENTS"))
+	return enabled
+}
+
+// notifyLifecycle sends a connection lifecycle webhook for a device
+func notifyLifecycle(dc *DeviceClient, event, status, reason string) {
+	if !lifecycleEventsEnabled() {
+		return
+	}
+
+	GetWebhookService().SendEvent(dc.DeviceID, event, LifecycleEvent{
+		Status:    status,
+		Phone:     dc.GetPhone(),
+		Timestamp: time.Now().Unix(),
+		Reason:    reason,
+	})
+}
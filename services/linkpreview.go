@@ -0,0 +1,295 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	// Decoders for og:image thumbnails
+	_ "image/gif"
+	_ "image/png"
+
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"golang.org/x/net/html"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// linkPreviewPageLimit caps how much of a page is read looking for OpenGraph tags
+	linkPreviewPageLimit = 512 << 10
+	// linkPreviewImageLimit caps the size of a downloaded og:image
+	linkPreviewImageLimit = 2 << 20
+	// linkPreviewThumbnailSize is the longest side of the JPEG thumbnail sent with a preview
+	linkPreviewThumbnailSize = 300
+	// linkPreviewMaxPixels caps the decoded size of an og:image; a small file can declare huge dimensions
+	linkPreviewMaxPixels = 4096 * 4096
+	// linkPreviewMaxRedirects caps how many redirects a page or image fetch follows
+	linkPreviewMaxRedirects = 3
+)
+
+// linkPreviewClient fetches pages and images for previews. It only connects to
+// public addresses, checked after DNS resolution, so a link (or a redirect) to
+// loopback, link-local or private hosts can't make the server read internal URLs.
+var linkPreviewClient = &http.Client{
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 5 * time.Second,
+			Control: refusePrivateAddress,
+		}).DialContext,
+		TLSHandshakeTimeout: 5 * time.Second,
+	},
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if len(via) >= linkPreviewMaxRedirects {
+			return fmt.Errorf("stopped after %d redirects", linkPreviewMaxRedirects)
+		}
+		return nil
+	},
+}
+
+// cgnatPrefix is the shared address space carriers use, which isn't publicly routable
+var cgnatPrefix = netip.MustParsePrefix("100.64.0.0/10")
+
+// refusePrivateAddress fails a dial to anything but a public unicast address
+func refusePrivateAddress(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("refusing to dial %s: %v", address, err)
+	}
+	ip := addrPort.Addr().Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() || cgnatPrefix.Contains(ip) {
+		return fmt.Errorf("refusing to dial non-public address %s", ip)
+	}
+	return nil
+}
+
+// urlPattern finds http(s) links in message text
+var urlPattern = regexp.MustCompile(`https?://[^\s<>"]+`)
+
+// LinkPreview is the OpenGraph summary of a link, attached to a text message
+type LinkPreview struct {
+	// MatchedText is the URL as it appears in the message
+	MatchedText string
+	Title       string
+	Description string
+	// Thumbnail is a JPEG image, empty when the page has no usable og:image
+	Thumbnail       []byte
+	ThumbnailWidth  int
+	ThumbnailHeight int
+}
+
+// linkPreviewsEnabled reports whether LINK_PREVIEW_ENABLED generates previews for every text with a link
+func linkPreviewsEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("LINK_PREVIEW_ENABLED"))
+	return enabled
+}
+
+// linkPreviewTimeout reads LINK_PREVIEW_TIMEOUT_SECONDS, the budget for fetching a page and its image
+func linkPreviewTimeout() time.Duration {
+	return time.Duration(positiveEnvInt("LINK_PREVIEW_TIMEOUT_SECONDS", 5)) * time.Second
+}
+
+// firstURL returns the first link in text, without trailing punctuation
+func firstURL(text string) string {
+	return strings.TrimRight(urlPattern.FindString(text), ".,;:!?)]}'")
+}
+
+// LinkPreviewFor builds a preview for the first link in text when requested, or
+// for any link when LINK_PREVIEW_ENABLED is set. It returns nil when there is
+// no link or the page can't be previewed, so the message goes out as plain text.
+func (s *WhatsAppService) LinkPreviewFor(text string, requested bool) *LinkPreview {
+	if !requested && !linkPreviewsEnabled() {
+		return nil
+	}
+	link := firstURL(text)
+	if link == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), linkPreviewTimeout())
+	defer cancel()
+
+	preview, err := fetchLinkPreview(ctx, link)
+	if err != nil {
+		s.logger.Warnf("Link preview for %s failed, sending plain text: %v", link, err)
+		return nil
+	}
+	return preview
+}
+
+// fetchLinkPreview reads the OpenGraph title, description and image of a page
+func fetchLinkPreview(ctx context.Context, link string) (*LinkPreview, error) {
+	page, err := fetchLimited(ctx, link, linkPreviewPageLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := pageMeta(page)
+	preview := &LinkPreview{
+		MatchedText: link,
+		Title:       firstNonEmpty(meta["og:title"], meta["title"]),
+		Description: firstNonEmpty(meta["og:description"], meta["description"]),
+	}
+	if preview.Title == "" {
+		return nil, fmt.Errorf("page has no title")
+	}
+
+	// A missing or broken image still leaves a text-only preview
+	if imageURL := meta["og:image"]; imageURL != "" {
+		if data, err := fetchLimited(ctx, imageURL, linkPreviewImageLimit); err == nil {
+			preview.Thumbnail, preview.ThumbnailWidth, preview.ThumbnailHeight, _ = jpegThumbnail(data)
+		}
+	}
+	return preview, nil
+}
+
+// fetchLimited GETs a URL, failing when the body exceeds limit
+func fetchLimited(ctx context.Context, link string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "WhatsApp/2 WAKU link preview")
+
+	resp, err := linkPreviewClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("response larger than %d bytes", limit)
+	}
+	return body, nil
+}
+
+// pageMeta collects the <title> and the og:* and description <meta> tags of a page
+func pageMeta(page []byte) map[string]string {
+	meta := make(map[string]string)
+	tokenizer := html.NewTokenizer(bytes.NewReader(page))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return meta
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "title":
+				if tokenizer.Next() == html.TextToken && meta["title"] == "" {
+					meta["title"] = strings.TrimSpace(string(tokenizer.Text()))
+				}
+			case "meta":
+				var key, content string
+				for _, attr := range token.Attr {
+					switch attr.Key {
+					case "property", "name":
+						key = strings.ToLower(attr.Val)
+					case "content":
+						content = strings.TrimSpace(attr.Val)
+					}
+				}
+				if key != "" && meta[key] == "" {
+					meta[key] = content
+				}
+			case "body":
+				// OpenGraph tags live in <head>
+				return meta
+			}
+		}
+	}
+}
+
+// jpegThumbnail scales an image down to linkPreviewThumbnailSize and encodes
+// it as JPEG, returning the thumbnail with its dimensions
+func jpegThumbnail(data []byte) ([]byte, int, int, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width > linkPreviewMaxPixels/config.Height {
+		return nil, 0, 0, fmt.Errorf("image of %dx%d pixels is too large", config.Width, config.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if longest := max(width, height); longest > linkPreviewThumbnailSize {
+		width = width * linkPreviewThumbnailSize / longest
+		height = height * linkPreviewThumbnailSize / longest
+	}
+	if width == 0 || height == 0 {
+		return nil, 0, 0, fmt.Errorf("image too small")
+	}
+
+	// Nearest-neighbour scaling is plenty for a preview thumbnail
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			dst.Set(x, y, src.At(bounds.Min.X+x*bounds.Dx()/width, bounds.Min.Y+y*bounds.Dy()/height))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 75}); err != nil {
+		return nil, 0, 0, err
+	}
+	return buf.Bytes(), width, height, nil
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// applyLinkPreview turns a text message into an extended text message carrying the preview
+func applyLinkPreview(msg *waProto.Message, preview *LinkPreview) {
+	if preview == nil {
+		return
+	}
+	if msg.Conversation != nil {
+		msg.ExtendedTextMessage = &waProto.ExtendedTextMessage{Text: msg.Conversation}
+		msg.Conversation = nil
+	}
+	ext := msg.ExtendedTextMessage
+	if ext == nil {
+		return
+	}
+
+	ext.MatchedText = proto.String(preview.MatchedText)
+	ext.Title = proto.String(preview.Title)
+	ext.Description = proto.String(preview.Description)
+	ext.PreviewType = waProto.ExtendedTextMessage_NONE.Enum()
+	if len(preview.Thumbnail) > 0 {
+		ext.JPEGThumbnail = preview.Thumbnail
+		ext.ThumbnailWidth = proto.Uint32(uint32(preview.ThumbnailWidth))
+		ext.ThumbnailHeight = proto.Uint32(uint32(preview.ThumbnailHeight))
+	}
+}
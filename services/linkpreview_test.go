@@ -0,0 +1,81 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// pngDeclaring encodes a 1x1 PNG and rewrites its header to declare width x height
+func pngDeclaring(t *testing.T, width, height uint32) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	// 8-byte signature, then the IHDR chunk: length, type, width, height, ...
+	ihdr := data[8+8 : 8+8+13]
+	binary.BigEndian.PutUint32(ihdr[0:4], width)
+	binary.BigEndian.PutUint32(ihdr[4:8], height)
+	binary.BigEndian.PutUint32(data[8+8+13:], crc32.ChecksumIEEE(data[8+4:8+8+13]))
+	return data
+}
+
+func TestJPEGThumbnailRejectsHugeDimensions(t *testing.T) {
+	if _, _, _, err := jpegThumbnail(pngDeclaring(t, 30000, 30000)); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Fatalf("jpegThumbnail of a 30000x30000 image: err = %v, want too large", err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 600, 400))); err != nil {
+		t.Fatal(err)
+	}
+	thumb, width, height, err := jpegThumbnail(buf.Bytes())
+	if err != nil || len(thumb) == 0 || width != linkPreviewThumbnailSize || height != 200 {
+		t.Fatalf("jpegThumbnail = %d bytes %dx%d, %v", len(thumb), width, height, err)
+	}
+}
+
+func TestRefusePrivateAddress(t *testing.T) {
+	for _, address := range []string{
+		"127.0.0.1:80",
+		"[::1]:443",
+		"10.1.2.3:80",
+		"172.16.0.1:80",
+		"192.168.1.1:80",
+		"169.254.169.254:80",
+		"[fe80::1]:80",
+		"[fd00::1]:80",
+		"100.64.0.1:80",
+		"0.0.0.0:80",
+		"[::ffff:127.0.0.1]:80",
+	} {
+		if err := refusePrivateAddress("tcp", address, nil); err == nil {
+			t.Errorf("dial to %s allowed", address)
+		}
+	}
+	for _, address := range []string{"93.184.216.34:443", "[2606:2800:220:1:248:1893:25c8:1946]:443"} {
+		if err := refusePrivateAddress("tcp", address, nil); err != nil {
+			t.Errorf("dial to %s refused: %v", address, err)
+		}
+	}
+}
+
+func TestFetchLimitedRefusesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<title>internal</title>"))
+	}))
+	defer server.Close()
+
+	if _, err := fetchLimited(context.Background(), server.URL, linkPreviewPageLimit); err == nil {
+		t.Fatal("fetched a page from a loopback address")
+	}
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// defaultLogBufferLines is the number of log lines kept per device when LOG_BUFFER_LINES is unset
+const defaultLogBufferLines = 500
+
+// sensitivePattern matches long key-like tokens (media keys, QR refs, auth blobs) that must not be exposed
+var sensitivePattern = regexp.MustCompile(`[A-Za-z0-9+/=_-]{32,}`)
+
+// jidUserPattern matches the user part of a JID, which is a phone number or
+// an ID tied to one, leaving the server visible
+var jidUserPattern = regexp.MustCompile(`[0-9][0-9.:-]*@(s\.whatsapp\.net|c\.us|g\.us|lid|broadcast|newsletter)\b`)
+
+// phonePattern matches phone numbers written in international format
+var phonePattern = regexp.MustCompile(`\+[0-9]{8,15}\b`)
+
+// logLevelRanks orders the log levels so lines below LOG_LEVEL can be skipped
+var logLevelRanks = map[string]int{"DEBUG": 0, "INFO": 1, "WARN": 2, "ERROR": 3}
+
+// redactLogLine hides secrets, JIDs and phone numbers from a buffered log line
+func redactLogLine(line string) string {
+	line = sensitivePattern.ReplaceAllString(line, "[redacted]")
+	line = jidUserPattern.ReplaceAllString(line, "[redacted]@$1")
+	return phonePattern.ReplaceAllString(line, "[redacted]")
+}
+
+// LogLine is a single captured log entry
+type LogLine struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Module  string `json:"module"`
+	Message string `json:"message"`
+}
+
+// logBuffer is a fixed-size ring buffer of recent log lines for one device
+type logBuffer struct {
+	mu    sync.Mutex
+	lines []LogLine
+	next  int
+	full  bool
+}
+
+func newLogBuffer(size int) *logBuffer {
+	return &logBuffer{lines: make([]LogLine, size)}
+}
+
+func (b *logBuffer) add(line LogLine) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lines[b.next] = line
+	b.next = (b.next + 1) % len(b.lines)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// last returns up to n of the most recent lines, oldest first
+func (b *logBuffer) last(n int) []LogLine {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	count := b.next
+	if b.full {
+		count = len(b.lines)
+	}
+	if n <= 0 || n > count {
+		n = count
+	}
+
+	result := make([]LogLine, 0, n)
+	for i := count - n; i < count; i++ {
+		idx := i
+		if b.full {
+			idx = (b.next + i) % len(b.lines)
+		}
+		result = append(result, b.lines[idx])
+	}
+	return result
+}
+
+// deviceLogger wraps a waLog.Logger and records the lines at or above
+// LOG_LEVEL in the device's ring buffer
+type deviceLogger struct {
+	parent   waLog.Logger
+	module   string
+	buffer   *logBuffer
+	minLevel int
+}
+
+// newDeviceLogger creates a logger for a device that feeds its log buffer
+func newDeviceLogger(deviceID string, parent waLog.Logger, buffer *logBuffer) waLog.Logger {
+	return &deviceLogger{
+		parent:   parent,
+		module:   deviceID,
+		buffer:   buffer,
+		minLevel: logLevelRanks[logLevel()],
+	}
+}
+
+func (l *deviceLogger) record(level, msg string, args ...interface{}) {
+	// Filtered lines aren't formatted at all; whatsmeow logs a lot at DEBUG
+	if logLevelRanks[level] < l.minLevel {
+		return
+	}
+	l.buffer.add(LogLine{
+		Time:    time.Now().Format(time.RFC3339),
+		Level:   level,
+		Module:  l.module,
+		Message: redactLogLine(fmt.Sprintf(msg, args...)),
+	})
+}
+
+func (l *deviceLogger) Warnf(msg string, args ...interface{}) {
+	l.record("WARN", msg, args...)
+	l.parent.Warnf(msg, args...)
+}
+
+func (l *deviceLogger) Errorf(msg string, args ...interface{}) {
+	l.record("ERROR", msg, args...)
+	l.parent.Errorf(msg, args...)
+}
+
+func (l *deviceLogger) Infof(msg string, args ...interface{}) {
+	l.record("INFO", msg, args...)
+	l.parent.Infof(msg, args...)
+}
+
+func (l *deviceLogger) Debugf(msg string, args ...interface{}) {
+	l.record("DEBUG", msg, args...)
+	l.parent.Debugf(msg, args...)
+}
+
+func (l *deviceLogger) Sub(module string) waLog.Logger {
+	return &deviceLogger{
+		parent:   l.parent.Sub(module),
+		module:   l.module + "/" + module,
+		buffer:   l.buffer,
+		minLevel: l.minLevel,
+	}
+}
+
+// logBufferSize returns the configured per-device log buffer size
+func logBufferSize() int {
+	size, err := strconv.Atoi(os.Getenv("LOG_BUFFER_LINES"))
+	if err != nil || size <= 0 {
+		return defaultLogBufferLines
+	}
+	return size
+}
+
+// GetDeviceLogs returns up to n of the most recent log lines for a device
+func (s *WhatsAppService) GetDeviceLogs(deviceID string, n int) ([]LogLine, error) {
+	client, err := s.GetSession(deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.logs.last(n), nil
+}
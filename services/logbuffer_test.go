@@ -0,0 +1,54 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+func TestDeviceLoggerSkipsFilteredLevels(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "info")
+	buffer := newLogBuffer(10)
+	logger := newDeviceLogger("dev", waLog.Noop, buffer).Sub("Client")
+
+	logger.Debugf("noisy %s", "frame")
+	logger.Infof("connected")
+	logger.Warnf("slow keepalive")
+
+	lines := buffer.last(0)
+	if len(lines) != 2 {
+		t.Fatalf("buffered %d lines, want the INFO and WARN ones: %+v", len(lines), lines)
+	}
+	if lines[0].Level != "INFO" || lines[0].Module != "dev/Client" {
+		t.Fatalf("first line = %+v", lines[0])
+	}
+}
+
+func TestDeviceLoggerBuffersDebugAtDebugLevel(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "debug")
+	buffer := newLogBuffer(10)
+	newDeviceLogger("dev", waLog.Noop, buffer).Debugf("frame")
+
+	if lines := buffer.last(0); len(lines) != 1 || lines[0].Level != "DEBUG" {
+		t.Fatalf("buffered %+v, want the DEBUG line", lines)
+	}
+}
+
+func TestRedactLogLine(t *testing.T) {
+	line := redactLogLine("Sending message to 628123456789@s.whatsapp.net and 628123456789:12@s.whatsapp.net in 120363025246125486@g.us via 12345678901234@lid for +628123456789")
+	for _, leak := range []string{"628123456789", "120363025246125486", "12345678901234"} {
+		if strings.Contains(line, leak) {
+			t.Errorf("line %q still contains %s", line, leak)
+		}
+	}
+	for _, kept := range []string{"[redacted]@s.whatsapp.net", "[redacted]@g.us", "[redacted]@lid"} {
+		if !strings.Contains(line, kept) {
+			t.Errorf("line %q lost the server of %s", line, kept)
+		}
+	}
+
+	if got := redactLogLine("Got 3 receipts in 250ms"); got != "Got 3 receipts in 250ms" {
+		t.Errorf("line without identifiers changed to %q", got)
+	}
+}
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"os"
+	"strings"
+	"sync"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+var (
+	appLogger     waLog.Logger
+	appLoggerOnce sync.Once
+)
+
+// AppLogger returns the service logger shared by WhatsAppService and the webhook service
+func AppLogger() waLog.Logger {
+	appLoggerOnce.Do(func() {
+		appLogger = waLog.Stdout("WhatsApp", logLevel(), true)
+	})
+	return appLogger
+}
+
+// logLevel maps LOG_LEVEL onto a whatsmeow log level, defaulting to INFO
+func logLevel() string {
+	switch strings.ToLower(os.Getenv("LOG_LEVEL")) {
+	case "debug":
+		return "DEBUG"
+	case "warn", "warning":
+		return "WARN"
+	case "error":
+		return "ERROR"
+	default:
+		return "INFO"
+	}
+}
+
+// debugLogging reports whether LOG_LEVEL=debug, which allows message bodies in logs
+func debugLogging() bool {
+	return strings.ToLower(os.Getenv("LOG_LEVEL")) == "debug"
+}
+
+// redactBody hides message text from logs unless debug logging is enabled
+func redactBody(text string) string {
+	if text == "" || debugLogging() {
+		return text
+	}
+	return "[redacted]"
+}
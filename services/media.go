@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// mediaHost is the default WhatsApp CDN host used to resolve direct paths
+const mediaHost = "https://mmg.whatsapp.net"
+
+// ErrInvalidMediaURL is returned when a media reference doesn't point at WhatsApp's CDN
+var ErrInvalidMediaURL = errors.New("media URL must be an https whatsapp.net URL")
+
+// mediaCheckClient never follows redirects, so a check can't be bounced off the CDN to another host
+var mediaCheckClient = &http.Client{
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// isWhatsAppHost reports whether host is whatsapp.net or one of its subdomains
+func isWhatsAppHost(host string) bool {
+	host = strings.ToLower(host)
+	return host == "whatsapp.net" || strings.HasSuffix(host, ".whatsapp.net")
+}
+
+// Media availability states reported by CheckMediaAvailability
+const (
+	MediaAvailable = "available"
+	MediaExpired   = "expired"
+	MediaUnknown   = "unknown"
+)
+
+// CheckMediaAvailability tells whether encrypted media can still be downloaded
+// from WhatsApp's CDN by requesting only its first byte
+func (s *WhatsAppService) CheckMediaAvailability(deviceID, mediaURL, directPath string) (string, int, error) {
+	if _, err := s.GetSession(deviceID); err != nil {
+		return "", 0, err
+	}
+
+	target := mediaURL
+	if target == "" {
+		if directPath == "" {
+			return "", 0, fmt.Errorf("%w: url or direct_path is required", ErrInvalidMediaURL)
+		}
+		target = mediaHost + "/" + strings.TrimPrefix(directPath, "/")
+	}
+
+	parsed, err := url.Parse(target)
+	if err != nil || parsed.Scheme != "https" || !isWhatsAppHost(parsed.Hostname()) {
+		return "", 0, ErrInvalidMediaURL
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create request: %v", err)
+	}
+	req.Header.Set("Range", "bytes=0-0")
+
+	resp, err := mediaCheckClient.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to check media: %v", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusPartialContent:
+		return MediaAvailable, resp.StatusCode, nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone || resp.StatusCode == http.StatusForbidden:
+		return MediaExpired, resp.StatusCode, nil
+	default:
+		return MediaUnknown, resp.StatusCode, nil
+	}
+}
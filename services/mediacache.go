@@ -0,0 +1,265 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"waku/utils"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+const (
+	defaultMediaCacheSize = 1000
+	defaultMediaCacheTTL  = 24 * time.Hour
+)
+
+// ErrMediaNotFound is returned when a message's media isn't cached or has expired
+var ErrMediaNotFound = errors.New("media not found or expired")
+
+// cachedMedia holds what's needed to download a received attachment again
+type cachedMedia struct {
+	message    whatsmeow.DownloadableMessage
+	mimetype   string
+	fileName   string
+	fileLength uint64
+}
+
+// DownloadedMedia is a decrypted attachment of a received message
+type DownloadedMedia struct {
+	Data     []byte
+	Mimetype string
+	FileName string
+}
+
+// newMediaCache creates the received-media cache from MEDIA_CACHE_SIZE and MEDIA_CACHE_TTL_MINUTES
+func newMediaCache() *lruCache[cachedMedia] {
+	size := defaultMediaCacheSize
+	if n, err := strconv.Atoi(os.Getenv("MEDIA_CACHE_SIZE")); err == nil && n > 0 {
+		size = n
+	}
+	ttl := defaultMediaCacheTTL
+	if minutes, err := strconv.Atoi(os.Getenv("MEDIA_CACHE_TTL_MINUTES")); err == nil && minutes > 0 {
+		ttl = time.Duration(minutes) * time.Minute
+	}
+	return newLRUCache[cachedMedia](size, ttl)
+}
+
+// mediaCacheKey scopes message IDs to their device
+func mediaCacheKey(deviceID, messageID string) string {
+	return deviceID + "/" + messageID
+}
+
+// mediaOf returns the downloadable attachment of a message, or nil when it has none
+func mediaOf(msg *waProto.Message) *cachedMedia {
+	switch {
+	case msg.GetImageMessage() != nil:
+		m := msg.GetImageMessage()
+		return &cachedMedia{message: m, mimetype: m.GetMimetype(), fileLength: m.GetFileLength()}
+	case msg.GetVideoMessage() != nil:
+		m := msg.GetVideoMessage()
+		return &cachedMedia{message: m, mimetype: m.GetMimetype(), fileLength: m.GetFileLength()}
+	case msg.GetAudioMessage() != nil:
+		m := msg.GetAudioMessage()
+		return &cachedMedia{message: m, mimetype: m.GetMimetype(), fileLength: m.GetFileLength()}
+	case msg.GetDocumentMessage() != nil:
+		m := msg.GetDocumentMessage()
+		return &cachedMedia{message: m, mimetype: m.GetMimetype(), fileName: m.GetFileName(), fileLength: m.GetFileLength()}
+	case msg.GetStickerMessage() != nil:
+		m := msg.GetStickerMessage()
+		return &cachedMedia{message: m, mimetype: m.GetMimetype(), fileLength: m.GetFileLength()}
+	default:
+		return nil
+	}
+}
+
+// cacheMedia remembers the attachment of a received message so it can be downloaded later
+func (s *WhatsAppService) cacheMedia(deviceID string, evt *events.Message) {
+	if media := mediaOf(evt.Message); media != nil {
+		s.media.Set(mediaCacheKey(deviceID, evt.Info.ID), *media)
+	}
+}
+
+// MediaPath returns the API path serving a received message's attachment
+func MediaPath(deviceID, messageID string) string {
+	return fmt.Sprintf("/media/%s/%s", deviceID, messageID)
+}
+
+// DownloadMedia downloads and decrypts the attachment of a received message
+func (s *WhatsAppService) DownloadMedia(deviceID, messageID string) (*DownloadedMedia, error) {
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	media, ok := s.media.Get(mediaCacheKey(deviceID, messageID))
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrMediaNotFound, messageID)
+	}
+
+	data, err := client.Client.Download(context.Background(), media.message)
+	if errors.Is(err, whatsmeow.ErrMediaDownloadFailedWith404) || errors.Is(err, whatsmeow.ErrMediaDownloadFailedWith410) {
+		return nil, fmt.Errorf("%w: %s", ErrMediaNotFound, messageID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to download media: %v", err)
+	}
+
+	mimetype := media.mimetype
+	if mimetype == "" {
+		mimetype = "application/octet-stream"
+	}
+	return &DownloadedMedia{Data: data, Mimetype: mimetype, FileName: media.fileName}, nil
+}
+
+// DownloadsPath is the URL prefix serving media saved for webhooks
+const DownloadsPath = "/downloads"
+
+// saveIncomingMedia downloads a received attachment into DOWNLOAD_MEDIA_DIR under
+// a hashed name and returns the URL path it is served from. Attachments larger
+// than WEBHOOK_MAX_DOWNLOAD_MB fail with ErrMediaTooLarge, checked against the
+// declared size up front and enforced while streaming to disk.
+func (s *WhatsAppService) saveIncomingMedia(deviceID string, evt *events.Message) (string, error) {
+	media := mediaOf(evt.Message)
+	if media == nil {
+		return "", fmt.Errorf("message has no media")
+	}
+
+	maxSize := webhookMaxDownloadSize()
+	if maxSize > 0 && media.fileLength > uint64(maxSize) {
+		return "", fmt.Errorf("%w: %d bytes exceeds %d", ErrMediaTooLarge, media.fileLength, maxSize)
+	}
+
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return "", err
+	}
+
+	dir := utils.DownloadMediaDir()
+	if err := utils.EnsureDiskSpace(dir, int64(media.fileLength)); err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(mediaCacheKey(deviceID, evt.Info.ID)))
+	name := downloadPrefix(deviceID) + hex.EncodeToString(sum[:]) + mediaExtension(media)
+	path := filepath.Join(dir, name)
+
+	file, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to save media: %v", err)
+	}
+
+	var target whatsmeow.File = file
+	if maxSize > 0 {
+		target = &limitedFile{File: file, limit: maxSize + downloadSizeSlack}
+	}
+	err = client.Client.DownloadToFile(context.Background(), media.message, target)
+	file.Close()
+	if err != nil {
+		os.Remove(path)
+		if errors.Is(err, errDownloadLimit) {
+			return "", fmt.Errorf("%w: exceeds %d bytes", ErrMediaTooLarge, maxSize)
+		}
+		return "", fmt.Errorf("failed to download media: %v", err)
+	}
+
+	return fmt.Sprintf("%s/%s/%s", DownloadsPath, deviceID, name), nil
+}
+
+// downloadPrefix starts the name of every file saved for a device, so a
+// download can be matched to the device that received it
+func downloadPrefix(deviceID string) string {
+	sum := sha256.Sum256([]byte(deviceID))
+	return hex.EncodeToString(sum[:8]) + "-"
+}
+
+// DownloadFilePath returns the saved media file name refers to, provided it was received by deviceID
+func DownloadFilePath(deviceID, name string) (string, error) {
+	if name != filepath.Base(name) || !strings.HasPrefix(name, downloadPrefix(deviceID)) {
+		return "", ErrMediaNotFound
+	}
+
+	path := filepath.Join(utils.DownloadMediaDir(), name)
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		return "", ErrMediaNotFound
+	}
+	return path, nil
+}
+
+// mediaExtension picks a file extension for saved media from its name or MIME type
+func mediaExtension(media *cachedMedia) string {
+	if ext := filepath.Ext(media.fileName); ext != "" {
+		return strings.ToLower(ext)
+	}
+	mimetype, _, _ := strings.Cut(media.mimetype, ";")
+	if exts, err := mime.ExtensionsByType(strings.TrimSpace(mimetype)); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ".bin"
+}
+
+// defaultWebhookMaxDownloadSize caps media saved for webhooks unless WEBHOOK_MAX_DOWNLOAD_MB says otherwise
+const defaultWebhookMaxDownloadSize = 100 << 20
+
+// downloadSizeSlack allows for the encryption padding and MAC on top of the plaintext size
+const downloadSizeSlack = 64 << 10
+
+// ErrMediaTooLarge is returned when received media exceeds WEBHOOK_MAX_DOWNLOAD_MB
+var ErrMediaTooLarge = errors.New("media exceeds the maximum download size")
+
+// errDownloadLimit aborts a download that grows past its size limit
+var errDownloadLimit = errors.New("download size limit reached")
+
+// webhookMaxDownloadSize reads WEBHOOK_MAX_DOWNLOAD_MB in bytes; 0 means unlimited
+func webhookMaxDownloadSize() int64 {
+	raw := os.Getenv("WEBHOOK_MAX_DOWNLOAD_MB")
+	if raw == "" {
+		return defaultWebhookMaxDownloadSize
+	}
+	mb, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil || mb < 0 {
+		return defaultWebhookMaxDownloadSize
+	}
+	return mb << 20
+}
+
+// limitedFile fails writes that would grow the file past limit, so a sender
+// lying about the attachment size can't fill the disk
+type limitedFile struct {
+	*os.File
+	limit int64
+}
+
+func (f *limitedFile) Write(p []byte) (int, error) {
+	offset, err := f.File.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if offset+int64(len(p)) > f.limit {
+		return 0, errDownloadLimit
+	}
+	return f.File.Write(p)
+}
+
+func (f *limitedFile) WriteAt(p []byte, off int64) (int, error) {
+	if off+int64(len(p)) > f.limit {
+		return 0, errDownloadLimit
+	}
+	return f.File.WriteAt(p, off)
+}
+
+// ReadFrom routes io.Copy through Write; the embedded file's ReadFrom would bypass the limit
+func (f *limitedFile) ReadFrom(r io.Reader) (int64, error) {
+	return io.Copy(struct{ io.Writer }{f}, r)
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// MetricsEnabled reports whether METRICS_ENABLED is set, exposing /metrics
+func MetricsEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("METRICS_ENABLED"))
+	return enabled
+}
+
+// Prometheus metrics. Device IDs are operator-chosen and few, so they are
+// used as labels; recipients and webhook URLs are not.
+var (
+	messagesSent = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "waku_messages_sent_total",
+		Help: "Messages accepted by the WhatsApp server, by device and message type.",
+	}, []string{"device_id", "type"})
+
+	messagesFailed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "waku_messages_failed_total",
+		Help: "Messages that failed to send, by device and message type.",
+	}, []string{"device_id", "type"})
+
+	webhookDeliveries = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "waku_webhook_deliveries_total",
+		Help: "Webhook payloads delivered successfully.",
+	})
+
+	webhookFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "waku_webhook_failures_total",
+		Help: "Webhook payloads that were not delivered, by reason (retries_exhausted or circuit_open).",
+	}, []string{"reason"})
+
+	qrCodesGenerated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "waku_qr_codes_generated_total",
+		Help: "QR codes generated for pairing, by device.",
+	}, []string{"device_id"})
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "waku_sessions",
+		Help: "Sessions loaded on the server.",
+	}, func() float64 {
+		return float64(countSessions(false))
+	})
+
+	_ = promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "waku_sessions_connected",
+		Help: "Sessions currently connected to WhatsApp.",
+	}, func() float64 {
+		return float64(countSessions(true))
+	})
+)
+
+// countSessions counts loaded sessions, only the connected ones if connectedOnly
+func countSessions(connectedOnly bool) int {
+	if waService == nil {
+		return 0
+	}
+	count := 0
+	for _, client := range waService.GetAllSessions() {
+		if !connectedOnly || client.IsConnected() {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// defaultNumberCacheTTL is how long on-WhatsApp results are reused unless NUMBER_CACHE_TTL_MINUTES says otherwise
+const defaultNumberCacheTTL = time.Hour
+
+// MaxCheckNumbers is the largest batch accepted by CheckNumbers
+const MaxCheckNumbers = 100
+
+// Digit bounds of an international phone number without the leading +
+const (
+	minPhoneDigits = 7
+	maxPhoneDigits = 15
+)
+
+// NumberCheck is the on-WhatsApp status of one phone number
+type NumberCheck struct {
+	Phone      string `json:"phone"`
+	OnWhatsApp bool   `json:"on_whatsapp"`
+	// Registered mirrors OnWhatsApp
+	Registered bool   `json:"registered"`
+	JID        string `json:"jid,omitempty"`
+	Cached     bool   `json:"cached"`
+}
+
+// NumberCacheStats reports how well the on-WhatsApp cache is saving lookups
+type NumberCacheStats struct {
+	Hits       int64 `json:"hits"`
+	Misses     int64 `json:"misses"`
+	Entries    int   `json:"entries"`
+	TTLSeconds int64 `json:"ttl_seconds"`
+}
+
+// numberChecker caches IsOnWhatsApp results, which WhatsApp rate-limits
+type numberChecker struct {
+	cache  *ttlCache[NumberCheck]
+	ttl    time.Duration
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+// newNumberChecker creates a checker using NUMBER_CACHE_TTL_MINUTES
+func newNumberChecker() *numberChecker {
+	ttl := defaultNumberCacheTTL
+	if minutes, err := strconv.Atoi(os.Getenv("NUMBER_CACHE_TTL_MINUTES")); err == nil && minutes > 0 {
+		ttl = time.Duration(minutes) * time.Minute
+	}
+	return &numberChecker{cache: newTTLCache[NumberCheck](ttl), ttl: ttl}
+}
+
+// phoneFormatting is the punctuation people write phone numbers with
+var phoneFormatting = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "", ".", "")
+
+// normalizePhone strips the leading + and formatting so cache keys don't depend on them
+func normalizePhone(phone string) string {
+	return strings.TrimPrefix(phoneFormatting.Replace(strings.TrimSpace(phone)), "+")
+}
+
+// NormalizePhoneNumber returns a phone number as bare international digits,
+// or false when it isn't a plausible phone number
+func NormalizePhoneNumber(phone string) (string, bool) {
+	phone = normalizePhone(phone)
+	if len(phone) < minPhoneDigits || len(phone) > maxPhoneDigits {
+		return "", false
+	}
+	for _, r := range phone {
+		if r < '0' || r > '9' {
+			return "", false
+		}
+	}
+	return phone, true
+}
+
+// CheckNumbers reports which phone numbers are registered on WhatsApp. Cached
+// results are reused unless bypassCache is set; only the rest are queried.
+func (s *WhatsAppService) CheckNumbers(deviceID string, phones []string, bypassCache bool) ([]NumberCheck, error) {
+	if len(phones) > MaxCheckNumbers {
+		return nil, fmt.Errorf("at most %d numbers can be checked at once", MaxCheckNumbers)
+	}
+
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	checker := s.numbers
+	results := make([]NumberCheck, len(phones))
+	pending := make(map[string][]int)
+	var queries []string
+
+	for i, phone := range phones {
+		phone = normalizePhone(phone)
+		if !bypassCache {
+			if cached, ok := checker.cache.Get(phone); ok {
+				checker.hits.Add(1)
+				cached.Cached = true
+				results[i] = cached
+				continue
+			}
+		}
+
+		checker.misses.Add(1)
+		results[i] = NumberCheck{Phone: phone}
+		if _, queued := pending[phone]; !queued {
+			queries = append(queries, "+"+phone)
+		}
+		pending[phone] = append(pending[phone], i)
+	}
+
+	if len(queries) == 0 {
+		return results, nil
+	}
+
+	resp, err := client.Client.IsOnWhatsApp(queries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check numbers: %v", err)
+	}
+
+	for _, r := range resp {
+		phone := normalizePhone(r.Query)
+		check := NumberCheck{Phone: phone, OnWhatsApp: r.IsIn, Registered: r.IsIn}
+		if r.IsIn {
+			check.JID = r.JID.String()
+		}
+		checker.cache.Set(phone, check)
+		for _, i := range pending[phone] {
+			results[i] = check
+		}
+	}
+
+	return results, nil
+}
+
+// NumberCacheStats returns the on-WhatsApp cache hit/miss counters
+func (s *WhatsAppService) NumberCacheStats() NumberCacheStats {
+	return NumberCacheStats{
+		Hits:       s.numbers.hits.Load(),
+		Misses:     s.numbers.misses.Load(),
+		Entries:    s.numbers.cache.Len(),
+		TTLSeconds: int64(s.numbers.ttl.Seconds()),
+	}
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+)
+
+// pairCodeWait bounds how long PairWithCode waits for the socket to be ready for pairing
+const pairCodeWait = 15 * time.Second
+
+// ErrAlreadyPaired is returned when code pairing is requested for a logged-in session
+var ErrAlreadyPaired = errors.New("session is already paired")
+
+// SessionOptions controls how a new session pairs
+type SessionOptions struct {
+	// PairWithCode skips QR pairing; the session connects when a pairing code is requested
+	PairWithCode bool
+	// Name is a human-readable label stored as the device's display name
+	Name string
+}
+
+// pairingReady returns the channel closed once the socket can accept a pairing request
+func (dc *DeviceClient) pairingReady() chan struct{} {
+	dc.pairOnce.Do(func() {
+		dc.pairReady = make(chan struct{})
+	})
+	return dc.pairReady
+}
+
+// markPairingReady signals that WhatsApp has offered pairing, which happens with the first QR event
+func (dc *DeviceClient) markPairingReady() {
+	ready := dc.pairingReady()
+	select {
+	case <-ready:
+	default:
+		close(ready)
+	}
+}
+
+// PairWithCode links a session by phone number and returns the 8-character code
+// the user enters under Linked Devices on their phone
+func (s *WhatsAppService) PairWithCode(deviceID, phone string) (string, error) {
+	client, err := s.GetSession(deviceID)
+	if err != nil {
+		return "", err
+	}
+
+	if client.Client.Store.ID != nil {
+		return "", fmt.Errorf("%w: %s", ErrAlreadyPaired, deviceID)
+	}
+
+	if !client.Client.IsConnected() {
+		if err := client.Client.Connect(); err != nil {
+			return "", fmt.Errorf("failed to connect: %v", err)
+		}
+	}
+
+	select {
+	case <-client.pairingReady():
+	case <-time.After(pairCodeWait):
+		return "", fmt.Errorf("timed out waiting for WhatsApp to accept pairing")
+	}
+
+	code, err := client.Client.PairPhone(context.Background(), normalizePhone(phone), true, whatsmeow.PairClientChrome, "Chrome (Linux)")
+	if err != nil {
+		return "", fmt.Errorf("failed to request pairing code: %v", err)
+	}
+
+	s.logger.Infof("Pairing code requested for device: %s", deviceID)
+	return code, nil
+}
@@ -0,0 +1,99 @@
+package services
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// EventSessionExpired is sent when an unpaired session is removed after PENDING_SESSION_TTL_MINUTES
+const EventSessionExpired = "session_expired"
+
+// pendingSessionCheckInterval is how often abandoned pairings are looked for
+const pendingSessionCheckInterval = time.Minute
+
+// PendingSession is a session still waiting for its QR code to be scanned
+type PendingSession struct {
+	DeviceID   string     `json:"device_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	AgeSeconds int64      `json:"age_seconds"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+// pendingSessionTTL reads PENDING_SESSION_TTL_MINUTES; 0 disables auto-expiry
+func pendingSessionTTL() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("PENDING_SESSION_TTL_MINUTES"))
+	if err != nil || minutes <= 0 {
+		return 0
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// isPending reports whether a session has never been paired
+func (dc *DeviceClient) isPending() bool {
+	return dc.Client.Store.ID == nil
+}
+
+// GetPendingSessions lists sessions still waiting for a QR scan, oldest first
+func (s *WhatsAppService) GetPendingSessions() []PendingSession {
+	ttl := pendingSessionTTL()
+	now := time.Now()
+
+	pending := make([]PendingSession, 0)
+	for _, client := range s.GetAllSessions() {
+		if !client.isPending() {
+			continue
+		}
+
+		session := PendingSession{
+			DeviceID:   client.DeviceID,
+			CreatedAt:  client.CreatedAt,
+			AgeSeconds: int64(now.Sub(client.CreatedAt).Seconds()),
+		}
+		if ttl > 0 {
+			expiresAt := client.CreatedAt.Add(ttl)
+			session.ExpiresAt = &expiresAt
+		}
+		pending = append(pending, session)
+	}
+
+	sort.Slice(pending, func(i, j int) bool {
+		return pending[i].CreatedAt.Before(pending[j].CreatedAt)
+	})
+	return pending
+}
+
+// expirePendingSessions deletes sessions that stayed unpaired longer than ttl
+func (s *WhatsAppService) expirePendingSessions(ttl time.Duration) {
+	for _, session := range s.GetPendingSessions() {
+		if time.Duration(session.AgeSeconds)*time.Second < ttl {
+			continue
+		}
+
+		if err := s.DeleteSession(session.DeviceID); err != nil {
+			s.logger.Errorf("Failed to expire pending session %s: %v", session.DeviceID, err)
+			continue
+		}
+
+		s.logger.Infof("Expired pending session %s after %ds without a QR scan", session.DeviceID, session.AgeSeconds)
+		GetWebhookService().SendEvent(session.DeviceID, EventSessionExpired, map[string]interface{}{
+			"created_at":  session.CreatedAt.Unix(),
+			"age_seconds": session.AgeSeconds,
+		})
+	}
+}
+
+// reapPendingSessions periodically expires abandoned pairings when a TTL is configured
+func (s *WhatsAppService) reapPendingSessions() {
+	ttl := pendingSessionTTL()
+	if ttl == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(pendingSessionCheckInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.expirePendingSessions(ttl)
+	}
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// Connection states reported in PhoneState
+const (
+	ConnectionOnline         = "online"
+	ConnectionOffline        = "offline"
+	ConnectionKeepAliveLost  = "keepalive_timeout"
+	ConnectionStreamReplaced = "stream_replaced"
+	ConnectionLoggedOut      = "logged_out"
+)
+
+// PhoneState is the last-known state of the phone behind a session. Fields
+// stay nil until the corresponding event has been received; multi-device
+// connections currently never report battery, so those remain nil.
+type PhoneState struct {
+	BatteryLevel *int       `json:"battery_level"`
+	Charging     *bool      `json:"charging"`
+	Connection   *string    `json:"connection"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+}
+
+// phoneState guards a device's PhoneState
+type phoneState struct {
+	mu    sync.RWMutex
+	state PhoneState
+}
+
+// setConnection records a connectivity change
+func (p *phoneState) setConnection(connection string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	p.state.Connection = &connection
+	p.state.UpdatedAt = &now
+}
+
+// snapshot returns a copy of the current state
+func (p *phoneState) snapshot() PhoneState {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.state
+}
+
+// GetPhoneState returns the last-known phone state of a session
+func (s *WhatsAppService) GetPhoneState(deviceID string) (PhoneState, error) {
+	client, err := s.GetSession(deviceID)
+	if err != nil {
+		return PhoneState{}, err
+	}
+
+	return client.phone.snapshot(), nil
+}
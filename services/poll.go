@@ -0,0 +1,195 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+const (
+	// MinPollOptions and MaxPollOptions bound the number of poll options WhatsApp accepts
+	MinPollOptions = 2
+	MaxPollOptions = 12
+
+	// pollCacheTTL is how long polls are remembered for tallying votes
+	pollCacheTTL = 7 * 24 * time.Hour
+)
+
+// ErrInvalidPoll is returned when a poll's question, options or selectable count are invalid
+var ErrInvalidPoll = errors.New("invalid poll")
+
+// PollOptionCount is the number of votes for one poll option
+type PollOptionCount struct {
+	Name  string `json:"name"`
+	Votes int    `json:"votes"`
+}
+
+// PollVote is a decrypted poll vote with the poll's running tally
+type PollVote struct {
+	PollID   string            `json:"poll_id"`
+	Question string            `json:"question"`
+	Voter    string            `json:"voter"`
+	Selected []string          `json:"selected"`
+	Counts   []PollOptionCount `json:"counts"`
+}
+
+// pollTally keeps a poll's options and each voter's latest selection,
+// since every vote replaces the voter's previous one
+type pollTally struct {
+	mu       sync.Mutex
+	question string
+	options  []string
+	votes    map[string][]string
+}
+
+func newPollTally(question string, options []string) *pollTally {
+	return &pollTally{question: question, options: options, votes: make(map[string][]string)}
+}
+
+// vote records a voter's selection, given as option hashes, and returns the selected names
+func (t *pollTally) vote(voter string, hashes [][]byte) []string {
+	names := make(map[string]string, len(t.options))
+	for i, hash := range whatsmeow.HashPollOptions(t.options) {
+		names[string(hash)] = t.options[i]
+	}
+
+	selected := make([]string, 0, len(hashes))
+	for _, hash := range hashes {
+		if name, ok := names[string(hash)]; ok {
+			selected = append(selected, name)
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.votes[voter] = selected
+	return selected
+}
+
+// counts returns the number of votes per option, in option order
+func (t *pollTally) counts() []PollOptionCount {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	counts := make([]PollOptionCount, len(t.options))
+	index := make(map[string]int, len(t.options))
+	for i, name := range t.options {
+		counts[i].Name = name
+		index[name] = i
+	}
+	for _, selected := range t.votes {
+		for _, name := range selected {
+			counts[index[name]].Votes++
+		}
+	}
+	return counts
+}
+
+// pollCacheKey scopes poll message IDs to their device
+func pollCacheKey(deviceID, pollID string) string {
+	return deviceID + "/" + pollID
+}
+
+// pollCreationOf returns a message's poll, whichever version it was sent as
+func pollCreationOf(msg *waProto.Message) *waProto.PollCreationMessage {
+	switch {
+	case msg.GetPollCreationMessage() != nil:
+		return msg.GetPollCreationMessage()
+	case msg.GetPollCreationMessageV2() != nil:
+		return msg.GetPollCreationMessageV2()
+	case msg.GetPollCreationMessageV3() != nil:
+		return msg.GetPollCreationMessageV3()
+	default:
+		return nil
+	}
+}
+
+// observePoll remembers polls seen in a chat so later votes on them can be tallied
+func (s *WhatsAppService) observePoll(deviceID string, evt *events.Message) {
+	poll := pollCreationOf(evt.Message)
+	if poll == nil {
+		return
+	}
+
+	options := make([]string, 0, len(poll.GetOptions()))
+	for _, option := range poll.GetOptions() {
+		options = append(options, option.GetOptionName())
+	}
+	s.polls.Set(pollCacheKey(deviceID, evt.Info.ID), newPollTally(poll.GetName(), options))
+}
+
+// SendPoll sends a poll allowing up to selectable options to be chosen; 0 allows any number
+func (s *WhatsAppService) SendPoll(deviceID, phone, groupJID, question string, options []string, selectable int, opts SendOptions) (string, int64, error) {
+	if question == "" {
+		return "", 0, fmt.Errorf("%w: question is required", ErrInvalidPoll)
+	}
+	if len(options) < MinPollOptions || len(options) > MaxPollOptions {
+		return "", 0, fmt.Errorf("%w: between %d and %d options are required", ErrInvalidPoll, MinPollOptions, MaxPollOptions)
+	}
+	seen := make(map[string]bool, len(options))
+	for _, option := range options {
+		if option == "" || seen[option] {
+			return "", 0, fmt.Errorf("%w: options must be non-empty and unique", ErrInvalidPoll)
+		}
+		seen[option] = true
+	}
+	if selectable < 0 || selectable > len(options) {
+		return "", 0, fmt.Errorf("%w: selectable_count must be between 0 and %d", ErrInvalidPoll, len(options))
+	}
+
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	jid, err := resolveRecipient(phone, groupJID)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := s.ensureGroupMember(client, jid); err != nil {
+		return "", 0, err
+	}
+
+	resp, err := s.deliver(client, jid, client.Client.BuildPollCreation(question, options, selectable), opts)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to send poll: %v", err)
+	}
+
+	s.polls.Set(pollCacheKey(deviceID, resp.ID), newPollTally(question, options))
+	return resp.ID, resp.Timestamp.Unix(), nil
+}
+
+// decryptPollVote decrypts a poll vote and updates the poll's tally. Votes
+// on polls this server hasn't seen are returned without option names.
+func (s *WhatsAppService) decryptPollVote(deviceID string, evt *events.Message) (*PollVote, error) {
+	client, err := s.GetSession(deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	vote, err := client.Client.DecryptPollVote(context.Background(), evt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt poll vote: %v", err)
+	}
+
+	result := &PollVote{
+		PollID:   evt.Message.GetPollUpdateMessage().GetPollCreationMessageKey().GetID(),
+		Voter:    extractPhoneNumber(evt.Info.Sender),
+		Selected: []string{},
+	}
+
+	tally, ok := s.polls.Get(pollCacheKey(deviceID, result.PollID))
+	if !ok {
+		return result, nil
+	}
+	result.Question = tally.question
+	result.Selected = tally.vote(evt.Info.Sender.ToNonAD().String(), vote.GetSelectedOptions())
+	result.Counts = tally.counts()
+	return result, nil
+}
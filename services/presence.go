@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// EventPresence is the webhook event for a subscribed contact going online or offline
+const EventPresence = "presence"
+
+// presenceCacheTTL is how long a contact's last-known presence is kept
+const presenceCacheTTL = 24 * time.Hour
+
+var (
+	// ErrInvalidPresence is returned for an unknown presence state
+	ErrInvalidPresence = errors.New("invalid presence state")
+	// ErrPresenceUnknown is returned when no presence update has arrived for a contact
+	ErrPresenceUnknown = errors.New("presence unknown")
+)
+
+// ContactPresence is the last-known online status of a contact
+type ContactPresence struct {
+	Phone string `json:"phone"`
+	JID   string `json:"jid"`
+	// Status is online or offline
+	Status string `json:"status"`
+	// LastSeen is 0 when the contact hides their last seen time
+	LastSeen  int64 `json:"last_seen,omitempty"`
+	UpdatedAt int64 `json:"updated_at"`
+}
+
+// chatPresences maps the API's chat presence states to WhatsApp's state and media
+var chatPresences = map[string]struct {
+	state types.ChatPresence
+	media types.ChatPresenceMedia
+}{
+	"composing": {types.ChatPresenceComposing, types.ChatPresenceMediaText},
+	"recording": {types.ChatPresenceComposing, types.ChatPresenceMediaAudio},
+	"paused":    {types.ChatPresencePaused, types.ChatPresenceMediaText},
+}
+
+// SendChatPresence shows or clears the typing or recording indicator in a chat.
+// WhatsApp only relays it after the session has announced itself available.
+func (s *WhatsAppService) SendChatPresence(deviceID, phone, groupJID, state string) error {
+	presence, ok := chatPresences[state]
+	if !ok {
+		return fmt.Errorf("%w: %q, use composing, recording or paused", ErrInvalidPresence, state)
+	}
+
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return err
+	}
+
+	jid, err := resolveRecipient(phone, groupJID)
+	if err != nil {
+		return err
+	}
+
+	if err := client.Client.SendChatPresence(jid, presence.state, presence.media); err != nil {
+		return fmt.Errorf("failed to send chat presence: %v", err)
+	}
+	return nil
+}
+
+// SetAvailability marks the session as online or offline for its contacts
+func (s *WhatsAppService) SetAvailability(deviceID, availability string) error {
+	presence := types.Presence(availability)
+	if presence != types.PresenceAvailable && presence != types.PresenceUnavailable {
+		return fmt.Errorf("%w: %q, use available or unavailable", ErrInvalidPresence, availability)
+	}
+
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return err
+	}
+
+	if err := client.Client.SendPresence(presence); err != nil {
+		return fmt.Errorf("failed to send presence: %v", err)
+	}
+	return nil
+}
+
+// presenceKey scopes a contact's presence to its device
+func presenceKey(deviceID, phone string) string {
+	return deviceID + "|" + phone
+}
+
+// SubscribePresence asks WhatsApp to send the presence updates of a contact.
+// Updates only arrive while the session is available and if the contact
+// shares their online status.
+func (s *WhatsAppService) SubscribePresence(deviceID, phone string) (types.JID, error) {
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return types.JID{}, err
+	}
+
+	jid, err := resolveRecipient(phone, "")
+	if err != nil {
+		return types.JID{}, err
+	}
+
+	if err := client.Client.SubscribePresence(jid); err != nil {
+		return types.JID{}, fmt.Errorf("failed to subscribe to presence: %v", err)
+	}
+	return jid, nil
+}
+
+// GetContactPresence returns the last presence update received for a contact
+func (s *WhatsAppService) GetContactPresence(deviceID, phone string) (ContactPresence, error) {
+	if _, err := s.GetSession(deviceID); err != nil {
+		return ContactPresence{}, err
+	}
+
+	presence, ok := s.presences.Get(presenceKey(deviceID, phone))
+	if !ok {
+		return ContactPresence{}, fmt.Errorf("%w for %s, subscribe to it first", ErrPresenceUnknown, phone)
+	}
+	return presence, nil
+}
+
+// observePresence records a contact's presence update and forwards it to the webhook
+func (s *WhatsAppService) observePresence(dc *DeviceClient, evt *events.Presence) {
+	from := evt.From.ToNonAD()
+	// Contacts addressed by LID are stored under their phone number when known
+	if from.Server == types.HiddenUserServer {
+		if pn, err := dc.Client.Store.LIDs.GetPNForLID(context.Background(), from); err == nil && !pn.IsEmpty() {
+			from = pn
+		}
+	}
+
+	presence := ContactPresence{
+		Phone:     from.User,
+		JID:       from.String(),
+		Status:    "online",
+		UpdatedAt: time.Now().Unix(),
+	}
+	if evt.Unavailable {
+		presence.Status = "offline"
+	}
+	if !evt.LastSeen.IsZero() {
+		presence.LastSeen = evt.LastSeen.Unix()
+	}
+
+	s.presences.Set(presenceKey(dc.DeviceID, presence.Phone), presence)
+	GetWebhookService().SendEvent(dc.DeviceID, EventPresence, presence)
+}
@@ -0,0 +1,61 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/types"
+)
+
+// profilePictureCacheTTL is how long fetched profile picture info is reused
+const profilePictureCacheTTL = 10 * time.Minute
+
+// ProfilePicture describes the profile picture of a user or group
+type ProfilePicture struct {
+	JID       string  `json:"jid"`
+	URL       *string `json:"url"`
+	ID        string  `json:"id,omitempty"`
+	Type      string  `json:"type,omitempty"`
+	Preview   bool    `json:"preview"`
+	FetchedAt int64   `json:"fetched_at"`
+}
+
+// GetProfilePicture fetches the profile picture of a user or group. A nil URL
+// means no picture is set. Results are cached briefly per device.
+func (s *WhatsAppService) GetProfilePicture(deviceID string, jid types.JID, preview bool) (*ProfilePicture, error) {
+	client, err := s.GetSession(deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !client.IsConnected() {
+		return nil, fmt.Errorf("%w. Please scan QR code first", ErrSessionNotConnected)
+	}
+
+	cacheKey := fmt.Sprintf("%s|%s|%t", deviceID, jid.String(), preview)
+	if picture, ok := s.pictureCache.Get(cacheKey); ok {
+		return picture, nil
+	}
+
+	picture := &ProfilePicture{
+		JID:       jid.String(),
+		Preview:   preview,
+		FetchedAt: time.Now().Unix(),
+	}
+
+	info, err := client.Client.GetProfilePictureInfo(jid, &whatsmeow.GetProfilePictureParams{Preview: preview})
+	if err != nil && !errors.Is(err, whatsmeow.ErrProfilePictureNotSet) {
+		return nil, fmt.Errorf("failed to get profile picture: %w", err)
+	}
+
+	if info != nil {
+		picture.URL = &info.URL
+		picture.ID = info.ID
+		picture.Type = info.Type
+	}
+
+	s.pictureCache.Set(cacheKey, picture)
+	return picture, nil
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// QRCode is the pairing code a session shows now
+type QRCode struct {
+	// Code is empty when no code is valid
+	Code      string
+	ExpiresIn time.Duration
+	// Expired reports that every code of the pairing attempt has run out
+	Expired bool
+}
+
+// qrState holds a session's pairing codes. Readers look the current code up
+// instead of consuming it, so any number of them see the same code.
+type qrState struct {
+	mu       sync.Mutex
+	codes    []string
+	issuedAt time.Time
+	// changed is closed and replaced whenever the codes change
+	changed chan struct{}
+}
+
+// changedLocked returns the channel closed on the next update
+func (q *qrState) changedLocked() chan struct{} {
+	if q.changed == nil {
+		q.changed = make(chan struct{})
+	}
+	return q.changed
+}
+
+// set stores the codes of a new pairing attempt and wakes every waiting reader
+func (q *qrState) set(codes []string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.codes = codes
+	q.issuedAt = time.Now()
+	close(q.changedLocked())
+	q.changed = nil
+}
+
+// current returns the code valid at now, rotating through the codes the way
+// whatsmeow's QR channel does, and the channel closed on the next update
+func (q *qrState) current(now time.Time) (QRCode, <-chan struct{}) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	changed := q.changedLocked()
+	if len(q.codes) == 0 {
+		return QRCode{}, changed
+	}
+
+	validUntil := q.issuedAt
+	for i, code := range q.codes {
+		validUntil = validUntil.Add(QRCodeTimeout(i))
+		if now.Before(validUntil) {
+			return QRCode{Code: code, ExpiresIn: validUntil.Sub(now)}, changed
+		}
+	}
+	return QRCode{Expired: true}, changed
+}
+
+// CurrentQRCode returns the session's current pairing code and a channel
+// closed when a new pairing attempt replaces the codes
+func (dc *DeviceClient) CurrentQRCode() (QRCode, <-chan struct{}) {
+	return dc.qr.current(time.Now())
+}
+
+// WaitQRCode returns the session's current pairing code, waiting for one to
+// be issued until ctx is done
+func (dc *DeviceClient) WaitQRCode(ctx context.Context) (QRCode, error) {
+	for {
+		qr, changed := dc.CurrentQRCode()
+		if qr.Code != "" {
+			return qr, nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return QRCode{}, ctx.Err()
+		}
+	}
+}
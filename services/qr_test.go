@@ -0,0 +1,78 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWaitQRCodeWakesEveryReader(t *testing.T) {
+	dc := &DeviceClient{}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	const readers = 20
+	codes := make(chan string, readers)
+	var started, done sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		started.Add(1)
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			started.Done()
+			qr, err := dc.WaitQRCode(ctx)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			codes <- qr.Code
+		}()
+	}
+	started.Wait()
+
+	dc.qr.set([]string{"code-1", "code-2"})
+	done.Wait()
+	close(codes)
+
+	seen := 0
+	for code := range codes {
+		seen++
+		if code != "code-1" {
+			t.Errorf("reader got %q, want code-1", code)
+		}
+	}
+	if seen != readers {
+		t.Fatalf("%d of %d readers got a code", seen, readers)
+	}
+}
+
+func TestQRStateRotatesAndExpires(t *testing.T) {
+	var q qrState
+	q.set([]string{"code-1", "code-2"})
+	issued := q.issuedAt
+
+	for _, tt := range []struct {
+		at      time.Duration
+		code    string
+		expired bool
+	}{
+		{0, "code-1", false},
+		{QRCodeTimeout(0) - time.Second, "code-1", false},
+		{QRCodeTimeout(0), "code-2", false},
+		{QRCodeTimeout(0) + QRCodeTimeout(1), "", true},
+	} {
+		qr, _ := q.current(issued.Add(tt.at))
+		if qr.Code != tt.code || qr.Expired != tt.expired {
+			t.Errorf("at %s: got %+v, want code %q expired %v", tt.at, qr, tt.code, tt.expired)
+		}
+	}
+
+	_, changed := q.current(issued)
+	q.set([]string{"code-3"})
+	select {
+	case <-changed:
+	default:
+		t.Fatal("set didn't close the changed channel")
+	}
+}
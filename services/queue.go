@@ -0,0 +1,150 @@
+package services
+
+import (
+	"container/heap"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+)
+
+// sendQueueEnabled reports whether sends are serialized through the per-device queue
+func sendQueueEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("SEND_QUEUE_ENABLED"))
+	return enabled
+}
+
+// sendQueueDelay returns the pause the queue worker takes between two sends
+func sendQueueDelay() time.Duration {
+	ms, err := strconv.Atoi(os.Getenv("SEND_QUEUE_DELAY_MS"))
+	if err != nil || ms < 0 {
+		return 0
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// sendResult is the outcome of a queued send
+type sendResult struct {
+	resp whatsmeow.SendResponse
+	err  error
+}
+
+// sendJob is a single queued send
+type sendJob struct {
+	priority bool
+	seq      uint64
+	run      func() (whatsmeow.SendResponse, error)
+	done     chan sendResult
+}
+
+// sendJobHeap orders priority jobs first, then by submission order
+type sendJobHeap []*sendJob
+
+func (h sendJobHeap) Len() int { return len(h) }
+func (h sendJobHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h sendJobHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *sendJobHeap) Push(x interface{}) { *h = append(*h, x.(*sendJob)) }
+func (h *sendJobHeap) Pop() interface{} {
+	old := *h
+	job := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return job
+}
+
+// sendQueue runs a device's sends one at a time. Priority jobs jump ahead of
+// every queued normal job but never interrupt the send already in flight;
+// jobs of equal priority keep FIFO order.
+type sendQueue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	jobs   sendJobHeap
+	seq    uint64
+	closed bool
+}
+
+func newSendQueue() *sendQueue {
+	q := &sendQueue{}
+	q.cond = sync.NewCond(&q.mu)
+	go q.worker()
+	return q
+}
+
+// submit queues a send and blocks until it has been executed
+func (q *sendQueue) submit(priority bool, run func() (whatsmeow.SendResponse, error)) (whatsmeow.SendResponse, error) {
+	job := &sendJob{
+		priority: priority,
+		run:      run,
+		done:     make(chan sendResult, 1),
+	}
+
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return whatsmeow.SendResponse{}, errSessionClosed
+	}
+	q.seq++
+	job.seq = q.seq
+	heap.Push(&q.jobs, job)
+	q.cond.Signal()
+	q.mu.Unlock()
+
+	result := <-job.done
+	return result.resp, result.err
+}
+
+// pending returns the number of queued jobs
+func (q *sendQueue) pending() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.jobs)
+}
+
+// close stops the worker and fails every job still waiting
+func (q *sendQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.closed = true
+	for len(q.jobs) > 0 {
+		job := heap.Pop(&q.jobs).(*sendJob)
+		job.done <- sendResult{err: errSessionClosed}
+	}
+	q.cond.Broadcast()
+}
+
+func (q *sendQueue) worker() {
+	for {
+		q.mu.Lock()
+		for len(q.jobs) == 0 && !q.closed {
+			q.cond.Wait()
+		}
+		if q.closed {
+			q.mu.Unlock()
+			return
+		}
+		job := heap.Pop(&q.jobs).(*sendJob)
+		q.mu.Unlock()
+
+		resp, err := job.run()
+		job.done <- sendResult{resp: resp, err: err}
+
+		if delay := sendQueueDelay(); delay > 0 {
+			time.Sleep(delay)
+		}
+	}
+}
+
+// sendQueue returns the device's send queue, creating it on first use
+func (dc *DeviceClient) sendQueue() *sendQueue {
+	dc.queueOnce.Do(func() {
+		dc.queue = newSendQueue()
+	})
+	return dc.queue
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// ErrReconnectTimeout is returned when a reconnected session doesn't come
+// back online in time; it may still connect in the background
+var ErrReconnectTimeout = errors.New("session did not reconnect in time")
+
+// reconnectTimeout is how long Reconnect waits for the Connected event
+const reconnectTimeout = 15 * time.Second
+
+// Reconnect drops a session's socket and connects again with its stored
+// credentials, waiting for it to come back online. A connected session is
+// left alone and reported as not reconnected.
+func (s *WhatsAppService) Reconnect(deviceID string) (*DeviceClient, bool, error) {
+	client, err := s.GetSession(deviceID)
+	if err != nil {
+		return nil, false, err
+	}
+	if client.Client.Store.ID == nil {
+		return nil, false, fmt.Errorf("%w: device is not paired. Please scan QR code first", ErrSessionNotConnected)
+	}
+	if client.IsConnected() && client.Client.IsConnected() {
+		return client, false, nil
+	}
+
+	// Listen before connecting so the event can't be missed
+	result := make(chan error, 1)
+	handlerID := client.Client.AddEventHandler(func(evt interface{}) {
+		var err error
+		switch v := evt.(type) {
+		case *events.Connected:
+		case *events.LoggedOut:
+			err = fmt.Errorf("%w: device was logged out (%s). Please scan QR code first", ErrSessionNotConnected, v.Reason)
+		case *events.ConnectFailure:
+			err = fmt.Errorf("%w: connection failed (%s)", ErrSessionNotConnected, v.Reason)
+		default:
+			return
+		}
+		select {
+		case result <- err:
+		default:
+		}
+	})
+	defer client.Client.RemoveEventHandler(handlerID)
+
+	client.Client.Disconnect()
+	client.markDisconnected()
+	if err := client.Client.Connect(); err != nil {
+		return nil, false, fmt.Errorf("failed to connect: %v", err)
+	}
+
+	select {
+	case err := <-result:
+		if err != nil {
+			return nil, false, err
+		}
+		s.logger.Infof("Device %s reconnected as %s", deviceID, client.GetPhone())
+		return client, true, nil
+	case <-time.After(reconnectTimeout):
+		return nil, false, fmt.Errorf("%w after %s", ErrReconnectTimeout, reconnectTimeout)
+	}
+}
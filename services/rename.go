@@ -0,0 +1,124 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.mau.fi/whatsmeow/store/sqlstore"
+)
+
+var (
+	// ErrSessionExists is returned when a device ID is already in use
+	ErrSessionExists = errors.New("session already exists")
+	// ErrInvalidDeviceID is returned for device IDs that can't be used as a directory name
+	ErrInvalidDeviceID = errors.New("invalid device_id")
+)
+
+// validateDeviceID rejects IDs that would escape or collide inside SESSION_DIR
+func validateDeviceID(deviceID string) error {
+	if deviceID == "" || deviceID == "." || deviceID == ".." || strings.ContainsAny(deviceID, `/\`) {
+		return fmt.Errorf("%w: %q", ErrInvalidDeviceID, deviceID)
+	}
+	return nil
+}
+
+// RenameSession moves a session to a new device ID, keeping its credentials,
+// and reconnects it under the new ID. If the session can't be brought back
+// under the new ID, the directory is moved back and the old ID reloaded.
+func (s *WhatsAppService) RenameSession(deviceID, newDeviceID string) (*DeviceClient, error) {
+	if err := validateDeviceID(newDeviceID); err != nil {
+		return nil, err
+	}
+
+	oldDir := filepath.Join(os.Getenv("SESSION_DIR"), deviceID)
+	newDir := filepath.Join(os.Getenv("SESSION_DIR"), newDeviceID)
+
+	s.mu.Lock()
+	client, exists := s.clients[deviceID]
+	if !exists {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w for device_id: %s", ErrSessionNotFound, deviceID)
+	}
+	if _, taken := s.clients[newDeviceID]; taken {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w for device_id: %s", ErrSessionExists, newDeviceID)
+	}
+	if _, err := os.Stat(newDir); err == nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w for device_id: %s", ErrSessionExists, newDeviceID)
+	}
+
+	// Stop the old client and release its database before the files move.
+	// Sends still queued on it fail; the new client builds its own queue.
+	client.stopAutoReconnect()
+	client.Client.Disconnect()
+	client.markDisconnected()
+	if client.queue != nil {
+		client.queue.close()
+	}
+	client.closeStore()
+	delete(s.clients, deviceID)
+	s.mu.Unlock()
+
+	targetID := newDeviceID
+	var renameErr error
+	if err := os.Rename(oldDir, newDir); err != nil {
+		renameErr = fmt.Errorf("failed to move session directory: %v", err)
+		targetID = deviceID
+	}
+
+	successor, err := s.adoptSession(client, targetID)
+	if err != nil && renameErr == nil {
+		renameErr = fmt.Errorf("session moved but failed to reconnect: %v", err)
+		if moveErr := os.Rename(newDir, oldDir); moveErr != nil {
+			s.logger.Errorf("Failed to move session %s back after failed rename: %v", deviceID, moveErr)
+			return nil, renameErr
+		}
+		targetID = deviceID
+		successor, err = s.adoptSession(client, deviceID)
+	}
+	if err != nil {
+		s.logger.Errorf("Failed to reload device %s after rename: %v", targetID, err)
+	}
+	if renameErr != nil {
+		return nil, renameErr
+	}
+
+	s.tracker.rename(deviceID, newDeviceID)
+	s.logger.Infof("Renamed session %s to %s", deviceID, newDeviceID)
+	return successor, nil
+}
+
+// adoptSession opens the session stored under deviceID in place of old, which
+// was stopped, and points old's pending revoke timers at the new client
+func (s *WhatsAppService) adoptSession(old *DeviceClient, deviceID string) (*DeviceClient, error) {
+	if err := s.loadSession(deviceID); err != nil {
+		return nil, err
+	}
+	successor, err := s.GetSession(deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	successor.EventHandler = old.EventHandler
+	old.successor.Store(successor)
+	return successor, nil
+}
+
+// current returns the client now serving dc's session, following renames
+func (dc *DeviceClient) current() *DeviceClient {
+	for next := dc.successor.Load(); next != nil; next = dc.successor.Load() {
+		dc = next
+	}
+	return dc
+}
+
+// closeStore closes the session database so its files can be moved
+func (dc *DeviceClient) closeStore() {
+	if container, ok := dc.Client.Store.Container.(*sqlstore.Container); ok {
+		container.Close()
+	}
+}
@@ -0,0 +1,34 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateSessionRejectsEscapingDeviceIDs(t *testing.T) {
+	root := t.TempDir()
+	sessionDir := filepath.Join(root, "sessions")
+	t.Setenv("SESSION_DIR", sessionDir)
+	t.Setenv("AUTO_CREATE_SESSION", "true")
+
+	s := &WhatsAppService{clients: make(map[string]*DeviceClient), logger: AppLogger()}
+	for _, deviceID := range []string{"", ".", "..", "../escape", `..\escape`, "a/b"} {
+		if _, err := s.CreateSession(deviceID, SessionOptions{}); !errors.Is(err, ErrInvalidDeviceID) {
+			t.Errorf("CreateSession(%q) error = %v, want ErrInvalidDeviceID", deviceID, err)
+		}
+
+		lookupErr := ErrSessionNotFound
+		if err := s.autoCreateSession(deviceID, lookupErr); err != lookupErr {
+			t.Errorf("autoCreateSession(%q) error = %v, want the lookup error", deviceID, err)
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "escape")); !os.IsNotExist(err) {
+		t.Fatalf("a directory was created outside SESSION_DIR: %v", err)
+	}
+	if len(s.clients) != 0 {
+		t.Fatalf("sessions were registered: %v", s.clients)
+	}
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/store/sqlstore"
+)
+
+// unpaired reports whether the session has no credentials or WhatsApp logged
+// it out. A session that merely failed to connect keeps valid credentials.
+func (dc *DeviceClient) unpaired() bool {
+	return dc.Client.Store.ID == nil || dc.loggedOut.Load()
+}
+
+// RepairPairing starts a fresh pairing for a logged-out session in place,
+// keeping its directory, settings and webhook handler. Stale credentials are
+// cleared from the session store and a new device identity is created.
+func (s *WhatsAppService) RepairPairing(deviceID string, opts SessionOptions) (*DeviceClient, error) {
+	old, err := s.GetSession(deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !old.unpaired() {
+		return nil, fmt.Errorf("%w and not logged out: %s", ErrAlreadyPaired, deviceID)
+	}
+
+	container, ok := old.Client.Store.Container.(*sqlstore.Container)
+	if !ok {
+		return nil, fmt.Errorf("session store for %s does not support re-pairing", deviceID)
+	}
+
+	old.stopAutoReconnect()
+	old.Client.Disconnect()
+	// Sends still queued on the old client fail; the new client builds its own queue
+	if old.queue != nil {
+		old.queue.close()
+	}
+	if old.Client.Store.ID != nil {
+		if err := old.Client.Store.Delete(context.Background()); err != nil {
+			return nil, fmt.Errorf("failed to clear pairing state: %v", err)
+		}
+	}
+
+	// Reuse the log buffer so the session's history survives the re-pair
+	client := whatsmeow.NewClient(container.NewDevice(), newDeviceLogger(deviceID, s.logger, old.logs))
+	client.EnableAutoReconnect = false
+	deviceClient := &DeviceClient{
+		Client:       client,
+		DeviceID:     deviceID,
+		CreatedAt:    time.Now(),
+		EventHandler: old.EventHandler,
+		config:       old.GetConfig(),
+		logs:         old.logs,
+	}
+	client.AddEventHandler(deviceClient.eventHandler)
+
+	s.mu.Lock()
+	s.clients[deviceID] = deviceClient
+	s.mu.Unlock()
+
+	if !opts.PairWithCode {
+		go s.connectWithQR(deviceClient)
+	}
+
+	s.logger.Infof("Pairing reset for device: %s", deviceID)
+	return deviceClient, nil
+}
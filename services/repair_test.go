@@ -0,0 +1,32 @@
+package services
+
+import (
+	"testing"
+
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/store"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+func TestUnpairedRequiresLogout(t *testing.T) {
+	paired := types.NewJID("628111111111", types.DefaultUserServer)
+	newClient := func(id *types.JID) *DeviceClient {
+		return &DeviceClient{Client: whatsmeow.NewClient(&store.Device{ID: id}, nil)}
+	}
+
+	if !newClient(nil).unpaired() {
+		t.Error("session without credentials should be unpaired")
+	}
+
+	// Never connected or hit a stream error: IsLoggedIn is false but credentials are valid
+	offline := newClient(&paired)
+	if offline.unpaired() {
+		t.Error("session that only failed to connect was treated as unpaired")
+	}
+
+	offline.eventHandler(&events.LoggedOut{})
+	if !offline.unpaired() {
+		t.Error("session logged out by WhatsApp should be unpaired")
+	}
+}
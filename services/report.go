@@ -0,0 +1,186 @@
+package services
+
+import (
+	"net/url"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// processStart is when the server started, for uptime reporting
+var processStart = time.Now()
+
+// reportEnvPrefixes selects the environment variables that configure this server
+var reportEnvPrefixes = []string{
+	"API_", "HOST", "PORT", "SESSION_", "AUTO_CREATE_", "STORE_", "MESSAGE_", "PENDING_",
+	"KEEPALIVE_", "NUMBER_", "SEND_QUEUE_", "BULK_", "TEMP_", "MEDIA_", "DOWNLOAD_", "MAX_",
+	"FFMPEG_", "WEBHOOK_", "CONTACT_", "GROUP_", "LOG_", "RATE_LIMIT_", "RECONNECT_", "METRICS_",
+}
+
+// reportSecretMarkers flag variable names whose values must never leave the server
+var reportSecretMarkers = []string{"TOKEN", "SECRET", "PASSWORD", "KEY"}
+
+// DeviceReport is the support-report view of one session
+type DeviceReport struct {
+	DeviceID       string     `json:"device_id"`
+	Status         string     `json:"status"`
+	Phone          string     `json:"phone"`
+	ConnectedAt    *time.Time `json:"connected_at,omitempty"`
+	LastKeepAlive  *time.Time `json:"last_keepalive,omitempty"`
+	Connection     *string    `json:"connection"`
+	RecentErrors   int        `json:"recent_errors"`
+	RecentWarnings int        `json:"recent_warnings"`
+}
+
+// Report bundles server and session state for support tickets
+type Report struct {
+	GeneratedAt   time.Time            `json:"generated_at"`
+	StartedAt     time.Time            `json:"started_at"`
+	UptimeSeconds int64                `json:"uptime_seconds"`
+	GoVersion     string               `json:"go_version"`
+	Config        map[string]string    `json:"config"`
+	Features      map[string]bool      `json:"features"`
+	Devices       []DeviceReport       `json:"devices"`
+	Webhooks      []WebhookTargetStats `json:"webhooks"`
+	Store         *StoreStats          `json:"store,omitempty"`
+	NumberCache   NumberCacheStats     `json:"number_cache"`
+}
+
+// reportConfig returns the server's configuration with secrets redacted
+func reportConfig() map[string]string {
+	config := make(map[string]string)
+	for _, entry := range os.Environ() {
+		name, value, _ := strings.Cut(entry, "=")
+		if !hasAnyPrefix(name, reportEnvPrefixes) {
+			continue
+		}
+
+		switch {
+		case value == "":
+			config[name] = ""
+		case containsAny(name, reportSecretMarkers):
+			config[name] = "[redacted]"
+		case strings.HasSuffix(name, "_URL"):
+			config[name] = redactURL(value)
+		default:
+			config[name] = value
+		}
+	}
+	return config
+}
+
+// redactURL strips credentials and query parameters, which often carry secrets
+func redactURL(raw string) string {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return "[redacted]"
+	}
+	if parsed.User != nil {
+		parsed.User = url.User("redacted")
+	}
+	if parsed.RawQuery != "" {
+		parsed.RawQuery = "redacted"
+	}
+	return parsed.String()
+}
+
+// redactURLs applies redactURL to every URL inside a message
+func redactURLs(message string) string {
+	return urlPattern.ReplaceAllStringFunc(message, redactURL)
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(s string, markers []string) bool {
+	for _, marker := range markers {
+		if strings.Contains(s, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// countLevels counts buffered log lines at the error and warning levels
+func (b *logBuffer) countLevels() (errorCount, warnCount int) {
+	for _, line := range b.last(0) {
+		switch line.Level {
+		case "ERROR":
+			errorCount++
+		case "WARN":
+			warnCount++
+		}
+	}
+	return errorCount, warnCount
+}
+
+// deviceReport summarizes one session for the support report
+func (s *WhatsAppService) deviceReport(client *DeviceClient) DeviceReport {
+	report := DeviceReport{
+		DeviceID: client.DeviceID,
+		Status:   "disconnected",
+		Phone:    client.GetPhone(),
+	}
+
+	switch {
+	case client.IsConnected():
+		report.Status = "connected"
+		connectedAt := client.GetConnectedAt()
+		report.ConnectedAt = &connectedAt
+	case client.Client.Store.ID == nil:
+		report.Status = "waiting_for_qr_scan"
+	}
+
+	if lastKeepAlive := client.LastKeepAlive(); !lastKeepAlive.IsZero() {
+		report.LastKeepAlive = &lastKeepAlive
+	}
+	if state, err := s.GetPhoneState(client.DeviceID); err == nil {
+		report.Connection = state.Connection
+	}
+	report.RecentErrors, report.RecentWarnings = client.logs.countLevels()
+
+	return report
+}
+
+// GetReport gathers uptime, redacted configuration, per-device state and
+// delivery statistics into one document for support tickets
+func (s *WhatsAppService) GetReport() Report {
+	now := time.Now()
+	report := Report{
+		GeneratedAt:   now,
+		StartedAt:     processStart,
+		UptimeSeconds: int64(now.Sub(processStart).Seconds()),
+		GoVersion:     runtime.Version(),
+		Config:        reportConfig(),
+		Features:      Features(),
+		Devices:       make([]DeviceReport, 0),
+		NumberCache:   s.NumberCacheStats(),
+	}
+
+	for _, client := range s.GetAllSessions() {
+		report.Devices = append(report.Devices, s.deviceReport(client))
+	}
+	sort.Slice(report.Devices, func(i, j int) bool {
+		return report.Devices[i].DeviceID < report.Devices[j].DeviceID
+	})
+
+	report.Webhooks = GetWebhookService().Stats()
+	for i := range report.Webhooks {
+		report.Webhooks[i].URL = redactURL(report.Webhooks[i].URL)
+		report.Webhooks[i].LastError = redactURLs(report.Webhooks[i].LastError)
+	}
+
+	if stats, err := s.GetStoreStats(); err == nil {
+		report.Store = stats
+	}
+
+	return report
+}
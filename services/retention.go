@@ -0,0 +1,164 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultMessageRetentionDays keeps stored messages for this long unless MESSAGE_RETENTION_DAYS says otherwise
+const defaultMessageRetentionDays = 90
+
+// defaultPruneInterval is how often the pruner runs unless MESSAGE_PRUNE_INTERVAL_MINUTES says otherwise
+const defaultPruneInterval = time.Hour
+
+// pruneStats records what the pruner has removed since startup
+type pruneStats struct {
+	mu       sync.Mutex
+	total    int64
+	byDevice map[string]int64
+	lastRun  time.Time
+}
+
+// record adds one device's pruned row count
+func (p *pruneStats) record(deviceID string, pruned int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.byDevice == nil {
+		p.byDevice = make(map[string]int64)
+	}
+	p.total += pruned
+	p.byDevice[deviceID] += pruned
+}
+
+// finish marks the end of a pruning run
+func (p *pruneStats) finish(at time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastRun = at
+}
+
+// apply copies the pruning counters into store stats
+func (p *pruneStats) apply(stats *StoreStats) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats.PrunedTotal = p.total
+	if !p.lastRun.IsZero() {
+		lastRun := p.lastRun
+		stats.LastPruneAt = &lastRun
+	}
+	for i := range stats.Devices {
+		stats.Devices[i].Pruned = p.byDevice[stats.Devices[i].DeviceID]
+	}
+}
+
+// messageRetentionDays returns the default retention window; 0 keeps messages forever
+func messageRetentionDays() int {
+	raw := os.Getenv("MESSAGE_RETENTION_DAYS")
+	if raw == "" {
+		return defaultMessageRetentionDays
+	}
+	days, err := strconv.Atoi(raw)
+	if err != nil || days < 0 {
+		return defaultMessageRetentionDays
+	}
+	return days
+}
+
+// pruneInterval returns how often stored messages are pruned
+func pruneInterval() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("MESSAGE_PRUNE_INTERVAL_MINUTES"))
+	if err != nil || minutes <= 0 {
+		return defaultPruneInterval
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// retentionDaysFor returns a device's retention window, honouring its per-device override
+func (s *WhatsAppService) retentionDaysFor(deviceID string) int {
+	if client, err := s.GetSession(deviceID); err == nil {
+		if days := client.GetConfig().MessageRetentionDays; days != nil {
+			return *days
+		}
+	}
+	return messageRetentionDays()
+}
+
+// deviceIDs returns every device with stored messages
+func (m *messageStore) deviceIDs() ([]string, error) {
+	rows, err := m.db.Query("SELECT DISTINCT device_id FROM messages")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query message store: %v", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to read device ID: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// prune deletes a device's messages older than the cutoff and returns how many were removed
+func (m *messageStore) prune(deviceID string, cutoff time.Time) (int64, error) {
+	res, err := m.db.Exec("DELETE FROM messages WHERE device_id = ? AND timestamp < ?", deviceID, cutoff.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune messages: %v", err)
+	}
+	return res.RowsAffected()
+}
+
+// pruneMessages applies each device's retention window to the message store
+func (s *WhatsAppService) pruneMessages() {
+	deviceIDs, err := s.store.deviceIDs()
+	if err != nil {
+		s.logger.Errorf("Failed to prune message store: %v", err)
+		return
+	}
+
+	now := time.Now()
+	var total int64
+	for _, deviceID := range deviceIDs {
+		days := s.retentionDaysFor(deviceID)
+		if days == 0 {
+			continue
+		}
+
+		pruned, err := s.store.prune(deviceID, now.AddDate(0, 0, -days))
+		if err != nil {
+			s.logger.Errorf("Failed to prune messages of %s: %v", deviceID, err)
+			continue
+		}
+		if pruned > 0 {
+			s.store.pruned.record(deviceID, pruned)
+			s.logger.Infof("Pruned %d messages older than %d days from %s", pruned, days, deviceID)
+		}
+		total += pruned
+	}
+	s.store.pruned.finish(now)
+
+	if total > 0 {
+		s.logger.Infof("Message store pruning removed %d messages", total)
+	}
+}
+
+// runMessagePruner prunes the message store on startup and then periodically
+func (s *WhatsAppService) runMessagePruner() {
+	if s.store == nil {
+		return
+	}
+
+	s.pruneMessages()
+
+	ticker := time.NewTicker(pruneInterval())
+	defer ticker.Stop()
+	for range ticker.C {
+		s.pruneMessages()
+	}
+}
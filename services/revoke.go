@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go.mau.fi/whatsmeow/types"
+)
+
+var (
+	// ErrMessageNotFound is returned when the message to revoke isn't known
+	ErrMessageNotFound = errors.New("message not found")
+
+	// ErrRevokeNotPermitted is returned when revoking someone else's message without being a group admin
+	ErrRevokeNotPermitted = errors.New("not permitted to revoke this message")
+)
+
+// isOwnJID reports whether a JID belongs to the session's own account
+func isOwnJID(client *DeviceClient, jid types.JID) bool {
+	store := client.Client.Store
+	return (store.ID != nil && jid.User == store.ID.User) || (!store.LID.IsEmpty() && jid.User == store.LID.User)
+}
+
+// isGroupAdmin reports whether the session is an admin of a group
+func (s *WhatsAppService) isGroupAdmin(client *DeviceClient, group types.JID) (bool, error) {
+	info, err := s.fetchGroupInfo(client, group)
+	if err != nil {
+		return false, err
+	}
+	for _, participant := range info.Participants {
+		if participant.IsAdmin && (isOwnJID(client, participant.JID) || isOwnJID(client, participant.PhoneNumber) || isOwnJID(client, participant.LID)) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RevokeMessage deletes a message for everyone. sender is empty for the
+// session's own messages; revoking another member's message requires being
+// an admin of the group. When the message store knows the message, its
+// sender is used and unknown messages fail with ErrMessageNotFound.
+func (s *WhatsAppService) RevokeMessage(deviceID, phone, groupJID, messageID, sender string) (string, error) {
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return "", err
+	}
+
+	chat, err := resolveRecipient(phone, groupJID)
+	if err != nil {
+		return "", err
+	}
+
+	senderJID := types.EmptyJID
+	if sender != "" {
+		if senderJID, err = parseChatJID(sender); err != nil {
+			return "", fmt.Errorf("invalid sender: %v", err)
+		}
+	}
+
+	if _, tracked := s.tracker.get(deviceID, messageID); !tracked && s.store != nil {
+		stored, err := s.store.findByID(deviceID, messageID)
+		if err != nil {
+			return "", err
+		}
+		if stored == nil {
+			return "", fmt.Errorf("%w: %s", ErrMessageNotFound, messageID)
+		}
+		if !stored.FromMe && sender == "" {
+			if senderJID, err = types.ParseJID(stored.SenderJID); err != nil {
+				return "", fmt.Errorf("invalid stored sender: %v", err)
+			}
+		}
+	}
+
+	if !senderJID.IsEmpty() && isOwnJID(client, senderJID) {
+		senderJID = types.EmptyJID
+	}
+	if !senderJID.IsEmpty() {
+		if chat.Server != types.GroupServer {
+			return "", fmt.Errorf("%w: only your own messages can be revoked in personal chats", ErrRevokeNotPermitted)
+		}
+		admin, err := s.isGroupAdmin(client, chat)
+		if err != nil {
+			return "", err
+		}
+		if !admin {
+			return "", fmt.Errorf("%w: revoking other members' messages requires group admin", ErrRevokeNotPermitted)
+		}
+	}
+
+	resp, err := client.Client.SendMessage(context.Background(), chat, client.Client.BuildRevoke(chat, senderJID, messageID))
+	if err != nil {
+		return "", fmt.Errorf("failed to revoke message: %v", err)
+	}
+
+	s.logger.Infof("Revoked message %s on device %s", messageID, deviceID)
+	return resp.ID, nil
+}
+
+// findByID returns a stored message by ID alone, since personal chats may be
+// stored under either the phone number or the LID of the other party
+func (m *messageStore) findByID(deviceID, messageID string) (*StoredMessage, error) {
+	row := m.db.QueryRow(`SELECT device_id, message_id, chat_jid, sender_jid, from_me, timestamp, message_type, text
+		FROM messages WHERE device_id = ? AND message_id = ? LIMIT 1`, deviceID, messageID)
+
+	var msg StoredMessage
+	err := row.Scan(&msg.DeviceID, &msg.MessageID, &msg.ChatJID, &msg.SenderJID, &msg.FromMe, &msg.Timestamp, &msg.MessageType, &msg.Text)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query message store: %v", err)
+	}
+	return &msg, nil
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	// ErrMessageRejected is returned when WhatsApp refuses a message type for this account
+	ErrMessageRejected = errors.New("message type rejected by WhatsApp")
+	// ErrNotOnWhatsApp is returned when recipient verification finds no WhatsApp account
+	ErrNotOnWhatsApp = errors.New("phone number is not registered on WhatsApp")
+	// ErrInvalidCTAButton is returned when a call-to-action button can't be built
+	ErrInvalidCTAButton = errors.New("invalid CTA button")
+
+	// errSessionClosed is returned for queued sends when their session is deleted
+	errSessionClosed = errors.New("session was closed before the message could be sent")
+)
+
+// resolveRecipient builds the destination JID from either a phone number or a group JID
+func resolveRecipient(phone, groupJID string) (types.JID, error) {
+	if groupJID != "" {
+		jid, err := types.ParseJID(groupJID)
+		if err != nil {
+			return types.JID{}, fmt.Errorf("invalid group JID: %v", err)
+		}
+		return jid, nil
+	}
+
+	if phone == "" {
+		return types.JID{}, fmt.Errorf("phone or group_jid is required")
+	}
+	return types.NewJID(phone, types.DefaultUserServer), nil
+}
+
+// connectedSession returns a session that is ready to send messages
+func (s *WhatsAppService) connectedSession(deviceID string) (*DeviceClient, error) {
+	client, err := s.GetSession(deviceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.ensureConnection(client); err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
+
+// deliver sends a prepared message through the device's WhatsApp client.
+// Every send path goes through here so per-send behaviour lives in one place.
+func (s *WhatsAppService) deliver(client *DeviceClient, jid types.JID, msg *waProto.Message, opts SendOptions) (whatsmeow.SendResponse, error) {
+	s.applyDisappearing(client, jid, msg, opts)
+
+	// Tracking and storage keep seeing the media itself, not the view-once wrapper
+	outgoing := msg
+	if opts.ViewOnce {
+		outgoing = viewOnceMessage(proto.Clone(msg).(*waProto.Message))
+	}
+
+	send := func() (whatsmeow.SendResponse, error) {
+		return client.Client.SendMessage(context.Background(), jid, outgoing)
+	}
+
+	var resp whatsmeow.SendResponse
+	var err error
+	if sendQueueEnabled() {
+		resp, err = client.sendQueue().submit(opts.Priority, send)
+	} else {
+		resp, err = send()
+	}
+	if err != nil {
+		messagesFailed.WithLabelValues(client.DeviceID, messageKind(msg)).Inc()
+		return resp, err
+	}
+	messagesSent.WithLabelValues(client.DeviceID, messageKind(msg)).Inc()
+
+	// Track delivery state so receipts can update it later
+	sent := s.tracker.record(client.DeviceID, jid, resp.ID, msg, resp.Timestamp, opts.RequestID)
+	go notifyServerAck(sent)
+	s.storeMessage(storedFromSend(client, jid, resp.ID, msg, resp.Timestamp.Unix()))
+	if opts.ExpireAfter > 0 {
+		s.scheduleExpiry(client, jid, resp.ID, opts.ExpireAfter)
+	}
+	if opts.RevokeAfterRead > 0 {
+		s.tracker.revokeOnRead(client.DeviceID, resp.ID, opts.RevokeAfterRead)
+	}
+	return resp, nil
+}
+
+// VerifyRecipient resolves a phone number through WhatsApp and returns the
+// canonical JID to send to, which may differ from the number as given
+func (s *WhatsAppService) VerifyRecipient(deviceID, phone string) (types.JID, error) {
+	checks, err := s.CheckNumbers(deviceID, []string{phone}, false)
+	if err != nil {
+		return types.JID{}, err
+	}
+	if !checks[0].OnWhatsApp {
+		return types.JID{}, fmt.Errorf("%w: %s", ErrNotOnWhatsApp, phone)
+	}
+
+	jid, err := types.ParseJID(checks[0].JID)
+	if err != nil {
+		return types.JID{}, fmt.Errorf("failed to verify recipient: %v", err)
+	}
+	return jid, nil
+}
+
+// CTAButton describes the call-to-action button of an interactive message
+type CTAButton struct {
+	Label       string
+	URL         string
+	PhoneNumber string
+}
+
+// SendCTAMessage sends an interactive message with a URL or call button
+func (s *WhatsAppService) SendCTAMessage(deviceID, phone, groupJID, body string, button CTAButton, opts SendOptions) (string, int64, error) {
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	jid, err := resolveRecipient(phone, groupJID)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := s.ensureGroupMember(client, jid); err != nil {
+		return "", 0, err
+	}
+
+	nativeButton, err := buildCTAButton(button)
+	if err != nil {
+		return "", 0, err
+	}
+
+	resp, err := s.deliver(client, jid, interactiveMessage(client, body, nativeButton, opts), opts)
+	if errors.Is(err, whatsmeow.ErrServerReturnedError) {
+		return "", 0, fmt.Errorf("%w: failed to send CTA message (interactive messages are often limited to business accounts): %v", ErrMessageRejected, err)
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to send CTA message: %v", err)
+	}
+
+	return resp.ID, resp.Timestamp.Unix(), nil
+}
+
+// SendLocationRequest asks a contact to share their location
+func (s *WhatsAppService) SendLocationRequest(deviceID, phone, body string, opts SendOptions) (string, int64, error) {
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	jid, err := resolveRecipient(phone, "")
+	if err != nil {
+		return "", 0, err
+	}
+
+	// WhatsApp has no standalone location-request message; it is a native flow button
+	button := &waProto.InteractiveMessage_NativeFlowMessage_NativeFlowButton{
+		Name:             proto.String("send_location"),
+		ButtonParamsJSON: proto.String("{}"),
+	}
+
+	resp, err := s.deliver(client, jid, interactiveMessage(client, body, button, opts), opts)
+	if errors.Is(err, whatsmeow.ErrServerReturnedError) {
+		return "", 0, fmt.Errorf("%w: failed to send location request (not supported for this account or recipient): %v", ErrMessageRejected, err)
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to send location request: %v", err)
+	}
+
+	return resp.ID, resp.Timestamp.Unix(), nil
+}
+
+// Location is a point shared in a location message
+type Location struct {
+	Latitude  float64
+	Longitude float64
+	Name      string
+	Address   string
+}
+
+// SendLocationMessage shares GPS coordinates with a contact or group
+func (s *WhatsAppService) SendLocationMessage(deviceID, phone, groupJID string, location Location, opts SendOptions) (string, int64, error) {
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	jid, err := resolveRecipient(phone, groupJID)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := s.ensureGroupMember(client, jid); err != nil {
+		return "", 0, err
+	}
+
+	msg := &waProto.Message{
+		LocationMessage: &waProto.LocationMessage{
+			DegreesLatitude:  proto.Float64(location.Latitude),
+			DegreesLongitude: proto.Float64(location.Longitude),
+		},
+	}
+	if location.Name != "" {
+		msg.LocationMessage.Name = proto.String(location.Name)
+	}
+	if location.Address != "" {
+		msg.LocationMessage.Address = proto.String(location.Address)
+	}
+
+	resp, err := s.deliver(client, jid, msg, opts)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to send location: %v", err)
+	}
+
+	return resp.ID, resp.Timestamp.Unix(), nil
+}
+
+// interactiveMessage builds a view-once interactive message with a single native flow button
+func interactiveMessage(client *DeviceClient, body string, button *waProto.InteractiveMessage_NativeFlowMessage_NativeFlowButton, opts SendOptions) *waProto.Message {
+	interactive := &waProto.InteractiveMessage{
+		Body: &waProto.InteractiveMessage_Body{Text: proto.String(body)},
+		InteractiveMessage: &waProto.InteractiveMessage_NativeFlowMessage_{
+			NativeFlowMessage: &waProto.InteractiveMessage_NativeFlowMessage{
+				Buttons:        []*waProto.InteractiveMessage_NativeFlowMessage_NativeFlowButton{button},
+				MessageVersion: proto.Int32(1),
+			},
+		},
+	}
+
+	// The device footer goes into the interactive footer rather than the body
+	footer := client.GetConfig().Footer
+	if opts.Footer != nil {
+		footer = *opts.Footer
+	}
+	if footer != "" {
+		interactive.Footer = &waProto.InteractiveMessage_Footer{Text: proto.String(footer)}
+	}
+
+	// Interactive messages must be wrapped in a view-once container to render on phones
+	return &waProto.Message{
+		ViewOnceMessage: &waProto.FutureProofMessage{
+			Message: &waProto.Message{InteractiveMessage: interactive},
+		},
+	}
+}
+
+// buildCTAButton creates the native flow button for a URL or call CTA
+func buildCTAButton(button CTAButton) (*waProto.InteractiveMessage_NativeFlowMessage_NativeFlowButton, error) {
+	var name string
+	var params map[string]string
+
+	switch {
+	case button.URL != "":
+		parsed, err := url.Parse(button.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("%w: button URL must be a valid http(s) URL", ErrInvalidCTAButton)
+		}
+		name = "cta_url"
+		params = map[string]string{
+			"display_text": button.Label,
+			"url":          button.URL,
+			"merchant_url": button.URL,
+		}
+	case button.PhoneNumber != "":
+		name = "cta_call"
+		params = map[string]string{
+			"display_text": button.Label,
+			"phone_number": button.PhoneNumber,
+		}
+	default:
+		return nil, fmt.Errorf("%w: button URL or phone number is required", ErrInvalidCTAButton)
+	}
+
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode button params: %v", err)
+	}
+
+	return &waProto.InteractiveMessage_NativeFlowMessage_NativeFlowButton{
+		Name:             proto.String(name),
+		ButtonParamsJSON: proto.String(string(paramsJSON)),
+	}, nil
+}
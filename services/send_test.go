@@ -0,0 +1,26 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBuildCTAButtonRejectsInvalidButtons(t *testing.T) {
+	for _, button := range []CTAButton{
+		{Label: "Open", URL: "ftp://example.com/file"},
+		{Label: "Open", URL: "https://"},
+		{Label: "Open", URL: "javascript:alert(1)"},
+		{Label: "Open"},
+	} {
+		if _, err := buildCTAButton(button); !errors.Is(err, ErrInvalidCTAButton) {
+			t.Errorf("buildCTAButton(%+v) error = %v, want ErrInvalidCTAButton", button, err)
+		}
+	}
+
+	if _, err := buildCTAButton(CTAButton{Label: "Track", URL: "https://example.com/track/1"}); err != nil {
+		t.Fatalf("valid URL button: %v", err)
+	}
+	if _, err := buildCTAButton(CTAButton{Label: "Call", PhoneNumber: "628123456789"}); err != nil {
+		t.Fatalf("valid call button: %v", err)
+	}
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrInvalidSimulation is returned when a simulated message can't be built from the request
+var ErrInvalidSimulation = errors.New("invalid simulated message")
+
+// SimulatedMessageTypes are the message types SimulateIncoming can produce
+var SimulatedMessageTypes = []string{"text", "image", "video", "audio", "document"}
+
+// simulatedMessage builds the content of a synthetic message; media types
+// only carry a caption, since there is no attachment to download
+func simulatedMessage(msgType, body string) (*waProto.Message, error) {
+	switch msgType {
+	case "", "text":
+		return &waProto.Message{Conversation: proto.String(body)}, nil
+	case "image":
+		return &waProto.Message{ImageMessage: &waProto.ImageMessage{Caption: proto.String(body), Mimetype: proto.String("image/jpeg")}}, nil
+	case "video":
+		return &waProto.Message{VideoMessage: &waProto.VideoMessage{Caption: proto.String(body), Mimetype: proto.String("video/mp4")}}, nil
+	case "audio":
+		return &waProto.Message{AudioMessage: &waProto.AudioMessage{Mimetype: proto.String("audio/ogg; codecs=opus")}}, nil
+	case "document":
+		return &waProto.Message{DocumentMessage: &waProto.DocumentMessage{Caption: proto.String(body), Mimetype: proto.String("application/pdf"), FileName: proto.String("simulated.pdf")}}, nil
+	default:
+		return nil, fmt.Errorf("%w: type must be one of %s", ErrInvalidSimulation, strings.Join(SimulatedMessageTypes, ", "))
+	}
+}
+
+// SimulateIncoming runs a synthetic incoming message from a phone number
+// through the webhook pipeline, so webhook consumers can be tested without a
+// real sender. It returns the generated message ID.
+func (s *WhatsAppService) SimulateIncoming(deviceID, msgType, from, body string) (string, error) {
+	if _, err := s.GetSession(deviceID); err != nil {
+		return "", err
+	}
+
+	phone := normalizePhone(from)
+	if len(phone) < 10 || strings.Trim(phone, "0123456789") != "" {
+		return "", fmt.Errorf("%w: from must be country_code + number", ErrInvalidSimulation)
+	}
+
+	content, err := simulatedMessage(msgType, body)
+	if err != nil {
+		return "", err
+	}
+
+	sender := types.NewJID(phone, types.DefaultUserServer)
+	evt := &events.Message{
+		Info: types.MessageInfo{
+			MessageSource: types.MessageSource{
+				Chat:      sender,
+				Sender:    sender,
+				SenderAlt: sender,
+			},
+			ID:        fmt.Sprintf("SIMULATED-%d", time.Now().UnixNano()),
+			PushName:  "Simulated Sender",
+			Timestamp: time.Now(),
+		},
+		Message: content,
+	}
+
+	s.logger.Infof("Simulating incoming %s message for device %s from %s", msgType, deviceID, phone)
+	GetWebhookService().HandleIncomingMessage(deviceID, evt)
+
+	return evt.Info.ID, nil
+}
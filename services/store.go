@@ -0,0 +1,327 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// ErrStoreDisabled is returned by features that need STORE_MESSAGES=true
+var ErrStoreDisabled = errors.New("message store is disabled (set STORE_MESSAGES=true)")
+
+// StoredMessage is a message persisted in the message store
+type StoredMessage struct {
+	DeviceID    string `json:"device_id"`
+	MessageID   string `json:"message_id"`
+	ChatJID     string `json:"chat_jid"`
+	SenderJID   string `json:"sender_jid"`
+	FromMe      bool   `json:"from_me"`
+	Timestamp   int64  `json:"timestamp"`
+	MessageType string `json:"message_type"`
+	Text        string `json:"text"`
+}
+
+// messageStore persists messages of every device in a single SQLite database
+type messageStore struct {
+	db     *sql.DB
+	path   string
+	pruned pruneStats
+}
+
+const messageStoreSchema = `
+CREATE TABLE IF NOT EXISTS messages (
+	device_id    TEXT NOT NULL,
+	message_id   TEXT NOT NULL,
+	chat_jid     TEXT NOT NULL,
+	sender_jid   TEXT NOT NULL,
+	from_me      INTEGER NOT NULL,
+	timestamp    INTEGER NOT NULL,
+	message_type TEXT NOT NULL,
+	text         TEXT NOT NULL,
+	PRIMARY KEY (device_id, chat_jid, message_id)
+);
+CREATE INDEX IF NOT EXISTS idx_messages_chat_time ON messages (device_id, chat_jid, timestamp);
+CREATE INDEX IF NOT EXISTS idx_messages_device_time ON messages (device_id, timestamp);
+`
+
+// storeMessagesEnabled reports whether STORE_MESSAGES is set
+func storeMessagesEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("STORE_MESSAGES"))
+	return enabled
+}
+
+// openMessageStore opens (and creates) SESSION_DIR/messages.db
+func openMessageStore() (*messageStore, error) {
+	sessionDir := os.Getenv("SESSION_DIR")
+	if sessionDir == "" {
+		sessionDir = "./sessions"
+	}
+	if err := os.MkdirAll(sessionDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create session directory: %v", err)
+	}
+
+	dbPath := filepath.Join(sessionDir, "messages.db")
+	db, err := sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)", dbPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open message store: %v", err)
+	}
+
+	if _, err := db.Exec(messageStoreSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize message store: %v", err)
+	}
+
+	return &messageStore{db: db, path: dbPath}, nil
+}
+
+// save inserts messages, ignoring ones already stored, and returns how many were new
+func (m *messageStore) save(messages ...StoredMessage) (int, error) {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT OR IGNORE INTO messages
+		(device_id, message_id, chat_jid, sender_jid, from_me, timestamp, message_type, text)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prepare insert: %v", err)
+	}
+	defer stmt.Close()
+
+	inserted := 0
+	for _, msg := range messages {
+		res, err := stmt.Exec(msg.DeviceID, msg.MessageID, msg.ChatJID, msg.SenderJID, msg.FromMe, msg.Timestamp, msg.MessageType, msg.Text)
+		if err != nil {
+			return 0, fmt.Errorf("failed to store message: %v", err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			inserted++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit messages: %v", err)
+	}
+	return inserted, nil
+}
+
+// oldest returns the oldest stored message of a chat, or nil when there is none
+func (m *messageStore) oldest(deviceID, chatJID string) (*StoredMessage, error) {
+	row := m.db.QueryRow(`SELECT device_id, message_id, chat_jid, sender_jid, from_me, timestamp, message_type, text
+		FROM messages WHERE device_id = ? AND chat_jid = ? ORDER BY timestamp ASC LIMIT 1`, deviceID, chatJID)
+
+	var msg StoredMessage
+	err := row.Scan(&msg.DeviceID, &msg.MessageID, &msg.ChatJID, &msg.SenderJID, &msg.FromMe, &msg.Timestamp, &msg.MessageType, &msg.Text)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query message store: %v", err)
+	}
+	return &msg, nil
+}
+
+// MessageQuery filters stored messages of a device
+type MessageQuery struct {
+	// ChatJID limits results to one chat when set
+	ChatJID string
+	// Since and Until bound the message timestamp (inclusive) when non-zero
+	Since time.Time
+	Until time.Time
+	Limit int
+}
+
+// query returns a device's messages matching q, newest first
+func (m *messageStore) query(deviceID string, q MessageQuery) ([]StoredMessage, error) {
+	sqlQuery := `SELECT device_id, message_id, chat_jid, sender_jid, from_me, timestamp, message_type, text
+		FROM messages WHERE device_id = ?`
+	args := []interface{}{deviceID}
+	if q.ChatJID != "" {
+		sqlQuery += " AND chat_jid = ?"
+		args = append(args, q.ChatJID)
+	}
+	if !q.Since.IsZero() {
+		sqlQuery += " AND timestamp >= ?"
+		args = append(args, q.Since.Unix())
+	}
+	if !q.Until.IsZero() {
+		sqlQuery += " AND timestamp <= ?"
+		args = append(args, q.Until.Unix())
+	}
+	sqlQuery += " ORDER BY timestamp DESC LIMIT ?"
+	args = append(args, q.Limit)
+
+	rows, err := m.db.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query message store: %v", err)
+	}
+	defer rows.Close()
+
+	messages := make([]StoredMessage, 0)
+	for rows.Next() {
+		var msg StoredMessage
+		if err := rows.Scan(&msg.DeviceID, &msg.MessageID, &msg.ChatJID, &msg.SenderJID, &msg.FromMe, &msg.Timestamp, &msg.MessageType, &msg.Text); err != nil {
+			return nil, fmt.Errorf("failed to read stored message: %v", err)
+		}
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read stored messages: %v", err)
+	}
+	return messages, nil
+}
+
+// QueryMessages returns a device's stored messages, newest first. The chat
+// filter accepts a full JID or a bare phone number.
+func (s *WhatsAppService) QueryMessages(deviceID string, q MessageQuery) ([]StoredMessage, error) {
+	if s.store == nil {
+		return nil, ErrStoreDisabled
+	}
+	if q.ChatJID != "" {
+		chat, err := parseChatJID(q.ChatJID)
+		if err != nil {
+			return nil, err
+		}
+		q.ChatJID = chat.String()
+	}
+	return s.store.query(deviceID, q)
+}
+
+// DeviceStoreStats summarizes one device's stored messages
+type DeviceStoreStats struct {
+	DeviceID string `json:"device_id"`
+	Messages int64  `json:"messages"`
+	Oldest   int64  `json:"oldest"`
+	Newest   int64  `json:"newest"`
+	Pruned   int64  `json:"pruned"`
+}
+
+// StoreStats summarizes the message store
+type StoreStats struct {
+	Devices       []DeviceStoreStats `json:"devices"`
+	TotalMessages int64              `json:"total_messages"`
+	SizeBytes     int64              `json:"size_bytes"`
+	RetentionDays int                `json:"retention_days"`
+	PrunedTotal   int64              `json:"pruned_total"`
+	LastPruneAt   *time.Time         `json:"last_prune_at,omitempty"`
+}
+
+// stats aggregates message counts per device; the grouping walks the device/time index
+func (m *messageStore) stats() (*StoreStats, error) {
+	rows, err := m.db.Query(`SELECT device_id, COUNT(*), MIN(timestamp), MAX(timestamp)
+		FROM messages GROUP BY device_id ORDER BY device_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query message store: %v", err)
+	}
+	defer rows.Close()
+
+	stats := &StoreStats{Devices: make([]DeviceStoreStats, 0)}
+	for rows.Next() {
+		var device DeviceStoreStats
+		if err := rows.Scan(&device.DeviceID, &device.Messages, &device.Oldest, &device.Newest); err != nil {
+			return nil, fmt.Errorf("failed to read message store stats: %v", err)
+		}
+		stats.Devices = append(stats.Devices, device)
+		stats.TotalMessages += device.Messages
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read message store stats: %v", err)
+	}
+
+	// Database pages plus the write-ahead log not yet checkpointed
+	var pageCount, pageSize int64
+	if err := m.db.QueryRow("PRAGMA page_count").Scan(&pageCount); err != nil {
+		return nil, fmt.Errorf("failed to read message store size: %v", err)
+	}
+	if err := m.db.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+		return nil, fmt.Errorf("failed to read message store size: %v", err)
+	}
+	stats.SizeBytes = pageCount * pageSize
+	if info, err := os.Stat(m.path + "-wal"); err == nil {
+		stats.SizeBytes += info.Size()
+	}
+
+	stats.RetentionDays = messageRetentionDays()
+	m.pruned.apply(stats)
+	return stats, nil
+}
+
+// GetStoreStats returns per-device message counts and the size of the message store
+func (s *WhatsAppService) GetStoreStats() (*StoreStats, error) {
+	if s.store == nil {
+		return nil, ErrStoreDisabled
+	}
+	return s.store.stats()
+}
+
+// storedFromEvent converts a whatsmeow message event to a stored message
+func storedFromEvent(deviceID string, evt *events.Message) StoredMessage {
+	return StoredMessage{
+		DeviceID:    deviceID,
+		MessageID:   evt.Info.ID,
+		ChatJID:     evt.Info.Chat.String(),
+		SenderJID:   evt.Info.Sender.ToNonAD().String(),
+		FromMe:      evt.Info.IsFromMe,
+		Timestamp:   evt.Info.Timestamp.Unix(),
+		MessageType: messageKind(evt.Message),
+		Text:        messageText(evt.Message),
+	}
+}
+
+// storedFromSend builds the stored form of a message this device just sent
+func storedFromSend(client *DeviceClient, chat types.JID, messageID string, msg *waProto.Message, timestamp int64) StoredMessage {
+	sender := ""
+	if client.Client.Store.ID != nil {
+		sender = client.Client.Store.ID.ToNonAD().String()
+	}
+
+	return StoredMessage{
+		DeviceID:    client.DeviceID,
+		MessageID:   messageID,
+		ChatJID:     chat.String(),
+		SenderJID:   sender,
+		FromMe:      true,
+		Timestamp:   timestamp,
+		MessageType: messageKind(msg),
+		Text:        messageText(msg),
+	}
+}
+
+// messageText returns the text or caption of a message
+func messageText(msg *waProto.Message) string {
+	switch {
+	case msg.GetConversation() != "":
+		return msg.GetConversation()
+	case msg.GetExtendedTextMessage() != nil:
+		return msg.GetExtendedTextMessage().GetText()
+	case msg.GetImageMessage() != nil:
+		return msg.GetImageMessage().GetCaption()
+	case msg.GetVideoMessage() != nil:
+		return msg.GetVideoMessage().GetCaption()
+	case msg.GetDocumentMessage() != nil:
+		return msg.GetDocumentMessage().GetCaption()
+	default:
+		return ""
+	}
+}
+
+// storeMessage buffers a single message for chat history and persists it when the store is enabled
+func (s *WhatsAppService) storeMessage(msg StoredMessage) {
+	s.chatBuffer.add(msg)
+	if s.store == nil {
+		return
+	}
+	if _, err := s.store.save(msg); err != nil {
+		s.logger.Errorf("Failed to store message %s for device %s: %v", msg.MessageID, msg.DeviceID, err)
+	}
+}
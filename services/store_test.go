@@ -0,0 +1,55 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func BenchmarkMessageStore(b *testing.B) {
+	b.Setenv("SESSION_DIR", b.TempDir())
+	store, err := openMessageStore()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer store.db.Close()
+
+	const chat = "6281234567890@s.whatsapp.net"
+	batch := func(offset int) []StoredMessage {
+		messages := make([]StoredMessage, 100)
+		for i := range messages {
+			messages[i] = StoredMessage{
+				DeviceID:    "bench",
+				MessageID:   fmt.Sprintf("MSG%08d", offset+i),
+				ChatJID:     chat,
+				SenderJID:   chat,
+				Timestamp:   time.Now().Unix() + int64(offset+i),
+				MessageType: "text",
+				Text:        "hello from the benchmark",
+			}
+		}
+		return messages
+	}
+
+	b.Run("save", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := store.save(batch(i * 100)...); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("query", func(b *testing.B) {
+		if _, err := store.save(batch(-1000)...); err != nil {
+			b.Fatal(err)
+		}
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, err := store.query("bench", MessageQuery{ChatJID: chat, Limit: 50}); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// Live stream events sent to WebSocket subscribers
+const (
+	StreamEventQR         = "qr"
+	StreamEventConnection = "connection"
+	StreamEventMessage    = "message"
+)
+
+// streamBufferSize is how many events a subscriber may fall behind before events are dropped for it
+const streamBufferSize = 64
+
+// StreamEvent is one JSON frame of a device's live event stream
+type StreamEvent struct {
+	Event     string      `json:"event"`
+	DeviceID  string      `json:"device_id"`
+	Timestamp int64       `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// QR codes rotate like whatsmeow's QR channel: the first is valid longer than the rest
+const (
+	firstQRCodeTimeout = 60 * time.Second
+	nextQRCodeTimeout  = 20 * time.Second
+)
+
+// QRCodeEvent is the data of a qr stream event
+type QRCodeEvent struct {
+	// QRCode is the code to show now
+	QRCode string `json:"qr_code"`
+	// Codes lists every code of the pairing attempt, to be shown in turn
+	Codes     []string `json:"codes"`
+	ExpiresIn int      `json:"expires_in"`
+}
+
+func newQRCodeEvent(codes []string) QRCodeEvent {
+	return QRCodeEvent{QRCode: codes[0], Codes: codes, ExpiresIn: int(firstQRCodeTimeout.Seconds())}
+}
+
+// QRCodeTimeout returns how long the code at index stays valid
+func QRCodeTimeout(index int) time.Duration {
+	if index == 0 {
+		return firstQRCodeTimeout
+	}
+	return nextQRCodeTimeout
+}
+
+// ConnectionEvent is the data of a connection stream event
+type ConnectionEvent struct {
+	Status string `json:"status"`
+	Phone  string `json:"phone,omitempty"`
+}
+
+// eventHub fans a device's live events out to every subscriber of that device
+type eventHub struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[chan StreamEvent]struct{}
+}
+
+func newEventHub() *eventHub {
+	return &eventHub{subscribers: make(map[string]map[chan StreamEvent]struct{})}
+}
+
+// subscribe registers a subscriber for a device's events
+func (h *eventHub) subscribe(deviceID string) chan StreamEvent {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan StreamEvent, streamBufferSize)
+	if h.subscribers[deviceID] == nil {
+		h.subscribers[deviceID] = make(map[chan StreamEvent]struct{})
+	}
+	h.subscribers[deviceID][ch] = struct{}{}
+	return ch
+}
+
+// unsubscribe removes a subscriber and closes its channel
+func (h *eventHub) unsubscribe(deviceID string, ch chan StreamEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[deviceID][ch]; !ok {
+		return
+	}
+	delete(h.subscribers[deviceID], ch)
+	if len(h.subscribers[deviceID]) == 0 {
+		delete(h.subscribers, deviceID)
+	}
+	close(ch)
+}
+
+// publish sends an event to a device's subscribers; a subscriber whose buffer
+// is full misses the event rather than blocking the WhatsApp event handler
+func (h *eventHub) publish(deviceID, event string, data interface{}) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if len(h.subscribers[deviceID]) == 0 {
+		return
+	}
+
+	frame := StreamEvent{Event: event, DeviceID: deviceID, Timestamp: time.Now().Unix(), Data: data}
+	for ch := range h.subscribers[deviceID] {
+		select {
+		case ch <- frame:
+		default:
+		}
+	}
+}
+
+// SubscribeEvents streams a device's QR codes, connection changes and incoming
+// messages. The returned function unsubscribes and must be called when the
+// client goes away.
+func (s *WhatsAppService) SubscribeEvents(deviceID string) (<-chan StreamEvent, func(), error) {
+	if _, err := s.GetSession(deviceID); err != nil {
+		return nil, nil, err
+	}
+
+	ch := s.stream.subscribe(deviceID)
+	return ch, func() { s.stream.unsubscribe(deviceID, ch) }, nil
+}
+
+// publishConnection streams a device's connection state change
+func (s *WhatsAppService) publishConnection(dc *DeviceClient, status string) {
+	s.stream.publish(dc.DeviceID, StreamEventConnection, ConnectionEvent{Status: status, Phone: dc.GetPhone()})
+}
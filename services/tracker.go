@@ -0,0 +1,268 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// sentMessageTTL is how long delivery state is kept for a sent message
+const sentMessageTTL = 24 * time.Hour
+
+// Delivery states of a sent message, in lifecycle order
+const (
+	StatusSent      = "sent"
+	StatusDelivered = "delivered"
+	StatusRead      = "read"
+	StatusPlayed    = "played"
+	// StatusExpired marks a message revoked because it wasn't delivered in time
+	StatusExpired = "expired"
+)
+
+var statusRank = map[string]int{
+	StatusSent:      0,
+	StatusDelivered: 1,
+	StatusRead:      2,
+	StatusPlayed:    3,
+	StatusExpired:   4,
+}
+
+// SentMessage is the tracked delivery state of an outgoing message
+type SentMessage struct {
+	MessageID   string `json:"message_id"`
+	DeviceID    string `json:"device_id"`
+	Chat        string `json:"chat"`
+	MessageType string `json:"message_type"`
+	Status      string `json:"status"`
+	SentAt      int64  `json:"sent_at"`
+	DeliveredAt int64  `json:"delivered_at,omitempty"`
+	ReadAt      int64  `json:"read_at,omitempty"`
+	PlayedAt    int64  `json:"played_at,omitempty"`
+	// OriginRequestID is the API request that sent the message
+	OriginRequestID string `json:"origin_request_id,omitempty"`
+}
+
+// messageTracker keeps the delivery state of sent messages keyed by device and message ID
+type messageTracker struct {
+	mu       sync.Mutex
+	messages map[string]*SentMessage
+	// readRevokes holds the revoke delay of messages to revoke once read
+	readRevokes map[string]time.Duration
+	// byAge lists the entries in the order they were recorded, so pruning
+	// stops at the first entry that is still fresh
+	byAge []*SentMessage
+}
+
+func newMessageTracker() *messageTracker {
+	return &messageTracker{
+		messages:    make(map[string]*SentMessage),
+		readRevokes: make(map[string]time.Duration),
+	}
+}
+
+func trackerKey(deviceID, messageID string) string {
+	return deviceID + "|" + messageID
+}
+
+// record starts tracking a message that was just accepted by the server
+func (t *messageTracker) record(deviceID string, chat types.JID, messageID string, msg *waProto.Message, sentAt time.Time, requestID string) SentMessage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.prune()
+	entry := &SentMessage{
+		MessageID:   messageID,
+		DeviceID:    deviceID,
+		Chat:        chat.String(),
+		MessageType: messageKind(msg),
+		Status:      StatusSent,
+		SentAt:      sentAt.Unix(),
+
+		OriginRequestID: requestID,
+	}
+	t.messages[trackerKey(deviceID, messageID)] = entry
+	t.byAge = append(t.byAge, entry)
+	return *entry
+}
+
+// applyReceipt advances the status of every tracked message in a receipt and
+// returns the updated entries. Statuses never move backwards.
+func (t *messageTracker) applyReceipt(deviceID string, receipt *events.Receipt) []SentMessage {
+	status := receiptStatus(receipt.Type)
+	if status == "" {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	updated := make([]SentMessage, 0, len(receipt.MessageIDs))
+	for _, id := range receipt.MessageIDs {
+		entry, ok := t.messages[trackerKey(deviceID, id)]
+		if !ok || statusRank[status] <= statusRank[entry.Status] {
+			continue
+		}
+
+		entry.Status = status
+		ts := receipt.Timestamp.Unix()
+		switch status {
+		case StatusDelivered:
+			entry.DeliveredAt = ts
+		case StatusRead:
+			entry.ReadAt = ts
+		case StatusPlayed:
+			entry.PlayedAt = ts
+		}
+		updated = append(updated, *entry)
+	}
+
+	return updated
+}
+
+// expire marks a message expired if it is still undelivered, returning the updated entry
+func (t *messageTracker) expire(deviceID, messageID string) (SentMessage, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.messages[trackerKey(deviceID, messageID)]
+	if !ok || entry.Status != StatusSent {
+		return SentMessage{}, false
+	}
+
+	entry.Status = StatusExpired
+	return *entry, true
+}
+
+// revokeOnRead arranges for a tracked message to be revoked delay after it is read
+func (t *messageTracker) revokeOnRead(deviceID, messageID string, delay time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.readRevokes[trackerKey(deviceID, messageID)] = delay
+}
+
+// takeReadRevoke returns and clears the pending revoke delay of a message
+func (t *messageTracker) takeReadRevoke(deviceID, messageID string) (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := trackerKey(deviceID, messageID)
+	delay, ok := t.readRevokes[key]
+	delete(t.readRevokes, key)
+	return delay, ok
+}
+
+// rename moves the tracked messages of a device to its new ID
+func (t *messageTracker) rename(deviceID, newDeviceID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	prefix := trackerKey(deviceID, "")
+	for key, entry := range t.messages {
+		if entry.DeviceID != deviceID {
+			continue
+		}
+		newKey := trackerKey(newDeviceID, entry.MessageID)
+		entry.DeviceID = newDeviceID
+		t.messages[newKey] = entry
+		delete(t.messages, key)
+	}
+	for key, delay := range t.readRevokes {
+		if messageID, ok := strings.CutPrefix(key, prefix); ok {
+			t.readRevokes[trackerKey(newDeviceID, messageID)] = delay
+			delete(t.readRevokes, key)
+		}
+	}
+}
+
+// get returns a copy of a tracked message
+func (t *messageTracker) get(deviceID, messageID string) (SentMessage, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, ok := t.messages[trackerKey(deviceID, messageID)]
+	if !ok {
+		return SentMessage{}, false
+	}
+	return *entry, true
+}
+
+// GetMessageStatus returns the latest delivery state of a message sent by the device
+// within the last sentMessageTTL
+func (s *WhatsAppService) GetMessageStatus(deviceID, messageID string) (SentMessage, error) {
+	if _, err := s.GetSession(deviceID); err != nil {
+		return SentMessage{}, err
+	}
+
+	sent, ok := s.tracker.get(deviceID, messageID)
+	if !ok || time.Since(time.Unix(sent.SentAt, 0)) > sentMessageTTL {
+		return SentMessage{}, fmt.Errorf("%w: %s", ErrMessageNotFound, messageID)
+	}
+	return sent, nil
+}
+
+// prune drops entries older than sentMessageTTL; the caller holds the lock.
+// Entries are recorded in send order, so only the expired ones are visited.
+func (t *messageTracker) prune() {
+	cutoff := time.Now().Add(-sentMessageTTL).Unix()
+	expired := 0
+	for _, entry := range t.byAge {
+		if entry.SentAt >= cutoff {
+			break
+		}
+		// A message ID recorded again since belongs to the newer entry
+		key := trackerKey(entry.DeviceID, entry.MessageID)
+		if t.messages[key] == entry {
+			delete(t.messages, key)
+			delete(t.readRevokes, key)
+		}
+		expired++
+	}
+	clear(t.byAge[:expired])
+	t.byAge = t.byAge[expired:]
+}
+
+// receiptStatus maps a receipt type to a delivery status, or "" when irrelevant
+func receiptStatus(receiptType types.ReceiptType) string {
+	switch receiptType {
+	case types.ReceiptTypeDelivered:
+		return StatusDelivered
+	case types.ReceiptTypeRead:
+		return StatusRead
+	case types.ReceiptTypePlayed:
+		return StatusPlayed
+	default:
+		return ""
+	}
+}
+
+// messageKind returns the message type name used in responses and webhooks
+func messageKind(msg *waProto.Message) string {
+	switch {
+	case msg.GetImageMessage() != nil:
+		return "image"
+	case msg.GetVideoMessage() != nil:
+		return "video"
+	case msg.GetAudioMessage() != nil:
+		return "audio"
+	case msg.GetDocumentMessage() != nil:
+		return "document"
+	case msg.GetStickerMessage() != nil:
+		return "sticker"
+	case msg.GetLocationMessage() != nil, msg.GetLiveLocationMessage() != nil:
+		return "location"
+	case msg.GetContactMessage() != nil, msg.GetContactsArrayMessage() != nil:
+		return "contact"
+	case pollCreationOf(msg) != nil:
+		return "poll"
+	case msg.GetViewOnceMessage() != nil:
+		return "interactive"
+	default:
+		return "text"
+	}
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestTrackerMediaSendReceipts(t *testing.T) {
+	tracker := newMessageTracker()
+	chat := types.NewJID("628123456789", types.DefaultUserServer)
+	image := &waProto.Message{ImageMessage: &waProto.ImageMessage{Caption: proto.String("invoice")}}
+
+	sentAt := time.Now()
+	sent := tracker.record("dev", chat, "3EB0IMG", image, sentAt, "req-1")
+	if sent.MessageType != "image" || sent.Status != StatusSent || sent.OriginRequestID != "req-1" {
+		t.Fatalf("recorded %+v, want a sent image from req-1", sent)
+	}
+
+	receipt := func(receiptType types.ReceiptType, at time.Time) *events.Receipt {
+		return &events.Receipt{MessageIDs: []types.MessageID{"3EB0IMG"}, Timestamp: at, Type: receiptType}
+	}
+
+	deliveredAt := sentAt.Add(time.Second)
+	if updated := tracker.applyReceipt("dev", receipt(types.ReceiptTypeDelivered, deliveredAt)); len(updated) != 1 || updated[0].Status != StatusDelivered {
+		t.Fatalf("delivered receipt updated %+v", updated)
+	}
+	readAt := sentAt.Add(2 * time.Second)
+	if updated := tracker.applyReceipt("dev", receipt(types.ReceiptTypeRead, readAt)); len(updated) != 1 || updated[0].Status != StatusRead {
+		t.Fatalf("read receipt updated %+v", updated)
+	}
+	// A late delivered receipt never moves the status back
+	if updated := tracker.applyReceipt("dev", receipt(types.ReceiptTypeDelivered, readAt.Add(time.Second))); len(updated) != 0 {
+		t.Fatalf("late delivered receipt updated %+v", updated)
+	}
+	// Receipts of another device don't touch this one's messages
+	if updated := tracker.applyReceipt("other", receipt(types.ReceiptTypePlayed, readAt)); len(updated) != 0 {
+		t.Fatalf("other device's receipt updated %+v", updated)
+	}
+
+	got, ok := tracker.get("dev", "3EB0IMG")
+	if !ok {
+		t.Fatal("message not tracked")
+	}
+	if got.Status != StatusRead || got.DeliveredAt != deliveredAt.Unix() || got.ReadAt != readAt.Unix() || got.MessageType != "image" {
+		t.Fatalf("tracked %+v, want a read image with both timestamps", got)
+	}
+}
+
+func TestTrackerPrunesOnlyExpiredEntries(t *testing.T) {
+	tracker := newMessageTracker()
+	chat := types.NewJID("628123456789", types.DefaultUserServer)
+	text := &waProto.Message{Conversation: proto.String("hi")}
+
+	tracker.record("dev", chat, "old", text, time.Now().Add(-sentMessageTTL-time.Minute), "")
+	tracker.revokeOnRead("dev", "old", time.Minute)
+	tracker.record("dev", chat, "fresh", text, time.Now(), "")
+
+	if _, ok := tracker.get("dev", "old"); ok {
+		t.Fatal("expired entry survived the next record")
+	}
+	if _, ok := tracker.takeReadRevoke("dev", "old"); ok {
+		t.Fatal("expired entry kept its read revoke")
+	}
+	if _, ok := tracker.get("dev", "fresh"); !ok {
+		t.Fatal("fresh entry was pruned")
+	}
+	if len(tracker.byAge) != 1 {
+		t.Fatalf("age list holds %d entries, want 1", len(tracker.byAge))
+	}
+}
+
+func TestMessageKind(t *testing.T) {
+	tests := []struct {
+		msg  *waProto.Message
+		want string
+	}{
+		{&waProto.Message{Conversation: proto.String("hi")}, "text"},
+		{&waProto.Message{ExtendedTextMessage: &waProto.ExtendedTextMessage{Text: proto.String("hi")}}, "text"},
+		{&waProto.Message{DocumentMessage: &waProto.DocumentMessage{}}, "document"},
+		{&waProto.Message{StickerMessage: &waProto.StickerMessage{}}, "sticker"},
+		{&waProto.Message{LocationMessage: &waProto.LocationMessage{}}, "location"},
+		{&waProto.Message{ContactMessage: &waProto.ContactMessage{}}, "contact"},
+		{&waProto.Message{ContactsArrayMessage: &waProto.ContactsArrayMessage{}}, "contact"},
+		{&waProto.Message{PollCreationMessageV3: &waProto.PollCreationMessage{}}, "poll"},
+		{&waProto.Message{ViewOnceMessage: &waProto.FutureProofMessage{}}, "interactive"},
+	}
+	for _, tt := range tests {
+		if got := messageKind(tt.msg); got != tt.want {
+			t.Errorf("messageKind(%v) = %q, want %q", tt.msg, got, tt.want)
+		}
+	}
+}
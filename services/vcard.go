@@ -0,0 +1,107 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrInvalidContact is returned when a shared contact has no usable name, phone or vCard
+var ErrInvalidContact = errors.New("invalid contact")
+
+// SharedContact is a contact card sent in a contact message; VCard, when set,
+// is sent as-is instead of one generated from Name and Phone
+type SharedContact struct {
+	Name  string
+	Phone string
+	VCard string
+}
+
+// vcardEscaper escapes text values as required by RFC 2426
+var vcardEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, ";", `\;`, "\r\n", `\n`, "\n", `\n`)
+
+// buildVCard generates a vCard 3.0 body for a contact
+func buildVCard(name, phone string) string {
+	escaped := vcardEscaper.Replace(name)
+	return strings.Join([]string{
+		"BEGIN:VCARD",
+		"VERSION:3.0",
+		"N:" + escaped + ";;;;",
+		"FN:" + escaped,
+		fmt.Sprintf("TEL;type=CELL;type=VOICE;waid=%s:+%s", phone, phone),
+		"END:VCARD",
+	}, "\r\n") + "\r\n"
+}
+
+// contactMessage validates a contact and builds its WhatsApp message
+func contactMessage(contact SharedContact) (*waProto.ContactMessage, error) {
+	name := strings.TrimSpace(contact.Name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidContact)
+	}
+
+	vcard := contact.VCard
+	if vcard == "" {
+		phone := normalizePhone(contact.Phone)
+		if len(phone) < 10 || len(phone) > 15 || strings.Trim(phone, "0123456789") != "" {
+			return nil, fmt.Errorf("%w: phone %q must be country_code + number", ErrInvalidContact, contact.Phone)
+		}
+		vcard = buildVCard(name, phone)
+	} else if !strings.HasPrefix(strings.TrimSpace(vcard), "BEGIN:VCARD") {
+		return nil, fmt.Errorf("%w: vcard must start with BEGIN:VCARD", ErrInvalidContact)
+	}
+
+	return &waProto.ContactMessage{
+		DisplayName: proto.String(name),
+		Vcard:       proto.String(vcard),
+	}, nil
+}
+
+// SendContactMessage shares one contact card, or several as a contacts array message
+func (s *WhatsAppService) SendContactMessage(deviceID, phone, groupJID string, contacts []SharedContact, opts SendOptions) (string, int64, error) {
+	if len(contacts) == 0 {
+		return "", 0, fmt.Errorf("%w: at least one contact is required", ErrInvalidContact)
+	}
+
+	cards := make([]*waProto.ContactMessage, 0, len(contacts))
+	for _, contact := range contacts {
+		card, err := contactMessage(contact)
+		if err != nil {
+			return "", 0, err
+		}
+		cards = append(cards, card)
+	}
+
+	client, err := s.connectedSession(deviceID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	jid, err := resolveRecipient(phone, groupJID)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := s.ensureGroupMember(client, jid); err != nil {
+		return "", 0, err
+	}
+
+	msg := &waProto.Message{ContactMessage: cards[0]}
+	if len(cards) > 1 {
+		msg = &waProto.Message{
+			ContactsArrayMessage: &waProto.ContactsArrayMessage{
+				DisplayName: proto.String(fmt.Sprintf("%d contacts", len(cards))),
+				Contacts:    cards,
+			},
+		}
+	}
+
+	resp, err := s.deliver(client, jid, msg, opts)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to send contact: %v", err)
+	}
+
+	return resp.ID, resp.Timestamp.Unix(), nil
+}
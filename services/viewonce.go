@@ -0,0 +1,25 @@
+package services
+
+import (
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"google.golang.org/protobuf/proto"
+)
+
+// viewOnceMessage marks image, video and audio messages as view-once and wraps
+// them the way WhatsApp clients expect; other messages are returned unchanged
+func viewOnceMessage(msg *waProto.Message) *waProto.Message {
+	switch {
+	case msg.ImageMessage != nil:
+		msg.ImageMessage.ViewOnce = proto.Bool(true)
+	case msg.VideoMessage != nil:
+		msg.VideoMessage.ViewOnce = proto.Bool(true)
+	case msg.AudioMessage != nil:
+		msg.AudioMessage.ViewOnce = proto.Bool(true)
+	default:
+		return msg
+	}
+
+	return &waProto.Message{
+		ViewOnceMessage: &waProto.FutureProofMessage{Message: msg},
+	}
+}
@@ -3,21 +3,27 @@ package services
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
-	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
 )
 
 // WebhookPayload represents the data sent to webhook URL
 type WebhookPayload struct {
+	Event           string      `json:"event"`
 	DeviceID        string      `json:"device_id"`
+	DevicePhone     string      `json:"device_phone"`
 	MessageID       string      `json:"message_id"`
 	From            string      `json:"from"`
 	FromName        string      `json:"from_name"`
@@ -28,41 +34,181 @@ type WebhookPayload struct {
 	GroupJID        *string     `json:"group_jid"`
 	GroupName       *string     `json:"group_name"`
 	MediaURL        *string     `json:"media_url"`
+	MediaTooLarge   bool        `json:"media_too_large,omitempty"`
 	QuotedMessage   interface{} `json:"quoted_message"`
+	PollVote        *PollVote   `json:"poll_vote,omitempty"`
 }
 
+// WebhookEvent represents a non-message event sent to webhook URL
+type WebhookEvent struct {
+	Event       string      `json:"event"`
+	DeviceID    string      `json:"device_id"`
+	DevicePhone string      `json:"device_phone"`
+	Timestamp   int64       `json:"timestamp"`
+	Data        interface{} `json:"data"`
+}
+
+const (
+	defaultWebhookTimeout     = 10 * time.Second
+	defaultWebhookRetry       = 3
+	defaultWebhookBackoffBase = time.Second
+	// maxWebhookBackoff caps the exponential delay between delivery attempts
+	maxWebhookBackoff = time.Minute
+)
+
 // WebhookService handles sending incoming messages to webhook URL
 type WebhookService struct {
 	enabled    bool
 	webhookURL string
 	retryCount int
 	httpClient *http.Client
+	stats      *webhookStats
+	breaker    *circuitBreaker
+	logger     waLog.Logger
+
+	// backoffBase is the delay before the first retry, doubled on each further attempt
+	backoffBase time.Duration
+
+	// debug logs full request and response bodies of every delivery attempt
+	debug          bool
+	debugBodyLimit int
+
+	// messageTypes limits forwarded messages to these types; nil forwards all
+	messageTypes map[string]bool
+
+	// downloadMedia saves received attachments so media_url points at the file itself
+	downloadMedia bool
 }
 
 var webhookService *WebhookService
 
-// InitWebhookService initializes the webhook service
-func InitWebhookService() {
+// InitWebhookService initializes the webhook service, logging through logger
+func InitWebhookService(logger waLog.Logger) error {
 	enabled, _ := strconv.ParseBool(os.Getenv("WEBHOOK_ENABLED"))
-	retryCount, _ := strconv.Atoi(os.Getenv("WEBHOOK_RETRY"))
-	if retryCount == 0 {
-		retryCount = 3
+	retryCount := positiveEnvInt("WEBHOOK_RETRY", defaultWebhookRetry)
+	timeout := time.Duration(positiveEnvInt("WEBHOOK_TIMEOUT_SECONDS", int(defaultWebhookTimeout/time.Second))) * time.Second
+	backoffBase := time.Duration(positiveEnvInt("WEBHOOK_BACKOFF_BASE_MS", int(defaultWebhookBackoffBase/time.Millisecond))) * time.Millisecond
+
+	debug, _ := strconv.ParseBool(os.Getenv("WEBHOOK_DEBUG"))
+	downloadMedia, _ := strconv.ParseBool(os.Getenv("WEBHOOK_DOWNLOAD_MEDIA"))
+
+	transport, err := webhookTransport(os.Getenv("WEBHOOK_CA_FILE"))
+	if err != nil {
+		return err
+	}
+
+	// WEBHOOK_DEBUG needs debug output even when LOG_LEVEL is higher
+	if logger == nil || debug {
+		logger = webhookLogger(debug)
 	}
 
 	webhookService = &WebhookService{
-		enabled:    enabled,
-		webhookURL: os.Getenv("WEBHOOK_URL"),
-		retryCount: retryCount,
+		enabled:        enabled,
+		webhookURL:     os.Getenv("WEBHOOK_URL"),
+		retryCount:     retryCount,
+		backoffBase:    backoffBase,
+		stats:          newWebhookStats(),
+		breaker:        newCircuitBreaker(),
+		messageTypes:   parseMessageTypes(os.Getenv("WEBHOOK_MESSAGE_TYPES")),
+		logger:         logger,
+		debug:          debug,
+		debugBodyLimit: webhookDebugBodyLimit(),
+		downloadMedia:  downloadMedia,
 		httpClient: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout:   timeout,
+			Transport: transport,
 		},
 	}
+
+	return nil
+}
+
+// positiveEnvInt reads a positive integer from the environment, returning fallback when unset or invalid
+func positiveEnvInt(name string, fallback int) int {
+	n, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || n <= 0 {
+		return fallback
+	}
+	return n
+}
+
+// retryBackoff returns the delay before the attempt following the given one, capped at maxWebhookBackoff
+func (w *WebhookService) retryBackoff(attempt int) time.Duration {
+	base := w.backoffBase
+	if base <= 0 {
+		base = defaultWebhookBackoffBase
+	}
+	if attempt >= 16 {
+		return maxWebhookBackoff
+	}
+	if backoff := base << uint(attempt); backoff > 0 && backoff < maxWebhookBackoff {
+		return backoff
+	}
+	return maxWebhookBackoff
+}
+
+// webhookLogger creates the webhook logger; debug lowers its level so bodies are logged
+func webhookLogger(debug bool) waLog.Logger {
+	level := "INFO"
+	if debug {
+		level = "DEBUG"
+	}
+	return waLog.Stdout("Webhook", level, true)
+}
+
+// webhookTransport builds the HTTP transport for webhook delivery, trusting
+// the CA bundle in caFile in addition to the system roots when set
+func webhookTransport(caFile string) (http.RoundTripper, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if caFile == "" {
+		return transport, nil
+	}
+
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WEBHOOK_CA_FILE: %v", err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("WEBHOOK_CA_FILE %s contains no valid PEM certificates", caFile)
+	}
+
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return transport, nil
+}
+
+// parseMessageTypes parses a comma-separated list of message types, returning nil for "all"
+func parseMessageTypes(raw string) map[string]bool {
+	allowed := make(map[string]bool)
+	for _, t := range strings.Split(raw, ",") {
+		if t = strings.ToLower(strings.TrimSpace(t)); t != "" {
+			allowed[t] = true
+		}
+	}
+
+	if len(allowed) == 0 {
+		return nil
+	}
+	return allowed
+}
+
+// forwardsType reports whether messages of the given type are sent to the webhook
+func (w *WebhookService) forwardsType(messageType string) bool {
+	return w.messageTypes == nil || w.messageTypes[messageType]
 }
 
 // GetWebhookService returns the webhook service instance
 func GetWebhookService() *WebhookService {
 	if webhookService == nil {
-		InitWebhookService()
+		logger := AppLogger().Sub("Webhook")
+		if err := InitWebhookService(logger); err != nil {
+			logger.Errorf("Webhook service disabled: %v", err)
+			webhookService = &WebhookService{stats: newWebhookStats(), breaker: newCircuitBreaker(), logger: logger}
+		}
 	}
 	return webhookService
 }
@@ -76,47 +222,37 @@ func extractPhoneNumber(jid types.JID) string {
 	return user
 }
 
-// HandleIncomingMessage processes incoming WhatsApp messages and sends to webhook
-func (w *WebhookService) HandleIncomingMessage(deviceID string, evt *events.Message) {
-	if !w.enabled || w.webhookURL == "" {
-		fmt.Printf("Webhook disabled or URL not set, skipping message forwarding\n")
-		return
+// devicePhone returns the WhatsApp number of the device that received an event
+func devicePhone(deviceID string) string {
+	if waService == nil {
+		return ""
 	}
 
-	fmt.Printf("========== MESSAGE EVENT DUMP ==========\n")
-	fmt.Printf("Forwarding message from device %s to webhook: %s\n", deviceID, w.webhookURL)
-
-	// Dump full event info
-	eventJSON, _ := json.MarshalIndent(evt, "", "  ")
-	fmt.Printf("Full Event:\n%s\n", string(eventJSON))
+	client, err := waService.GetSession(deviceID)
+	if err != nil {
+		return ""
+	}
 
-	// Dump specific message info fields
-	fmt.Printf("Message Info:\n")
-	fmt.Printf("  ID: %s\n", evt.Info.ID)
-	fmt.Printf("  Sender: %s\n", evt.Info.Sender.String())
-	fmt.Printf("  SenderAlt: %s\n", evt.Info.SenderAlt.String())
-	fmt.Printf("  Chat: %s\n", evt.Info.Chat.String())
-	fmt.Printf("  IsFromMe: %v\n", evt.Info.IsFromMe)
-	fmt.Printf("  IsGroup: %v\n", evt.Info.IsGroup)
-	fmt.Printf("  PushName: %s\n", evt.Info.PushName)
-	fmt.Printf("  Timestamp: %v\n", evt.Info.Timestamp)
+	return client.GetPhone()
+}
 
-	// Use reflection to see all fields
-	fmt.Printf("Event Type: %s\n", reflect.TypeOf(evt).String())
+// HandleIncomingMessage processes incoming WhatsApp messages and sends to webhook
+func (w *WebhookService) HandleIncomingMessage(deviceID string, evt *events.Message) {
+	if !w.enabled || len(w.targets(deviceID)) == 0 {
+		w.logger.Debugf("Webhook disabled or URL not set, skipping message forwarding")
+		return
+	}
 
-	// Check message content directly (evt is already *events.Message)
-	fmt.Printf("Message Type: %s\n", reflect.TypeOf(evt).String())
+	w.logger.Debugf("Forwarding message %s from device %s to webhook: %v", evt.Info.ID, deviceID, w.targets(deviceID))
+	w.logger.Debugf("Message info: sender=%s sender_alt=%s chat=%s from_me=%v group=%v push_name=%s timestamp=%v",
+		evt.Info.Sender, evt.Info.SenderAlt, evt.Info.Chat, evt.Info.IsFromMe, evt.Info.IsGroup, evt.Info.PushName, evt.Info.Timestamp)
 
-	// Check message content
-	if evt.Message.GetConversation() != "" {
-		fmt.Printf("  Conversation: %s\n", evt.Message.GetConversation())
-	}
-	if evt.Message.GetExtendedTextMessage() != nil {
-		fmt.Printf("  Extended Text: %s\n", evt.Message.GetExtendedTextMessage().GetText())
+	// The full event carries the message body, so it is only dumped at LOG_LEVEL=debug
+	if debugLogging() {
+		eventJSON, _ := json.MarshalIndent(evt, "", "  ")
+		w.logger.Debugf("Full event:\n%s", eventJSON)
 	}
 
-	fmt.Printf("========================================\n")
-
 	// Determine the actual sender
 	var actualSender types.JID
 	var actualSenderName string
@@ -125,23 +261,25 @@ func (w *WebhookService) HandleIncomingMessage(deviceID string, evt *events.Mess
 		// This is a message sent by ourselves
 		actualSender = evt.Info.Sender
 		actualSenderName = "Me"
-		fmt.Printf("Message from self - using Sender: %s\n", actualSender.String())
+		w.logger.Debugf("Message from self - using Sender: %s", actualSender)
 	} else {
 		// This is a message from someone else - use SenderAlt if it has a valid user
 		if evt.Info.SenderAlt.User != "" {
 			actualSender = evt.Info.SenderAlt
 			actualSenderName = evt.Info.PushName
-			fmt.Printf("Message from others - using SenderAlt: %s, PushName: %s\n", actualSender.String(), actualSenderName)
+			w.logger.Debugf("Message from others - using SenderAlt: %s, PushName: %s", actualSender, actualSenderName)
 		} else {
 			actualSender = evt.Info.Chat
 			actualSenderName = evt.Info.PushName
-			fmt.Printf("Message from others - SenderAlt empty, using Chat: %s, PushName: %s\n", actualSender.String(), actualSenderName)
+			w.logger.Debugf("Message from others - SenderAlt empty, using Chat: %s, PushName: %s", actualSender, actualSenderName)
 		}
 	}
 
 	// Build webhook payload
 	payload := WebhookPayload{
+		Event:       "message",
 		DeviceID:    deviceID,
+		DevicePhone: devicePhone(deviceID),
 		MessageID:   evt.Info.ID,
 		From:        extractPhoneNumber(actualSender),
 		FromName:    actualSenderName,
@@ -166,66 +304,187 @@ func (w *WebhookService) HandleIncomingMessage(deviceID string, evt *events.Mess
 	} else if evt.Message.GetDocumentMessage() != nil {
 		payload.MessageType = "document"
 		payload.Message = evt.Message.GetDocumentMessage().GetCaption()
+	} else if evt.Message.GetPollUpdateMessage() != nil {
+		payload.MessageType = "poll_vote"
+		if waService != nil {
+			vote, err := waService.decryptPollVote(deviceID, evt)
+			if err != nil {
+				w.logger.Errorf("Failed to decrypt poll vote %s: %v", evt.Info.ID, err)
+			}
+			payload.PollVote = vote
+		}
+	}
+
+	// Skip message types the consumer didn't ask for, before downloading their media
+	if !w.forwardsType(payload.MessageType) {
+		w.logger.Debugf("Skipping %s message, not in WEBHOOK_MESSAGE_TYPES", payload.MessageType)
+		return
+	}
+
+	// Attachments are served from the media cache, or saved to disk when WEBHOOK_DOWNLOAD_MEDIA is set
+	if mediaOf(evt.Message) != nil && payload.MessageType != "text" {
+		mediaURL := MediaPath(deviceID, evt.Info.ID)
+		if w.downloadMedia && waService != nil {
+			saved, err := waService.saveIncomingMedia(deviceID, evt)
+			switch {
+			case errors.Is(err, ErrMediaTooLarge):
+				w.logger.Warnf("Skipping media of message %s: %v", evt.Info.ID, err)
+				payload.MediaTooLarge = true
+			case err != nil:
+				w.logger.Errorf("Failed to download media of message %s: %v", evt.Info.ID, err)
+			default:
+				mediaURL = saved
+			}
+		}
+		if !payload.MediaTooLarge {
+			payload.MediaURL = &mediaURL
+		}
 	}
 
 	// Handle group messages
 	if evt.Info.IsGroup {
 		groupJID := extractPhoneNumber(evt.Info.Chat)
 		payload.GroupJID = &groupJID
-		fmt.Printf("Group message - Group JID: %s\n", groupJID)
+		w.logger.Debugf("Group message - Group JID: %s", groupJID)
 	}
 
 	// Send to webhook with retry
-	fmt.Printf("Sending webhook payload: %+v\n", payload)
-	go w.sendWithRetry(payload)
+	logged := payload
+	logged.Message = redactBody(payload.Message)
+	if logged.QuotedMessage != nil && !debugLogging() {
+		logged.QuotedMessage = "[redacted]"
+	}
+	w.logger.Debugf("Sending webhook payload: %+v", logged)
+	w.dispatch(deviceID, payload)
+}
+
+// SendEvent forwards a non-message event to the webhook
+func (w *WebhookService) SendEvent(deviceID, event string, data interface{}) {
+	if !w.enabled {
+		return
+	}
+
+	w.dispatch(deviceID, WebhookEvent{
+		Event:       event,
+		DeviceID:    deviceID,
+		DevicePhone: devicePhone(deviceID),
+		Timestamp:   time.Now().Unix(),
+		Data:        data,
+	})
+}
+
+// forward sends a payload that carries its own event envelope to the webhook
+func (w *WebhookService) forward(deviceID string, payload interface{}) {
+	if !w.enabled || len(w.targets(deviceID)) == 0 {
+		return
+	}
+
+	w.dispatch(deviceID, payload)
+}
+
+// targets returns the webhook URLs for a device: its routed URLs when
+// configured, otherwise the global WEBHOOK_URL
+func (w *WebhookService) targets(deviceID string) []string {
+	if waService != nil {
+		if client, err := waService.GetSession(deviceID); err == nil {
+			if urls := client.GetConfig().WebhookURLs; len(urls) > 0 {
+				return urls
+			}
+		}
+	}
+
+	if w.webhookURL == "" {
+		return nil
+	}
+	return []string{w.webhookURL}
+}
+
+// dispatch fans a payload out to every target of the device, each with its own retries
+func (w *WebhookService) dispatch(deviceID string, payload interface{}) {
+	headers := w.headers(deviceID)
+	for _, target := range w.targets(deviceID) {
+		go w.sendWithRetry(target, headers, payload)
+	}
+}
+
+// headers returns the device's custom webhook headers
+func (w *WebhookService) headers(deviceID string) map[string]string {
+	if waService == nil {
+		return nil
+	}
+	client, err := waService.GetSession(deviceID)
+	if err != nil {
+		return nil
+	}
+	return client.GetConfig().WebhookHeaders
 }
 
 // sendWithRetry sends payload to webhook with exponential backoff retry
-func (w *WebhookService) sendWithRetry(payload WebhookPayload) {
+func (w *WebhookService) sendWithRetry(target string, headers map[string]string, payload interface{}) {
+	// Short-circuit while the target's breaker is open
+	if !w.breaker.allow(target) {
+		w.logger.Warnf("Webhook circuit open for %s, dead-lettering payload", target)
+		w.stats.recordDeadLetter(target)
+		webhookFailures.WithLabelValues("circuit_open").Inc()
+		return
+	}
+
 	var lastErr error
 	
 	for attempt := 0; attempt < w.retryCount; attempt++ {
-		fmt.Printf("Webhook attempt %d/%d\n", attempt+1, w.retryCount)
-		err := w.send(payload)
+		w.logger.Debugf("Webhook attempt %d/%d to %s", attempt+1, w.retryCount, target)
+		err := w.send(target, headers, payload)
+		w.stats.recordAttempt(target, err)
 		if err == nil {
-			fmt.Printf("Webhook sent successfully on attempt %d\n", attempt+1)
+			w.logger.Infof("Webhook sent to %s on attempt %d", target, attempt+1)
+			w.stats.recordDelivery(target, true)
+			webhookDeliveries.Inc()
+			w.breaker.record(target, true)
 			return // Success
 		}
 
 		lastErr = err
-		fmt.Printf("Webhook attempt %d failed: %v\n", attempt+1, err)
+		w.logger.Warnf("Webhook attempt %d to %s failed: %v", attempt+1, target, err)
 
 		// Exponential backoff
 		if attempt < w.retryCount-1 {
-			backoff := time.Duration(1<<uint(attempt)) * time.Second
-			fmt.Printf("Retrying webhook in %v\n", backoff)
+			backoff := w.retryBackoff(attempt)
+			w.logger.Debugf("Retrying webhook in %v", backoff)
 			time.Sleep(backoff)
 		}
 	}
 
 	// Log final failure
-	fmt.Printf("Failed to send webhook after %d attempts: %v\n", w.retryCount, lastErr)
+	w.logger.Errorf("Failed to send webhook to %s after %d attempts: %v", target, w.retryCount, lastErr)
+	w.stats.recordDelivery(target, false)
+	webhookFailures.WithLabelValues("retries_exhausted").Inc()
+	w.breaker.record(target, false)
 }
 
 // send sends the payload to webhook URL
-func (w *WebhookService) send(payload WebhookPayload) error {
+func (w *WebhookService) send(target string, headers map[string]string, payload interface{}) error {
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("failed to marshal payload: %v", err)
 	}
 
-	req, err := http.NewRequestWithContext(context.Background(), "POST", w.webhookURL, bytes.NewBuffer(jsonData))
+	req, err := http.NewRequestWithContext(context.Background(), "POST", target, bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %v", err)
 	}
 
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
 	req.Header.Set("Content-Type", "application/json")
+	w.logRequest(req, jsonData)
 
 	resp, err := w.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send request: %v", err)
 	}
 	defer resp.Body.Close()
+	w.logResponse(target, resp)
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		return fmt.Errorf("webhook returned status code: %d", resp.StatusCode)
@@ -234,15 +493,3 @@ func (w *WebhookService) send(payload WebhookPayload) error {
 	return nil
 }
 
-// SetupEventHandler sets up the event handler for a device client
-func SetupEventHandler(deviceID string, dc *DeviceClient) {
-	webhookSvc := GetWebhookService()
-	
-	dc.EventHandler = func(evt interface{}) {
-		switch v := evt.(type) {
-		case *events.Message:
-			webhookSvc.HandleIncomingMessage(deviceID, v)
-		}
-	}
-}
-
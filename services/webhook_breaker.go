@@ -0,0 +1,124 @@
+package services
+
+import (
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 60 * time.Second
+)
+
+// breakerState is the circuit state of a single webhook target
+type breakerState struct {
+	state               string
+	consecutiveFailures int
+	openedAt            time.Time
+	trialInFlight       bool
+}
+
+// circuitBreaker stops delivering to webhook targets that keep failing.
+// After threshold consecutive failed deliveries a target opens and new
+// payloads are dead-lettered; once the cooldown passes a single trial
+// delivery is let through (half-open) and its outcome closes or re-opens it.
+type circuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	mu        sync.Mutex
+	targets   map[string]*breakerState
+}
+
+// newCircuitBreaker reads WEBHOOK_BREAKER_THRESHOLD and WEBHOOK_BREAKER_COOLDOWN_SECONDS
+func newCircuitBreaker() *circuitBreaker {
+	threshold, err := strconv.Atoi(os.Getenv("WEBHOOK_BREAKER_THRESHOLD"))
+	if err != nil || threshold <= 0 {
+		threshold = defaultBreakerThreshold
+	}
+
+	cooldown := defaultBreakerCooldown
+	if seconds, err := strconv.Atoi(os.Getenv("WEBHOOK_BREAKER_COOLDOWN_SECONDS")); err == nil && seconds > 0 {
+		cooldown = time.Duration(seconds) * time.Second
+	}
+
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		targets:   make(map[string]*breakerState),
+	}
+}
+
+func (b *circuitBreaker) target(url string) *breakerState {
+	state, ok := b.targets[url]
+	if !ok {
+		state = &breakerState{state: BreakerClosed}
+		b.targets[url] = state
+	}
+	return state
+}
+
+// allow reports whether a delivery to url may proceed
+func (b *circuitBreaker) allow(url string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.target(url)
+	switch state.state {
+	case BreakerOpen:
+		if time.Since(state.openedAt) < b.cooldown {
+			return false
+		}
+		state.state = BreakerHalfOpen
+		state.trialInFlight = true
+		return true
+	case BreakerHalfOpen:
+		if state.trialInFlight {
+			return false
+		}
+		state.trialInFlight = true
+		return true
+	default:
+		return true
+	}
+}
+
+// record updates the circuit with the final outcome of a delivery
+func (b *circuitBreaker) record(url string, delivered bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := b.target(url)
+	state.trialInFlight = false
+
+	if delivered {
+		state.state = BreakerClosed
+		state.consecutiveFailures = 0
+		return
+	}
+
+	state.consecutiveFailures++
+	if state.state == BreakerHalfOpen || state.consecutiveFailures >= b.threshold {
+		state.state = BreakerOpen
+		state.openedAt = time.Now()
+	}
+}
+
+// state returns the current circuit state of url
+func (b *circuitBreaker) state(url string) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if state, ok := b.targets[url]; ok {
+		return state.state
+	}
+	return BreakerClosed
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// defaultWebhookDebugBodyLimit is how many bytes of each body WEBHOOK_DEBUG logs by default
+const defaultWebhookDebugBodyLimit = 2048
+
+// sensitiveHeaderMarkers flags header names whose values are never logged
+var sensitiveHeaderMarkers = []string{"authorization", "signature", "secret", "token", "api-key"}
+
+// webhookDebugBodyLimit reads WEBHOOK_DEBUG_MAX_BODY
+func webhookDebugBodyLimit() int {
+	n, err := strconv.Atoi(os.Getenv("WEBHOOK_DEBUG_MAX_BODY"))
+	if err != nil || n <= 0 {
+		return defaultWebhookDebugBodyLimit
+	}
+	return n
+}
+
+// truncateBody shortens a body for logging
+func truncateBody(body []byte, limit int) string {
+	if len(body) <= limit {
+		return string(body)
+	}
+	return string(body[:limit]) + "...(truncated)"
+}
+
+// redactHeaders formats request headers for logging, hiding credentials and signatures
+func redactHeaders(header http.Header) map[string]string {
+	redacted := make(map[string]string, len(header))
+	for name, values := range header {
+		value := strings.Join(values, ", ")
+		lower := strings.ToLower(name)
+		for _, marker := range sensitiveHeaderMarkers {
+			if strings.Contains(lower, marker) {
+				value = "[redacted]"
+				break
+			}
+		}
+		redacted[name] = value
+	}
+	return redacted
+}
+
+// logRequest logs an outgoing webhook request when WEBHOOK_DEBUG is enabled
+func (w *WebhookService) logRequest(req *http.Request, body []byte) {
+	if !w.debug {
+		return
+	}
+	w.logger.Debugf("POST %s headers=%v body=%s", req.URL, redactHeaders(req.Header), truncateBody(body, w.debugBodyLimit))
+}
+
+// logResponse logs a webhook response when WEBHOOK_DEBUG is enabled
+func (w *WebhookService) logResponse(target string, resp *http.Response) {
+	if !w.debug {
+		return
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, int64(w.debugBodyLimit)+1))
+	w.logger.Debugf("Response from %s: status=%d body=%s", target, resp.StatusCode, truncateBody(body, w.debugBodyLimit))
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// WebhookTargetStats aggregates delivery results for one webhook URL
+type WebhookTargetStats struct {
+	URL           string `json:"url"`
+	Attempts      int64  `json:"attempts"`
+	Delivered     int64  `json:"delivered"`
+	Failed        int64  `json:"failed"`
+	DeadLettered  int64  `json:"dead_lettered"`
+	BreakerState  string `json:"breaker_state"`
+	LastError     string `json:"last_error,omitempty"`
+	LastAttemptAt int64  `json:"last_attempt_at,omitempty"`
+}
+
+// webhookStats tracks delivery results per webhook target
+type webhookStats struct {
+	mu      sync.Mutex
+	targets map[string]*WebhookTargetStats
+}
+
+func newWebhookStats() *webhookStats {
+	return &webhookStats{targets: make(map[string]*WebhookTargetStats)}
+}
+
+func (s *webhookStats) target(url string) *WebhookTargetStats {
+	stats, ok := s.targets[url]
+	if !ok {
+		stats = &WebhookTargetStats{URL: url}
+		s.targets[url] = stats
+	}
+	return stats
+}
+
+// recordAttempt counts a single HTTP attempt and remembers its error
+func (s *webhookStats) recordAttempt(url string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.target(url)
+	stats.Attempts++
+	stats.LastAttemptAt = time.Now().Unix()
+	if err != nil {
+		stats.LastError = err.Error()
+	}
+}
+
+// recordDelivery counts the final outcome of a payload after all retries
+func (s *webhookStats) recordDelivery(url string, delivered bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.target(url)
+	if delivered {
+		stats.Delivered++
+	} else {
+		stats.Failed++
+	}
+}
+
+// recordDeadLetter counts a payload dropped because the target's circuit was open
+func (s *webhookStats) recordDeadLetter(url string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.target(url).DeadLettered++
+}
+
+// snapshot returns a copy of every target's stats
+func (s *webhookStats) snapshot() []WebhookTargetStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]WebhookTargetStats, 0, len(s.targets))
+	for _, stats := range s.targets {
+		result = append(result, *stats)
+	}
+	return result
+}
+
+// Stats returns aggregated delivery results per webhook target
+func (w *WebhookService) Stats() []WebhookTargetStats {
+	stats := w.stats.snapshot()
+	for i := range stats {
+		stats[i].BreakerState = w.breaker.state(stats[i].URL)
+	}
+	return stats
+}
+
+// ValidateWebhookURL checks that a webhook target is a well-formed http(s) URL
+func ValidateWebhookURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q: must be an absolute http(s) URL", raw)
+	}
+	return nil
+}
+
+// reservedWebhookHeaders are set by the webhook client and can't be overridden per device
+var reservedWebhookHeaders = map[string]bool{
+	"Content-Type":      true,
+	"Content-Length":    true,
+	"Host":              true,
+	"Transfer-Encoding": true,
+	"Connection":        true,
+}
+
+// ValidateWebhookHeader checks that a custom webhook header has a valid, overridable name and a single-line value
+func ValidateWebhookHeader(name, value string) error {
+	if name == "" {
+		return fmt.Errorf("webhook header name is required")
+	}
+	for _, r := range name {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("!#$%&'*+-.^_`|~", r)) {
+			return fmt.Errorf("invalid webhook header name %q", name)
+		}
+	}
+	if reservedWebhookHeaders[http.CanonicalHeaderKey(name)] {
+		return fmt.Errorf("webhook header %q can't be overridden", name)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return fmt.Errorf("webhook header %q must not contain line breaks", name)
+	}
+	return nil
+}
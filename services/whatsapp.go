@@ -2,12 +2,18 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+	"unicode/utf8"
+	"waku/utils"
 
 	"go.mau.fi/whatsmeow"
 	waProto "go.mau.fi/whatsmeow/proto/waE2E"
@@ -24,20 +30,107 @@ import (
 type DeviceClient struct {
 	Client       *whatsmeow.Client
 	DeviceID     string
-	QRChan       chan string
-	Connected    bool
-	Phone        string
-	ConnectedAt  time.Time
+	CreatedAt    time.Time
+	// EventHandler, when set, also receives incoming messages after the built-in store and webhook handling
 	EventHandler func(interface{})
+
+	// stateMu guards the connection state, written by the event handler and read by API requests
+	stateMu     sync.RWMutex
+	connected   bool
+	phoneNumber string
+	connectedAt time.Time
+
+	config   DeviceConfig
+	configMu sync.RWMutex
+	logs     *logBuffer
+	phone    phoneState
+
+	// lastKeepAlive is the unix time the socket was last known to be alive
+	lastKeepAlive atomic.Int64
+
+	reconnect reconnectState
+
+	queue     *sendQueue
+	queueOnce sync.Once
+
+	pairReady chan struct{}
+	pairOnce  sync.Once
+
+	// qr holds the current pairing codes for every reader of the QR endpoints
+	qr qrState
+
+	// successor is the client that took over when the session was renamed
+	successor atomic.Pointer[DeviceClient]
+
+	// loggedOut is set when WhatsApp reports the device as unlinked
+	loggedOut atomic.Bool
 }
 
+// SendOptions carries optional per-request send behaviour
+type SendOptions struct {
+	// PTT sends audio as a push-to-talk voice note
+	PTT bool
+	// Footer overrides the device footer when set; an empty string suppresses it
+	Footer *string
+	// Priority lets the message jump ahead of normal sends in the device queue
+	Priority bool
+	// ExpireAfter revokes the message if it hasn't been delivered within this duration
+	ExpireAfter time.Duration
+	// RevokeAfterRead revokes the message this long after a read receipt arrives
+	RevokeAfterRead time.Duration
+	// RequestID is the API request that sent the message, echoed in its webhooks
+	RequestID string
+	// Quote sends a text message as a reply to an earlier message
+	Quote *QuotedMessage
+	// Mentions are the users @-mentioned in a text message
+	Mentions []types.JID
+	// Disappearing overrides the chat's disappearing timer in seconds; 0 sends a normal message
+	Disappearing *uint32
+	// LinkPreview is attached to a text message as a rich link preview
+	LinkPreview *LinkPreview
+	// ViewOnce sends image, video and audio messages as view-once media
+	ViewOnce bool
+}
+
+const (
+	// MaxMessageLength is the maximum number of characters WhatsApp accepts in a text message
+	MaxMessageLength = 65536
+	// MaxCaptionLength is the maximum number of characters WhatsApp accepts in a media caption
+	MaxCaptionLength = 1024
+)
+
 // WhatsAppService manages multiple WhatsApp device clients
 type WhatsAppService struct {
 	clients map[string]*DeviceClient
 	mu      sync.RWMutex
 	logger  waLog.Logger
+
+	pictureCache *ttlCache[*ProfilePicture]
+	tracker      *messageTracker
+	groupCache   *ttlCache[*types.GroupInfo]
+	numbers      *numberChecker
+	membership   *ttlCache[map[string]bool]
+	media        *lruCache[cachedMedia]
+	chatTimers   *ttlCache[uint32]
+	polls        *ttlCache[*pollTally]
+	store        *messageStore
+	history      *historyWaiters
+	jobs         *ttlCache[*BulkJob]
+	presences    *ttlCache[ContactPresence]
+
+	// chatBuffer keeps recent messages per chat for history without the message store
+	chatBuffer *chatBuffer
+	// stream fans live events out to WebSocket subscribers
+	stream *eventHub
 }
 
+var (
+	// ErrSessionNotFound is returned when no session exists for a device ID
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrSessionNotConnected is returned when a session isn't logged in or connected
+	ErrSessionNotConnected = errors.New("session not connected")
+)
+
 var (
 	waService     *WhatsAppService
 	waServiceOnce sync.Once
@@ -47,17 +140,49 @@ var (
 func GetWhatsAppService() *WhatsAppService {
 	waServiceOnce.Do(func() {
 		waService = &WhatsAppService{
-			clients: make(map[string]*DeviceClient),
-			logger:  waLog.Stdout("WhatsApp", "INFO", true),
+			clients:      make(map[string]*DeviceClient),
+			logger:       AppLogger(),
+			pictureCache: newTTLCache[*ProfilePicture](profilePictureCacheTTL),
+			tracker:      newMessageTracker(),
+			groupCache:   newTTLCache[*types.GroupInfo](groupInfoCacheTTL),
+			numbers:      newNumberChecker(),
+			membership:   newTTLCache[map[string]bool](groupMembershipCacheTTL),
+			media:        newMediaCache(),
+			chatTimers:   newTTLCache[uint32](chatTimerCacheTTL),
+			polls:        newTTLCache[*pollTally](pollCacheTTL),
+			history:      newHistoryWaiters(),
+			jobs:         newTTLCache[*BulkJob](jobRetention),
+			presences:    newTTLCache[ContactPresence](presenceCacheTTL),
+			chatBuffer:   newChatBuffer(chatBufferSize()),
+			stream:       newEventHub(),
+		}
+
+		waService.configureKeepAlive()
+
+		// Open the message store before sessions start receiving events
+		if storeMessagesEnabled() {
+			store, err := openMessageStore()
+			if err != nil {
+				waService.logger.Errorf("Failed to open message store, messages won't be stored: %v", err)
+			} else {
+				waService.store = store
+			}
 		}
 
 		// Load existing sessions from disk
 		if err := waService.loadExistingSessions(); err != nil {
 			waService.logger.Errorf("Failed to load existing sessions: %v", err)
 		}
-	})
-	// Start reconnect attempts after loading sessions
+
+		// Remove sessions that are never paired
+		go waService.reapPendingSessions()
+
+		// Keep the message store within its retention window
+		go waService.runMessagePruner()
+
+		// Start reconnect attempts after loading sessions
 		go waService.retryReconnectAllSessions()
+	})
 
 	return waService
 }
@@ -87,7 +212,7 @@ func (s *WhatsAppService) retryReconnectAllSessions() {
 
 		for _, client := range sessions {
 			// Check if session needs reconnect (not connected but logged in)
-			if !client.Connected && client.Client.IsLoggedIn() && client.Client.Store.ID != nil {
+			if !client.IsConnected() && client.Client.IsLoggedIn() && client.Client.Store.ID != nil {
 				s.logger.Infof("Attempting to reconnect session: %s", client.DeviceID)
 
 				if err := client.Client.Connect(); err != nil {
@@ -99,17 +224,15 @@ func (s *WhatsAppService) retryReconnectAllSessions() {
 
 					// Verify connection
 					if client.Client.IsLoggedIn() && client.Client.Store.ID != nil {
-						client.Connected = true
-						client.Phone = client.Client.Store.ID.User
-						client.ConnectedAt = time.Now()
-						s.logger.Infof("Successfully reconnected %s as %s", client.DeviceID, client.Phone)
+						client.MarkConnected(client.Client.Store.ID.User)
+						s.logger.Infof("Successfully reconnected %s as %s", client.DeviceID, client.GetPhone())
 						reconnectedCount++
 					} else {
 						s.logger.Errorf("Session %s connected but not logged in", client.DeviceID)
 						failedCount++
 					}
 				}
-			} else if client.Connected {
+			} else if client.IsConnected() {
 				s.logger.Infof("Session %s already connected", client.DeviceID)
 			}
 		}
@@ -187,14 +310,34 @@ func (s *WhatsAppService) loadExistingSessions() error {
 
 // loadSession loads a single session from disk
 func (s *WhatsAppService) loadSession(deviceID string) error {
+	// Check if session already loaded
+	if _, err := s.GetSession(deviceID); err == nil {
+		return fmt.Errorf("session already loaded for device_id: %s", deviceID)
+	}
+
+	// Connecting can take seconds, so it happens without holding s.mu
+	deviceClient, err := s.openSession(deviceID)
+	if err != nil {
+		return err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Check if session already loaded
 	if _, exists := s.clients[deviceID]; exists {
+		deviceClient.Client.Disconnect()
+		deviceClient.closeStore()
 		return fmt.Errorf("session already loaded for device_id: %s", deviceID)
 	}
 
+	// Store client
+	s.clients[deviceID] = deviceClient
+
+	return nil
+}
+
+// openSession opens the session stored under deviceID and connects it
+func (s *WhatsAppService) openSession(deviceID string) (*DeviceClient, error) {
 	// Get session directory
 	sessionDir := filepath.Join(os.Getenv("SESSION_DIR"), deviceID)
 	dbPath := filepath.Join(sessionDir, "session.db")
@@ -202,27 +345,40 @@ func (s *WhatsAppService) loadSession(deviceID string) error {
 	// Create database container
 	container, err := sqlstore.New(context.Background(), "sqlite", fmt.Sprintf("file:%s?_pragma=foreign_keys(1)", dbPath), s.logger)
 	if err != nil {
-		return fmt.Errorf("failed to open database: %v", err)
+		return nil, fmt.Errorf("failed to open database: %v", err)
 	}
 
 	// Get first device from store
 	deviceStore, err := container.GetFirstDevice(context.Background())
 	if err != nil {
-		return fmt.Errorf("failed to get device from store: %v", err)
+		container.Close()
+		return nil, fmt.Errorf("failed to get device from store: %v", err)
 	}
 
 	if deviceStore == nil {
-		return fmt.Errorf("no device found in database")
+		container.Close()
+		return nil, fmt.Errorf("no device found in database")
 	}
 
-	// Create WhatsApp client
-	client := whatsmeow.NewClient(deviceStore, s.logger)
+	// Create WhatsApp client with a logger that keeps recent lines for this device
+	logs := newLogBuffer(logBufferSize())
+	client := whatsmeow.NewClient(deviceStore, newDeviceLogger(deviceID, s.logger, logs))
+	// Unexpected disconnects are retried with backoff by startAutoReconnect
+	client.EnableAutoReconnect = false
+
+	// Load per-device settings
+	config, err := loadDeviceConfig(deviceID)
+	if err != nil {
+		s.logger.Warnf("Failed to load config for device %s, using defaults: %v", deviceID, err)
+	}
 
 	// Create device client
 	deviceClient := &DeviceClient{
-		Client:   client,
-		DeviceID: deviceID,
-		QRChan:   make(chan string, 5),
+		Client:    client,
+		DeviceID:  deviceID,
+		CreatedAt: time.Now(),
+		config:    config,
+		logs:      logs,
 	}
 
 	// Set event handler
@@ -230,7 +386,8 @@ func (s *WhatsAppService) loadSession(deviceID string) error {
 
 	// Connect to WhatsApp
 	if err := client.Connect(); err != nil {
-		return fmt.Errorf("failed to connect: %v", err)
+		container.Close()
+		return nil, fmt.Errorf("failed to connect: %v", err)
 	}
 
 	// Wait a moment for connection to establish and check if already logged in with retry
@@ -245,24 +402,24 @@ func (s *WhatsAppService) loadSession(deviceID string) error {
 		}
 
 		if client.IsLoggedIn() {
-			deviceClient.Connected = true
-			deviceClient.Phone = client.Store.ID.User
-			deviceClient.ConnectedAt = time.Now()
-			s.logger.Infof("Device %s is already logged in as %s", deviceID, deviceClient.Phone)
+			deviceClient.MarkConnected(client.Store.ID.User)
+			s.logger.Infof("Device %s is already logged in as %s", deviceID, deviceClient.GetPhone())
 			break
 		} else if i == maxRetries-1 {
 			s.logger.Infof("Device %s loaded but not logged in, waiting for QR scan", deviceID)
 		}
 	}
 
-	// Store client
-	s.clients[deviceID] = deviceClient
-
-	return nil
+	return deviceClient, nil
 }
 
 // CreateSession creates a new WhatsApp session for a device
-func (s *WhatsAppService) CreateSession(deviceID string) (*DeviceClient, error) {
+func (s *WhatsAppService) CreateSession(deviceID string, opts SessionOptions) (*DeviceClient, error) {
+	// The device ID names the session directory, so it must stay inside SESSION_DIR
+	if err := validateDeviceID(deviceID); err != nil {
+		return nil, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -290,15 +447,31 @@ func (s *WhatsAppService) CreateSession(deviceID string) (*DeviceClient, error)
 		return nil, fmt.Errorf("failed to get device: %v", err)
 	}
 
-	// Create WhatsApp client
-	client := whatsmeow.NewClient(deviceStore, s.logger)
+	// Create WhatsApp client with a logger that keeps recent lines for this device
+	logs := newLogBuffer(logBufferSize())
+	client := whatsmeow.NewClient(deviceStore, newDeviceLogger(deviceID, s.logger, logs))
+	// Unexpected disconnects are retried with backoff by startAutoReconnect
+	client.EnableAutoReconnect = false
+
+	// Keep any settings left in the session directory, labelling the device if asked
+	config, err := loadDeviceConfig(deviceID)
+	if err != nil {
+		s.logger.Warnf("Failed to load config for device %s, using defaults: %v", deviceID, err)
+	}
+	if opts.Name != "" {
+		config.DisplayName = opts.Name
+		if err := saveDeviceConfig(deviceID, config); err != nil {
+			return nil, err
+		}
+	}
 
 	// Create device client
 	deviceClient := &DeviceClient{
 		Client:    client,
 		DeviceID:  deviceID,
-		QRChan:    make(chan string, 5),
-		Connected: false,
+		CreatedAt: time.Now(),
+		config:    config,
+		logs:      logs,
 	}
 
 	// Set up event handler
@@ -309,8 +482,10 @@ func (s *WhatsAppService) CreateSession(deviceID string) (*DeviceClient, error)
 
 	// Connect client
 	if client.Store.ID == nil {
-		// No session exists, need to pair with QR
-		go s.connectWithQR(deviceClient)
+		// No session exists, need to pair with QR unless a pairing code will be requested
+		if !opts.PairWithCode {
+			go s.connectWithQR(deviceClient)
+		}
 	} else {
 		// Session exists, try to reconnect
 		go s.reconnect(deviceClient)
@@ -344,10 +519,8 @@ func (s *WhatsAppService) reconnect(dc *DeviceClient) {
 
 	// Check if actually connected and logged in
 	if dc.Client.IsLoggedIn() && dc.Client.Store.ID != nil {
-		dc.Connected = true
-		dc.Phone = dc.Client.Store.ID.User
-		dc.ConnectedAt = time.Now()
-		s.logger.Infof("Device %s reconnected successfully as %s", dc.DeviceID, dc.Phone)
+		dc.MarkConnected(dc.Client.Store.ID.User)
+		s.logger.Infof("Device %s reconnected successfully as %s", dc.DeviceID, dc.GetPhone())
 	} else {
 		s.logger.Errorf("Device %s reconnected but not logged in", dc.DeviceID)
 	}
@@ -357,41 +530,108 @@ func (s *WhatsAppService) reconnect(dc *DeviceClient) {
 func (dc *DeviceClient) eventHandler(evt interface{}) {
 	switch v := evt.(type) {
 	case *events.QR:
-		// QR code event - send all codes to channel
-		for _, code := range v.Codes {
-			select {
-			case dc.QRChan <- code:
-				// Successfully sent QR code
-			default:
-				// Channel is full, clear old QR codes and try again
-				select {
-				case <-dc.QRChan:
-					dc.QRChan <- code
-				default:
-					// Still can't send, skip this QR code
-				}
-			}
+		dc.markPairingReady()
+		qrCodesGenerated.WithLabelValues(dc.DeviceID).Add(float64(len(v.Codes)))
+		dc.qr.set(v.Codes)
+		if waService != nil && len(v.Codes) > 0 {
+			waService.stream.publish(dc.DeviceID, StreamEventQR, newQRCodeEvent(v.Codes))
 		}
 
 	case *events.PairSuccess:
-		// QR code scanned successfully
+		// QR code scanned successfully; its codes are no longer valid
+		dc.qr.set(nil)
 		// Note: logger access will be fixed by making logger available to device client
 
 	case *events.Connected:
-		dc.Connected = true
-		dc.ConnectedAt = time.Now()
+		phone := ""
 		if dc.Client.Store.ID != nil {
-			dc.Phone = dc.Client.Store.ID.User
+			phone = dc.Client.Store.ID.User
+		}
+		dc.MarkConnected(phone)
+		dc.phone.setConnection(ConnectionOnline)
+		dc.markKeepAlive(time.Now())
+		go notifyLifecycle(dc, EventDeviceConnected, "connected", "")
+		if waService != nil {
+			waService.publishConnection(dc, "connected")
+		}
+		if contactSyncOnConnect() && waService != nil {
+			go waService.syncContactsOnConnect(dc)
 		}
 
 	case *events.Disconnected:
-		dc.Connected = false
+		dc.markDisconnected()
+		dc.phone.setConnection(ConnectionOffline)
+		go notifyLifecycle(dc, EventDeviceDisconnected, "disconnected", "")
+		if waService != nil {
+			waService.publishConnection(dc, "disconnected")
+			waService.startAutoReconnect(dc)
+		}
+
+	case *events.KeepAliveTimeout:
+		dc.phone.setConnection(ConnectionKeepAliveLost)
+		dc.markKeepAlive(v.LastSuccess)
+		if waService != nil && time.Since(v.LastSuccess) > whatsmeow.KeepAliveMaxFailTime {
+			go waService.dropDeadSocket(dc)
+		}
+
+	case *events.KeepAliveRestored:
+		dc.phone.setConnection(ConnectionOnline)
+		dc.markKeepAlive(time.Now())
+
+	case *events.StreamReplaced:
+		dc.phone.setConnection(ConnectionStreamReplaced)
+
+	case *events.LoggedOut:
+		dc.loggedOut.Store(true)
+		dc.markDisconnected()
+		dc.stopAutoReconnect()
+		dc.phone.setConnection(ConnectionLoggedOut)
+		go notifyLifecycle(dc, EventDeviceLoggedOut, "logged_out", v.Reason.String())
+		if waService != nil {
+			waService.publishConnection(dc, "logged_out")
+		}
+
+	case *events.JoinedGroup:
+		// Membership changed; the next group send re-fetches it
+		if waService != nil {
+			waService.membership.Delete(dc.DeviceID)
+		}
+
+	case *events.Receipt:
+		if waService != nil {
+			updated := waService.tracker.applyReceipt(dc.DeviceID, v)
+			go notifyReceipt(dc.DeviceID, v, updated)
+			go forwardReceipt(dc.DeviceID, v)
+			waService.scheduleReadRevokes(dc, updated)
+		}
+
+	case *events.Presence:
+		if waService != nil {
+			go waService.observePresence(dc, v)
+		}
+
+	case *events.HistorySync:
+		if waService != nil {
+			go waService.handleHistorySync(dc, v)
+		}
 
 	case *events.Message:
+		if waService != nil {
+			stored := storedFromEvent(dc.DeviceID, v)
+			waService.storeMessage(stored)
+			waService.stream.publish(dc.DeviceID, StreamEventMessage, stored)
+			waService.cacheMedia(dc.DeviceID, v)
+			waService.observeChatTimer(dc.DeviceID, v)
+			waService.observePoll(dc.DeviceID, v)
+		}
+
 		// Handle incoming message - send to webhook service
 		webhookSvc := GetWebhookService()
 		if webhookSvc != nil {
-			go webhookSvc.HandleIncomingMessage(dc.DeviceID, v)
+			go func() {
+				dc.acknowledgeIncoming(v)
+				webhookSvc.HandleIncomingMessage(dc.DeviceID, v)
+			}()
 		}
 		// Also call custom event handler if set
 		if dc.EventHandler != nil {
@@ -407,7 +647,7 @@ func (s *WhatsAppService) GetSession(deviceID string) (*DeviceClient, error) {
 
 	client, exists := s.clients[deviceID]
 	if !exists {
-		return nil, fmt.Errorf("session not found for device_id: %s", deviceID)
+		return nil, fmt.Errorf("%w for device_id: %s", ErrSessionNotFound, deviceID)
 	}
 
 	return client, nil
@@ -433,11 +673,11 @@ func (s *WhatsAppService) Logout(deviceID string) error {
 	s.mu.RUnlock()
 
 	if !exists {
-		return fmt.Errorf("session not found for device_id: %s", deviceID)
+		return fmt.Errorf("%w for device_id: %s", ErrSessionNotFound, deviceID)
 	}
 
 	client.Client.Disconnect()
-	client.Connected = false
+	client.markDisconnected()
 
 	return nil
 }
@@ -449,11 +689,14 @@ func (s *WhatsAppService) DeleteSession(deviceID string) error {
 
 	client, exists := s.clients[deviceID]
 	if !exists {
-		return fmt.Errorf("session not found for device_id: %s", deviceID)
+		return fmt.Errorf("%w for device_id: %s", ErrSessionNotFound, deviceID)
 	}
 
 	// Disconnect client
 	client.Client.Disconnect()
+	if client.queue != nil {
+		client.queue.close()
+	}
 
 	// Remove from map
 	delete(s.clients, deviceID)
@@ -470,38 +713,70 @@ func (s *WhatsAppService) DeleteSession(deviceID string) error {
 // ensureConnection ensures the client is properly connected, reconnects if necessary
 func (s *WhatsAppService) ensureConnection(client *DeviceClient) error {
 	// Check if client is actually connected (validate real connection status)
-	isActuallyConnected := client.Connected && client.Client.IsLoggedIn() && client.Client.Store.ID != nil
+	isActuallyConnected := client.IsConnected() && client.Client.IsLoggedIn() && client.Client.Store.ID != nil
 
 	if !isActuallyConnected {
 		// Check if client is logged in but not marked as connected
 		if client.Client.IsLoggedIn() && client.Client.Store.ID != nil {
 			// Try to reconnect
 			if err := client.Client.Connect(); err != nil {
-				return fmt.Errorf("session not connected and failed to reconnect: %v", err)
+				return fmt.Errorf("%w and failed to reconnect: %v", ErrSessionNotConnected, err)
 			}
 			// Wait a moment for connection to establish
 			time.Sleep(1 * time.Second)
 			// Verify reconnection was successful
 			if client.Client.IsLoggedIn() && client.Client.Store.ID != nil {
-				client.Connected = true
-				client.Phone = client.Client.Store.ID.User
-				client.ConnectedAt = time.Now()
+				client.MarkConnected(client.Client.Store.ID.User)
 				return nil
 			} else {
 				return fmt.Errorf("reconnected but client not logged in")
 			}
 		} else {
-			return fmt.Errorf("session not connected. Please scan QR code first")
+			return fmt.Errorf("%w. Please scan QR code first", ErrSessionNotConnected)
 		}
 	}
 	return nil
 }
 
+// SessionPendingError is returned when a send triggered automatic session
+// creation and the new session still has to be paired
+type SessionPendingError struct {
+	DeviceID string
+	QRURL    string
+}
+
+func (e *SessionPendingError) Error() string {
+	return fmt.Sprintf("session %s was created automatically and is waiting for QR scan at %s; the message was not sent", e.DeviceID, e.QRURL)
+}
+
+// autoCreateSession creates a missing session when AUTO_CREATE_SESSION is enabled.
+// It returns the original lookup error when auto-creation is disabled.
+func (s *WhatsAppService) autoCreateSession(deviceID string, lookupErr error) error {
+	if enabled, _ := strconv.ParseBool(os.Getenv("AUTO_CREATE_SESSION")); !enabled {
+		return lookupErr
+	}
+	// An ID that can't name a session directory never gets a session
+	if err := validateDeviceID(deviceID); err != nil {
+		return lookupErr
+	}
+
+	_, err := s.CreateSession(deviceID, SessionOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to auto-create session: %v", err)
+	}
+
+	s.logger.Infof("Auto-created session for device %s on first send", deviceID)
+	return &SessionPendingError{
+		DeviceID: deviceID,
+		QRURL:    fmt.Sprintf("/qr/%s", deviceID),
+	}
+}
+
 // SendMessage sends a text message to a phone number
-func (s *WhatsAppService) SendMessage(deviceID, phone, message string) (string, int64, error) {
+func (s *WhatsAppService) SendMessage(deviceID, phone, message string, opts SendOptions) (string, int64, error) {
 	client, err := s.GetSession(deviceID)
 	if err != nil {
-		return "", 0, err
+		return "", 0, s.autoCreateSession(deviceID, err)
 	}
 
 	// Ensure client is properly connected
@@ -509,15 +784,21 @@ func (s *WhatsAppService) SendMessage(deviceID, phone, message string) (string,
 		return "", 0, err
 	}
 
+	message, err = client.withFooter(message, opts, MaxMessageLength)
+	if err != nil {
+		return "", 0, err
+	}
+
 	// Parse JID
 	jid := types.NewJID(phone, types.DefaultUserServer)
 
 	// Send message
-	msg := &waProto.Message{
-		Conversation: &message,
+	msg, err := s.textMessage(deviceID, jid, message, opts)
+	if err != nil {
+		return "", 0, err
 	}
 
-	resp, err := client.Client.SendMessage(context.Background(), jid, msg)
+	resp, err := s.deliver(client, jid, msg, opts)
 	if err != nil {
 		return "", 0, fmt.Errorf("failed to send message: %v", err)
 	}
@@ -526,14 +807,19 @@ func (s *WhatsAppService) SendMessage(deviceID, phone, message string) (string,
 }
 
 // SendGroupMessage sends a text message to a group
-func (s *WhatsAppService) SendGroupMessage(deviceID, groupJID, message string) (string, int64, error) {
+func (s *WhatsAppService) SendGroupMessage(deviceID, groupJID, message string, opts SendOptions) (string, int64, error) {
 	client, err := s.GetSession(deviceID)
 	if err != nil {
 		return "", 0, err
 	}
 
-	if !client.Connected {
-		return "", 0, fmt.Errorf("session not connected. Please scan QR code first")
+	if !client.IsConnected() {
+		return "", 0, fmt.Errorf("%w. Please scan QR code first", ErrSessionNotConnected)
+	}
+
+	message, err = client.withFooter(message, opts, MaxMessageLength)
+	if err != nil {
+		return "", 0, err
 	}
 
 	// Parse group JID
@@ -541,13 +827,17 @@ func (s *WhatsAppService) SendGroupMessage(deviceID, groupJID, message string) (
 	if err != nil {
 		return "", 0, fmt.Errorf("invalid group JID: %v", err)
 	}
+	if err := s.ensureGroupMember(client, jid); err != nil {
+		return "", 0, err
+	}
 
 	// Send message
-	msg := &waProto.Message{
-		Conversation: &message,
+	msg, err := s.textMessage(deviceID, jid, message, opts)
+	if err != nil {
+		return "", 0, err
 	}
 
-	resp, err := client.Client.SendMessage(context.Background(), jid, msg)
+	resp, err := s.deliver(client, jid, msg, opts)
 	if err != nil {
 		return "", 0, fmt.Errorf("failed to send group message: %v", err)
 	}
@@ -556,95 +846,42 @@ func (s *WhatsAppService) SendGroupMessage(deviceID, groupJID, message string) (
 }
 
 // SendMediaMessage sends a media message to a phone number
-func (s *WhatsAppService) SendMediaMessage(deviceID, phone, filePath, caption string) (string, string, int64, error) {
+func (s *WhatsAppService) SendMediaMessage(deviceID, phone, filePath, caption string, opts SendOptions) (string, string, int64, error) {
 	client, err := s.GetSession(deviceID)
 	if err != nil {
 		return "", "", 0, err
 	}
 
-	if !client.Connected {
-		return "", "", 0, fmt.Errorf("session not connected. Please scan QR code first")
+	if !client.IsConnected() {
+		return "", "", 0, fmt.Errorf("%w. Please scan QR code first", ErrSessionNotConnected)
 	}
 
-	// Read file
-	fileData, err := os.ReadFile(filePath)
+	caption, err = client.withFooter(caption, opts, MaxCaptionLength)
 	if err != nil {
-		return "", "", 0, fmt.Errorf("failed to read file: %v", err)
+		return "", "", 0, err
 	}
 
-	// Upload media
-	uploaded, err := client.Client.Upload(context.Background(), fileData, whatsmeow.MediaImage)
+	// Voice notes are transcoded to OGG/Opus when a converter is configured
+	voiceNote := s.prepareVoiceNote(filePath, opts)
+	if voiceNote != nil && voiceNote.Path != "" {
+		defer utils.DeleteFile(voiceNote.Path)
+	}
+
+	// Upload media, streaming it from disk
+	uploaded, fileLen, err := uploadMediaFile(client.Client, mediaPath(filePath, voiceNote), getUploadMediaType(filepath.Ext(filePath)))
 	if err != nil {
-		return "", "", 0, fmt.Errorf("failed to upload media: %v", err)
+		return "", "", 0, err
 	}
 
 	// Parse JID
 	jid := types.NewJID(phone, types.DefaultUserServer)
 
 	// Determine media type and create message
-	var msg *waProto.Message
 	ext := filepath.Ext(filePath)
-	mimetype := getMimeType(ext)
-	fileLen := uint64(len(fileData))
-
-	switch {
-	case isImageExt(ext):
-		msg = &waProto.Message{
-			ImageMessage: &waProto.ImageMessage{
-				Caption:       proto.String(caption),
-				URL:           proto.String(uploaded.URL),
-				DirectPath:    proto.String(uploaded.DirectPath),
-				MediaKey:      uploaded.MediaKey,
-				Mimetype:      proto.String(mimetype),
-				FileEncSHA256: uploaded.FileEncSHA256,
-				FileSHA256:    uploaded.FileSHA256,
-				FileLength:    proto.Uint64(fileLen),
-			},
-		}
-	case isVideoExt(ext):
-		msg = &waProto.Message{
-			VideoMessage: &waProto.VideoMessage{
-				Caption:       proto.String(caption),
-				URL:           proto.String(uploaded.URL),
-				DirectPath:    proto.String(uploaded.DirectPath),
-				MediaKey:      uploaded.MediaKey,
-				Mimetype:      proto.String(mimetype),
-				FileEncSHA256: uploaded.FileEncSHA256,
-				FileSHA256:    uploaded.FileSHA256,
-				FileLength:    proto.Uint64(fileLen),
-			},
-		}
-	case isAudioExt(ext):
-		msg = &waProto.Message{
-			AudioMessage: &waProto.AudioMessage{
-				URL:           proto.String(uploaded.URL),
-				DirectPath:    proto.String(uploaded.DirectPath),
-				MediaKey:      uploaded.MediaKey,
-				Mimetype:      proto.String(mimetype),
-				FileEncSHA256: uploaded.FileEncSHA256,
-				FileSHA256:    uploaded.FileSHA256,
-				FileLength:    proto.Uint64(fileLen),
-			},
-		}
-	default:
-		// Document
-		msg = &waProto.Message{
-			DocumentMessage: &waProto.DocumentMessage{
-				Caption:       proto.String(caption),
-				URL:           proto.String(uploaded.URL),
-				DirectPath:    proto.String(uploaded.DirectPath),
-				MediaKey:      uploaded.MediaKey,
-				Mimetype:      proto.String(mimetype),
-				FileEncSHA256: uploaded.FileEncSHA256,
-				FileSHA256:    uploaded.FileSHA256,
-				FileLength:    proto.Uint64(fileLen),
-				FileName:      proto.String(filepath.Base(filePath)),
-			},
-		}
-	}
+	msg := buildMediaMessage(uploaded, filePath, caption, fileLen, voiceNote, opts)
 
 	// Send message
-	resp, err := client.Client.SendMessage(context.Background(), jid, msg)
+	resp, err := s.deliver(client, jid, msg, opts)
 	if err != nil {
 		return "", "", 0, fmt.Errorf("failed to send media: %v", err)
 	}
@@ -654,43 +891,162 @@ func (s *WhatsAppService) SendMediaMessage(deviceID, phone, filePath, caption st
 }
 
 // SendGroupMediaMessage sends a media message to a group
-func (s *WhatsAppService) SendGroupMediaMessage(deviceID, groupJID, filePath, caption string) (string, string, int64, error) {
+func (s *WhatsAppService) SendGroupMediaMessage(deviceID, groupJID, filePath, caption string, opts SendOptions) (string, string, int64, error) {
 	client, err := s.GetSession(deviceID)
 	if err != nil {
 		return "", "", 0, err
 	}
 
-	if !client.Connected {
-		return "", "", 0, fmt.Errorf("session not connected. Please scan QR code first")
+	if !client.IsConnected() {
+		return "", "", 0, fmt.Errorf("%w. Please scan QR code first", ErrSessionNotConnected)
 	}
 
-	// Read file
-	fileData, err := os.ReadFile(filePath)
+	caption, err = client.withFooter(caption, opts, MaxCaptionLength)
 	if err != nil {
-		return "", "", 0, fmt.Errorf("failed to read file: %v", err)
+		return "", "", 0, err
 	}
 
-	// Upload media
-	uploaded, err := client.Client.Upload(context.Background(), fileData, whatsmeow.MediaImage)
+	// Parse group JID, checking membership before spending an upload
+	jid, err := types.ParseJID(groupJID)
 	if err != nil {
-		return "", "", 0, fmt.Errorf("failed to upload media: %v", err)
+		return "", "", 0, fmt.Errorf("invalid group JID: %v", err)
+	}
+	if err := s.ensureGroupMember(client, jid); err != nil {
+		return "", "", 0, err
 	}
 
-	// Parse group JID
-	jid, err := types.ParseJID(groupJID)
+	// Voice notes are transcoded to OGG/Opus when a converter is configured
+	voiceNote := s.prepareVoiceNote(filePath, opts)
+	if voiceNote != nil && voiceNote.Path != "" {
+		defer utils.DeleteFile(voiceNote.Path)
+	}
+
+	// Upload media, streaming it from disk
+	uploaded, fileLen, err := uploadMediaFile(client.Client, mediaPath(filePath, voiceNote), getUploadMediaType(filepath.Ext(filePath)))
 	if err != nil {
-		return "", "", 0, fmt.Errorf("invalid group JID: %v", err)
+		return "", "", 0, err
 	}
 
 	// Determine media type and create message
-	var msg *waProto.Message
+	ext := filepath.Ext(filePath)
+	msg := buildMediaMessage(uploaded, filePath, caption, fileLen, voiceNote, opts)
+
+	// Send message
+	resp, err := s.deliver(client, jid, msg, opts)
+	if err != nil {
+		return "", "", 0, fmt.Errorf("failed to send group media: %v", err)
+	}
+
+	mediaType := getMediaTypeString(ext)
+	return resp.ID, mediaType, int64(fileLen), nil
+}
+
+// withFooter appends the device footer, or the per-request override, to text
+// and checks the result against the given length limit
+func (dc *DeviceClient) withFooter(text string, opts SendOptions, limit int) (string, error) {
+	footer := dc.GetConfig().Footer
+	if opts.Footer != nil {
+		footer = *opts.Footer
+	}
+
+	if footer != "" {
+		if text != "" {
+			text += "\n\n"
+		}
+		text += footer
+	}
+
+	if utf8.RuneCountInString(text) > limit {
+		return "", fmt.Errorf("message exceeds the maximum length of %d characters", limit)
+	}
+
+	return text, nil
+}
+
+// prepareVoiceNote transcodes audio to OGG/Opus for push-to-talk sends when
+// FFMPEG_PATH is configured. Without it, OGG/Opus files keep their own data and
+// only get a duration; a voice note with an empty Path means the original file.
+// It returns nil when the file should be sent as-is.
+func (s *WhatsAppService) prepareVoiceNote(filePath string, opts SendOptions) *utils.VoiceNote {
+	if !opts.PTT || !isAudioExt(filepath.Ext(filePath)) {
+		return nil
+	}
+
+	ffmpegPath := os.Getenv("FFMPEG_PATH")
+	if ffmpegPath == "" {
+		if !isOpusExt(filepath.Ext(filePath)) {
+			s.logger.Warnf("Sending %s as a voice note without conversion; only OGG/Opus plays as a voice note everywhere, set FFMPEG_PATH to convert", filepath.Base(filePath))
+			return nil
+		}
+		seconds, err := utils.OggOpusDuration(filePath)
+		if err != nil {
+			s.logger.Warnf("Sending %s as a voice note without a duration: %v", filepath.Base(filePath), err)
+			return nil
+		}
+		return &utils.VoiceNote{Seconds: seconds}
+	}
+
+	voiceNote, err := utils.ConvertToVoiceNote(ffmpegPath, filePath)
+	if err != nil {
+		s.logger.Warnf("Failed to convert %s to a voice note, sending as-is: %v", filepath.Base(filePath), err)
+		return nil
+	}
+
+	return voiceNote
+}
+
+// mediaPath returns the file that should actually be uploaded
+func mediaPath(filePath string, voiceNote *utils.VoiceNote) string {
+	if voiceNote != nil && voiceNote.Path != "" {
+		return voiceNote.Path
+	}
+	return filePath
+}
+
+// mediaUploader streams media to WhatsApp; *whatsmeow.Client implements it
+type mediaUploader interface {
+	UploadReader(ctx context.Context, plaintext io.Reader, tempFile io.ReadWriteSeeker, appInfo whatsmeow.MediaType) (whatsmeow.UploadResponse, error)
+}
+
+// uploadMediaFile encrypts and uploads a file without loading it into memory.
+// The encrypted copy is staged in TEMP_MEDIA_DIR so the janitor and disk checks cover it.
+func uploadMediaFile(uploader mediaUploader, path string, mediaType whatsmeow.MediaType) (whatsmeow.UploadResponse, uint64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return whatsmeow.UploadResponse{}, 0, fmt.Errorf("failed to read file: %v", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return whatsmeow.UploadResponse{}, 0, fmt.Errorf("failed to read file: %v", err)
+	}
+
+	encrypted, err := os.CreateTemp(utils.TempMediaDir(), "upload-*.enc")
+	if err != nil {
+		return whatsmeow.UploadResponse{}, 0, fmt.Errorf("failed to create upload buffer: %v", err)
+	}
+	defer func() {
+		encrypted.Close()
+		os.Remove(encrypted.Name())
+	}()
+
+	uploaded, err := uploader.UploadReader(context.Background(), file, encrypted, mediaType)
+	if err != nil {
+		return whatsmeow.UploadResponse{}, 0, fmt.Errorf("failed to upload media: %v", err)
+	}
+
+	return uploaded, uint64(info.Size()), nil
+}
+
+// buildMediaMessage creates the message matching the file's media type
+func buildMediaMessage(uploaded whatsmeow.UploadResponse, filePath, caption string, fileLen uint64, voiceNote *utils.VoiceNote, opts SendOptions) *waProto.Message {
 	ext := filepath.Ext(filePath)
 	mimetype := getMimeType(ext)
-	fileLen := uint64(len(fileData))
 
 	switch {
 	case isImageExt(ext):
-		msg = &waProto.Message{
+		return &waProto.Message{
 			ImageMessage: &waProto.ImageMessage{
 				Caption:       proto.String(caption),
 				URL:           proto.String(uploaded.URL),
@@ -703,7 +1059,7 @@ func (s *WhatsAppService) SendGroupMediaMessage(deviceID, groupJID, filePath, ca
 			},
 		}
 	case isVideoExt(ext):
-		msg = &waProto.Message{
+		return &waProto.Message{
 			VideoMessage: &waProto.VideoMessage{
 				Caption:       proto.String(caption),
 				URL:           proto.String(uploaded.URL),
@@ -715,8 +1071,30 @@ func (s *WhatsAppService) SendGroupMediaMessage(deviceID, groupJID, filePath, ca
 				FileLength:    proto.Uint64(fileLen),
 			},
 		}
+	case isAudioExt(ext):
+		audio := &waProto.AudioMessage{
+			URL:           proto.String(uploaded.URL),
+			DirectPath:    proto.String(uploaded.DirectPath),
+			MediaKey:      uploaded.MediaKey,
+			Mimetype:      proto.String(mimetype),
+			FileEncSHA256: uploaded.FileEncSHA256,
+			FileSHA256:    uploaded.FileSHA256,
+			FileLength:    proto.Uint64(fileLen),
+		}
+		if opts.PTT {
+			audio.PTT = proto.Bool(true)
+		}
+		if voiceNote != nil {
+			audio.Mimetype = proto.String("audio/ogg; codecs=opus")
+			audio.Seconds = proto.Uint32(voiceNote.Seconds)
+			if len(voiceNote.Waveform) > 0 {
+				audio.Waveform = voiceNote.Waveform
+			}
+		}
+		return &waProto.Message{AudioMessage: audio}
 	default:
-		msg = &waProto.Message{
+		// Document
+		return &waProto.Message{
 			DocumentMessage: &waProto.DocumentMessage{
 				Caption:       proto.String(caption),
 				URL:           proto.String(uploaded.URL),
@@ -726,19 +1104,10 @@ func (s *WhatsAppService) SendGroupMediaMessage(deviceID, groupJID, filePath, ca
 				FileEncSHA256: uploaded.FileEncSHA256,
 				FileSHA256:    uploaded.FileSHA256,
 				FileLength:    proto.Uint64(fileLen),
-				FileName:      proto.String(filepath.Base(filePath)),
+				FileName:      proto.String(utils.OriginalFilename(filePath)),
 			},
 		}
 	}
-
-	// Send message
-	resp, err := client.Client.SendMessage(context.Background(), jid, msg)
-	if err != nil {
-		return "", "", 0, fmt.Errorf("failed to send group media: %v", err)
-	}
-
-	mediaType := getMediaTypeString(ext)
-	return resp.ID, mediaType, int64(fileLen), nil
 }
 
 // GetContacts retrieves the contact list for a device
@@ -748,8 +1117,8 @@ func (s *WhatsAppService) GetContacts(deviceID string) ([]map[string]interface{}
 		return nil, err
 	}
 
-	if !client.Connected {
-		return nil, fmt.Errorf("session not connected. Please scan QR code first")
+	if !client.IsConnected() {
+		return nil, fmt.Errorf("%w. Please scan QR code first", ErrSessionNotConnected)
 	}
 
 	// Get contacts from store
@@ -772,41 +1141,20 @@ func (s *WhatsAppService) GetContacts(deviceID string) ([]map[string]interface{}
 	return result, nil
 }
 
-// GetGroups retrieves the group list for a device
-func (s *WhatsAppService) GetGroups(deviceID string) ([]map[string]interface{}, error) {
+// GetGroups retrieves the group list for a device, with each group's
+// participants when includeParticipants is set. The group query is abandoned
+// when ctx is done.
+func (s *WhatsAppService) GetGroups(ctx context.Context, deviceID string, limit, offset int, includeParticipants bool) (*GroupList, error) {
 	client, err := s.GetSession(deviceID)
 	if err != nil {
 		return nil, err
 	}
 
-	if !client.Connected {
-		return nil, fmt.Errorf("session not connected. Please scan QR code first")
+	if !client.IsConnected() {
+		return nil, fmt.Errorf("%w. Please scan QR code first", ErrSessionNotConnected)
 	}
 
-	// Get groups
-	groups, err := client.Client.GetJoinedGroups(context.Background())
-	if err != nil {
-		return nil, fmt.Errorf("failed to get groups: %v", err)
-	}
-
-	// Format groups
-	result := make([]map[string]interface{}, 0)
-	for _, group := range groups {
-		// Get group info
-		groupInfo, err := client.Client.GetGroupInfo(group.JID)
-		if err != nil {
-			continue
-		}
-
-		result = append(result, map[string]interface{}{
-			"jid":          group.JID.String(),
-			"name":         groupInfo.Name,
-			"participants": len(groupInfo.Participants),
-			"is_admin":     isGroupAdmin(client.Client.Store.ID.ToNonAD(), groupInfo),
-		})
-	}
-
-	return result, nil
+	return s.listGroups(ctx, client.Client, deviceID, client.Client.Store.ID.ToNonAD(), limit, offset, includeParticipants)
 }
 
 // Helper functions
@@ -832,7 +1180,7 @@ func isVideoExt(ext string) bool {
 }
 
 func isAudioExt(ext string) bool {
-	audioExts := []string{".mp3", ".ogg", ".m4a"}
+	audioExts := []string{".mp3", ".ogg", ".opus", ".m4a"}
 	for _, e := range audioExts {
 		if strings.ToLower(ext) == e {
 			return true
@@ -841,6 +1189,22 @@ func isAudioExt(ext string) bool {
 	return false
 }
 
+// isOpusExt reports whether an audio extension is normally OGG/Opus, the voice note format
+func isOpusExt(ext string) bool {
+	ext = strings.ToLower(ext)
+	return ext == ".ogg" || ext == ".opus"
+}
+
+// VoiceNoteWarning explains why an audio file sent with ptt may not show up
+// as a voice note, or returns "" when it will
+func VoiceNoteWarning(fileName string, ptt bool) string {
+	ext := filepath.Ext(fileName)
+	if !ptt || !isAudioExt(ext) || isOpusExt(ext) || os.Getenv("FFMPEG_PATH") != "" {
+		return ""
+	}
+	return fmt.Sprintf("%s audio is sent without conversion and may not play as a voice note; upload OGG/Opus or set FFMPEG_PATH", strings.TrimPrefix(strings.ToLower(ext), "."))
+}
+
 func getMediaTypeString(ext string) string {
 	switch {
 	case isImageExt(ext):
@@ -854,6 +1218,21 @@ func getMediaTypeString(ext string) string {
 	}
 }
 
+// getUploadMediaType returns the media type WhatsApp derives the encryption keys from;
+// it must match the message struct buildMediaMessage picks for the same extension
+func getUploadMediaType(ext string) whatsmeow.MediaType {
+	switch {
+	case isImageExt(ext):
+		return whatsmeow.MediaImage
+	case isVideoExt(ext):
+		return whatsmeow.MediaVideo
+	case isAudioExt(ext):
+		return whatsmeow.MediaAudio
+	default:
+		return whatsmeow.MediaDocument
+	}
+}
+
 func isGroupAdmin(userJID types.JID, groupInfo *types.GroupInfo) bool {
 	for _, participant := range groupInfo.Participants {
 		if participant.JID.User == userJID.User {
@@ -874,6 +1253,7 @@ func getMimeType(ext string) string {
 		".mkv":  "video/x-matroska",
 		".mp3":  "audio/mpeg",
 		".ogg":  "audio/ogg",
+		".opus": "audio/ogg",
 		".m4a":  "audio/mp4",
 		".pdf":  "application/pdf",
 		".doc":  "application/msword",
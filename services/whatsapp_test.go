@@ -0,0 +1,118 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.mau.fi/whatsmeow"
+	waProto "go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/util/cbcutil"
+)
+
+// mediaFields are the upload fields every media message must carry
+type mediaFields interface {
+	GetURL() string
+	GetDirectPath() string
+	GetMediaKey() []byte
+	GetFileEncSHA256() []byte
+	GetFileSHA256() []byte
+	GetFileLength() uint64
+}
+
+func TestMediaUploadTypeMatchesMessage(t *testing.T) {
+	uploaded := whatsmeow.UploadResponse{
+		URL:           "https://mmg.whatsapp.net/v/t62/file",
+		DirectPath:    "/v/t62/file",
+		MediaKey:      []byte("media-key"),
+		FileEncSHA256: []byte("enc-sha"),
+		FileSHA256:    []byte("sha"),
+	}
+
+	tests := []struct {
+		file      string
+		mediaType whatsmeow.MediaType
+		message   func(*waProto.Message) mediaFields
+	}{
+		{"photo.JPG", whatsmeow.MediaImage, func(m *waProto.Message) mediaFields { return m.GetImageMessage() }},
+		{"clip.mp4", whatsmeow.MediaVideo, func(m *waProto.Message) mediaFields { return m.GetVideoMessage() }},
+		{"note.mp3", whatsmeow.MediaAudio, func(m *waProto.Message) mediaFields { return m.GetAudioMessage() }},
+		{"report.pdf", whatsmeow.MediaDocument, func(m *waProto.Message) mediaFields { return m.GetDocumentMessage() }},
+		{"archive", whatsmeow.MediaDocument, func(m *waProto.Message) mediaFields { return m.GetDocumentMessage() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			if got := getUploadMediaType(filepath.Ext(tt.file)); got != tt.mediaType {
+				t.Fatalf("upload type = %q, want %q", got, tt.mediaType)
+			}
+
+			fields := tt.message(buildMediaMessage(uploaded, tt.file, "caption", 1234, nil, SendOptions{}))
+			if fields == nil {
+				t.Fatalf("message for %s isn't the %s struct", tt.file, tt.mediaType)
+			}
+			if fields.GetURL() != uploaded.URL || fields.GetDirectPath() != uploaded.DirectPath || fields.GetFileLength() != 1234 ||
+				!bytes.Equal(fields.GetMediaKey(), uploaded.MediaKey) ||
+				!bytes.Equal(fields.GetFileEncSHA256(), uploaded.FileEncSHA256) ||
+				!bytes.Equal(fields.GetFileSHA256(), uploaded.FileSHA256) {
+				t.Fatalf("upload fields not wired into the message: %v", fields)
+			}
+		})
+	}
+}
+
+// encryptingUploader runs whatsmeow's streaming encryption and reads the
+// encrypted copy back the way an upload would, without a WhatsApp connection
+type encryptingUploader struct{}
+
+func (encryptingUploader) UploadReader(ctx context.Context, plaintext io.Reader, tempFile io.ReadWriteSeeker, appInfo whatsmeow.MediaType) (whatsmeow.UploadResponse, error) {
+	var resp whatsmeow.UploadResponse
+	key := make([]byte, 32)
+	var err error
+	resp.FileSHA256, resp.FileEncSHA256, resp.FileLength, _, err = cbcutil.EncryptStream(key, make([]byte, 16), key, plaintext, tempFile)
+	if err != nil {
+		return resp, err
+	}
+	if _, err := tempFile.Seek(0, io.SeekStart); err != nil {
+		return resp, err
+	}
+	_, err = io.Copy(io.Discard, tempFile)
+	return resp, err
+}
+
+// BenchmarkUploadMediaFile reports allocations per upload, which should stay
+// flat as the file grows since uploads stream from disk
+func BenchmarkUploadMediaFile(b *testing.B) {
+	dir := b.TempDir()
+	b.Setenv("TEMP_MEDIA_DIR", dir)
+
+	for _, size := range []int64{1 << 20, 16 << 20, 64 << 20} {
+		path := filepath.Join(dir, fmt.Sprintf("document-%d.pdf", size))
+		file, err := os.Create(path)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.CopyN(file, rand.Reader, size); err != nil {
+			b.Fatal(err)
+		}
+		file.Close()
+
+		b.Run(fmt.Sprintf("%dMB", size>>20), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(size)
+			for i := 0; i < b.N; i++ {
+				uploaded, fileLen, err := uploadMediaFile(encryptingUploader{}, path, whatsmeow.MediaDocument)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if fileLen != uint64(size) || uploaded.FileLength != uint64(size) {
+					b.Fatalf("uploaded %d of %d bytes", uploaded.FileLength, size)
+				}
+			}
+		})
+	}
+}
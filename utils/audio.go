@@ -0,0 +1,197 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// VoiceNote holds a transcoded voice note and its playback metadata
+type VoiceNote struct {
+	Path     string
+	Seconds  uint32
+	Waveform []byte
+}
+
+const (
+	// voiceNoteSampleRate is the PCM rate used when analysing audio for duration and waveform
+	voiceNoteSampleRate = 8000
+	// voiceNoteWaveformBars is the number of waveform samples WhatsApp renders for a voice note
+	voiceNoteWaveformBars = 64
+	// voiceNoteTimeout bounds every ffmpeg invocation
+	voiceNoteTimeout = 2 * time.Minute
+)
+
+// ConvertToVoiceNote transcodes an audio file to OGG/Opus using ffmpeg and
+// computes its duration and waveform. The caller owns the returned file.
+func ConvertToVoiceNote(ffmpegPath, srcPath string) (*VoiceNote, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), voiceNoteTimeout)
+	defer cancel()
+
+	destPath := strings.TrimSuffix(srcPath, filepath.Ext(srcPath)) + ".voice.ogg"
+
+	// Transcode to mono Opus, which every WhatsApp client plays as a voice note
+	cmd := exec.CommandContext(ctx, ffmpegPath,
+		"-y", "-i", srcPath,
+		"-vn", "-ac", "1", "-ar", "48000",
+		"-c:a", "libopus", "-b:a", "32k", "-application", "voip",
+		destPath,
+	)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		_ = DeleteFile(destPath)
+		return nil, fmt.Errorf("failed to convert audio: %v: %s", err, lastLine(output))
+	}
+
+	seconds, waveform, err := AnalyzeAudio(ffmpegPath, destPath)
+	if err != nil {
+		_ = DeleteFile(destPath)
+		return nil, err
+	}
+
+	return &VoiceNote{
+		Path:     destPath,
+		Seconds:  seconds,
+		Waveform: waveform,
+	}, nil
+}
+
+// AnalyzeAudio decodes an audio file with ffmpeg and returns its duration in
+// seconds and a 64-bar waveform with values in the range 0-100
+func AnalyzeAudio(ffmpegPath, filePath string) (uint32, []byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), voiceNoteTimeout)
+	defer cancel()
+
+	var pcm, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, ffmpegPath,
+		"-i", filePath,
+		"-f", "s16le", "-ac", "1", "-ar", fmt.Sprint(voiceNoteSampleRate),
+		"pipe:1",
+	)
+	cmd.Stdout = &pcm
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return 0, nil, fmt.Errorf("failed to decode audio: %v: %s", err, lastLine(stderr.Bytes()))
+	}
+
+	samples := make([]int16, pcm.Len()/2)
+	if err := binary.Read(bytes.NewReader(pcm.Bytes()[:len(samples)*2]), binary.LittleEndian, samples); err != nil {
+		return 0, nil, fmt.Errorf("failed to read audio samples: %v", err)
+	}
+
+	seconds := uint32((len(samples) + voiceNoteSampleRate - 1) / voiceNoteSampleRate)
+	return seconds, computeWaveform(samples), nil
+}
+
+// computeWaveform averages the absolute amplitude of each bar and scales the
+// loudest bar to 100
+func computeWaveform(samples []int16) []byte {
+	waveform := make([]byte, voiceNoteWaveformBars)
+	if len(samples) == 0 {
+		return waveform
+	}
+
+	bars := make([]float64, voiceNoteWaveformBars)
+	var peak float64
+	for i := range bars {
+		start := i * len(samples) / voiceNoteWaveformBars
+		end := (i + 1) * len(samples) / voiceNoteWaveformBars
+		if end <= start {
+			continue
+		}
+
+		var sum float64
+		for _, s := range samples[start:end] {
+			if s < 0 {
+				sum -= float64(s)
+			} else {
+				sum += float64(s)
+			}
+		}
+		bars[i] = sum / float64(end-start)
+		if bars[i] > peak {
+			peak = bars[i]
+		}
+	}
+
+	if peak == 0 {
+		return waveform
+	}
+	for i, bar := range bars {
+		waveform[i] = byte(bar / peak * 100)
+	}
+	return waveform
+}
+
+// opusGranuleRate is the sample rate Ogg/Opus granule positions are counted in
+const opusGranuleRate = 48000
+
+// OggOpusDuration reads the duration of an Ogg/Opus file from its page headers,
+// without decoding it. It fails for Ogg files carrying another codec.
+func OggOpusDuration(filePath string) (uint32, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open audio: %v", err)
+	}
+	defer file.Close()
+
+	var header [27]byte
+	var preSkip uint16
+	granule := int64(-1)
+	for page := 0; ; page++ {
+		if _, err := io.ReadFull(file, header[:]); err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, fmt.Errorf("failed to read Ogg page: %v", err)
+		}
+		if string(header[:4]) != "OggS" {
+			return 0, fmt.Errorf("not an Ogg file")
+		}
+
+		segments := make([]byte, header[26])
+		if _, err := io.ReadFull(file, segments); err != nil {
+			return 0, fmt.Errorf("failed to read Ogg page: %v", err)
+		}
+		var size int64
+		for _, segment := range segments {
+			size += int64(segment)
+		}
+
+		// The first page holds the codec header, which names the codec and its pre-skip
+		if page == 0 {
+			body := make([]byte, size)
+			if _, err := io.ReadFull(file, body); err != nil {
+				return 0, fmt.Errorf("failed to read Ogg page: %v", err)
+			}
+			if len(body) < 12 || !bytes.HasPrefix(body, []byte("OpusHead")) {
+				return 0, fmt.Errorf("Ogg file is not Opus encoded")
+			}
+			preSkip = binary.LittleEndian.Uint16(body[10:12])
+		} else if _, err := file.Seek(size, io.SeekCurrent); err != nil {
+			return 0, fmt.Errorf("failed to read Ogg page: %v", err)
+		}
+
+		// -1 marks pages on which no packet ends
+		if position := int64(binary.LittleEndian.Uint64(header[6:14])); position >= 0 {
+			granule = position
+		}
+	}
+
+	samples := granule - int64(preSkip)
+	if samples <= 0 {
+		return 0, fmt.Errorf("Ogg file has no audio")
+	}
+	return uint32((samples + opusGranuleRate - 1) / opusGranuleRate), nil
+}
+
+// lastLine returns the last non-empty line of command output for error messages
+func lastLine(output []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
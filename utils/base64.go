@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidBase64Upload is returned when a base64 upload is malformed or too large
+var ErrInvalidBase64Upload = errors.New("invalid base64 upload")
+
+// MaxBase64BodySize bounds a JSON body carrying base64 media: the largest media
+// limit with base64's 4/3 overhead, plus room for the other fields
+func MaxBase64BodySize() int64 {
+	var largest int64
+	for _, limit := range MediaLimits {
+		largest = max(largest, limit)
+	}
+	return largest/3*4 + 64*1024
+}
+
+// StripDataURI returns the base64 payload of a data URI, or data unchanged when it isn't one
+func StripDataURI(data string) string {
+	if !strings.HasPrefix(data, "data:") {
+		return data
+	}
+	if _, payload, ok := strings.Cut(data, ";base64,"); ok {
+		return payload
+	}
+	return data
+}
+
+// Base64DecodedSize estimates the decoded size of a base64 payload without decoding it
+func Base64DecodedSize(payload string) int64 {
+	size := int64(base64.StdEncoding.DecodedLen(len(payload)))
+	return size - int64(strings.Count(payload[max(0, len(payload)-2):], "="))
+}
+
+// SaveBase64File decodes a base64 payload, optionally a data URI, into destDir
+// under a unique, sanitized form of filename. The size limit for the file's media type is checked before
+// decoding.
+func SaveBase64File(filename, data, destDir string) (string, error) {
+	name, err := uniqueFilename(filename)
+	if err != nil {
+		return "", err
+	}
+
+	payload := StripDataURI(strings.TrimSpace(data))
+	if payload == "" {
+		return "", fmt.Errorf("%w: data is empty", ErrInvalidBase64Upload)
+	}
+	size := Base64DecodedSize(payload)
+	if err := ValidateSize(name, size); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidBase64Upload, err)
+	}
+	if err := EnsureDiskSpace(destDir, size); err != nil {
+		return "", err
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidBase64Upload, err)
+	}
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	destPath := filepath.Join(destDir, name)
+	if err := os.WriteFile(destPath, decoded, 0644); err != nil {
+		os.Remove(destPath)
+		if isNoSpace(err) {
+			return "", fmt.Errorf("%w: %v", ErrInsufficientStorage, err)
+		}
+		return "", fmt.Errorf("failed to save file: %v", err)
+	}
+
+	return destPath, nil
+}
@@ -0,0 +1,66 @@
+package utils
+
+import "net/http"
+
+// Machine-readable error codes returned in the "code" field of error responses
+const (
+	CodeInvalidRequest      = "INVALID_REQUEST"
+	CodeUnauthorized        = "UNAUTHORIZED"
+	CodeForbidden           = "FORBIDDEN"
+	CodeNotFound            = "NOT_FOUND"
+	CodeConflict            = "CONFLICT"
+	CodeUnprocessable       = "UNPROCESSABLE"
+	CodeRateLimited         = "RATE_LIMITED"
+	CodeInternal            = "INTERNAL_ERROR"
+	CodeNotSupported        = "NOT_SUPPORTED"
+	CodeUnavailable         = "SERVICE_UNAVAILABLE"
+	CodeInsufficientStorage = "INSUFFICIENT_STORAGE"
+
+	CodeSessionNotFound     = "SESSION_NOT_FOUND"
+	CodeSessionNotConnected = "SESSION_NOT_CONNECTED"
+	CodeSessionPending      = "SESSION_PENDING"
+	CodeSessionExists       = "SESSION_EXISTS"
+	CodeAlreadyPaired       = "ALREADY_PAIRED"
+	CodeRecipientNotFound   = "RECIPIENT_NOT_FOUND"
+	CodeMessageRejected     = "MESSAGE_REJECTED"
+	CodeStoreDisabled       = "MESSAGE_STORE_DISABLED"
+	CodeNotGroupMember      = "NOT_GROUP_MEMBER"
+	CodeMediaNotFound       = "MEDIA_NOT_FOUND"
+	CodeEditWindowExpired   = "EDIT_WINDOW_EXPIRED"
+	CodeMessageNotFound     = "MESSAGE_NOT_FOUND"
+	CodeRevokeNotPermitted  = "REVOKE_NOT_PERMITTED"
+	CodeReconnectTimeout    = "RECONNECT_TIMEOUT"
+	CodeNotGroupAdmin       = "NOT_GROUP_ADMIN"
+	CodeGroupNotFound       = "GROUP_NOT_FOUND"
+
+	CodeProfilePictureNotSet     = "PROFILE_PICTURE_NOT_SET"
+	CodeProfilePictureRestricted = "PROFILE_PICTURE_RESTRICTED"
+)
+
+// CodeForStatus returns the generic error code for an HTTP status
+func CodeForStatus(statusCode int) string {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return CodeInvalidRequest
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusUnprocessableEntity:
+		return CodeUnprocessable
+	case http.StatusTooManyRequests:
+		return CodeRateLimited
+	case http.StatusNotImplemented:
+		return CodeNotSupported
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	case http.StatusInsufficientStorage:
+		return CodeInsufficientStorage
+	default:
+		return CodeInternal
+	}
+}
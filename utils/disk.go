@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	waLog "go.mau.fi/whatsmeow/util/log"
+)
+
+// ErrInsufficientStorage is returned when the temp directory has no room for an upload
+var ErrInsufficientStorage = errors.New("insufficient storage for upload")
+
+// diskHeadroom is kept free beyond the upload itself for conversions and other writers
+const diskHeadroom = 10 << 20
+
+// defaultTempFileMaxAge is how old a temp file must be before the janitor removes it
+const defaultTempFileMaxAge = time.Hour
+
+// defaultDownloadFileMaxAge is how long downloaded inbound media is kept by default
+const defaultDownloadFileMaxAge = 24 * time.Hour
+
+// TempFileMaxAge reads TEMP_FILE_MAX_AGE_MINUTES
+func TempFileMaxAge() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("TEMP_FILE_MAX_AGE_MINUTES"))
+	if err != nil || minutes <= 0 {
+		return defaultTempFileMaxAge
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// DownloadFileMaxAge reads DOWNLOAD_FILE_MAX_AGE_MINUTES
+func DownloadFileMaxAge() time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv("DOWNLOAD_FILE_MAX_AGE_MINUTES"))
+	if err != nil || minutes <= 0 {
+		return defaultDownloadFileMaxAge
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// TempMediaDir returns TEMP_MEDIA_DIR, where uploads are staged before sending
+func TempMediaDir() string {
+	if dir := os.Getenv("TEMP_MEDIA_DIR"); dir != "" {
+		return dir
+	}
+	return "./temp"
+}
+
+// DownloadMediaDir returns DOWNLOAD_MEDIA_DIR, where inbound media is stored,
+// falling back to the upload temp directory
+func DownloadMediaDir() string {
+	if dir := os.Getenv("DOWNLOAD_MEDIA_DIR"); dir != "" {
+		return dir
+	}
+	return TempMediaDir()
+}
+
+// CleanTempDir removes files in dir older than maxAge and returns how many
+// files and bytes were freed
+func CleanTempDir(dir string, maxAge time.Duration) (int, int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read temp directory: %v", err)
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	removed := 0
+	var freed int64
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, entry.Name())); err == nil {
+			removed++
+			freed += info.Size()
+		}
+	}
+
+	return removed, freed, nil
+}
+
+// RunJanitor periodically removes files older than maxAge from dir, reporting to logger
+func RunJanitor(dir string, maxAge time.Duration, logger waLog.Logger) {
+	ticker := time.NewTicker(10 * time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		removed, freed, err := CleanTempDir(dir, maxAge)
+		if err != nil {
+			logger.Warnf("Janitor failed to clean %s: %v", dir, err)
+			continue
+		}
+		if removed > 0 {
+			logger.Infof("Janitor removed %d file(s) from %s, freed %d KB", removed, dir, freed>>10)
+		}
+	}
+}
+
+// EnsureDiskSpace checks that dir has room for size bytes, cleaning stale temp
+// files once before giving up with ErrInsufficientStorage
+func EnsureDiskSpace(dir string, size int64) error {
+	needed := uint64(size) + diskHeadroom
+
+	free, ok := freeDiskSpace(dir)
+	if !ok || free >= needed {
+		return nil
+	}
+
+	// Free space taken by leftovers of earlier uploads and retry
+	CleanTempDir(dir, TempFileMaxAge())
+	if free, ok = freeDiskSpace(dir); !ok || free >= needed {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %d MB free in temp directory, %d MB needed", ErrInsufficientStorage, free>>20, needed>>20)
+}
+
+// isNoSpace reports whether err was caused by a full disk
+func isNoSpace(err error) bool {
+	return errors.Is(err, syscall.ENOSPC)
+}
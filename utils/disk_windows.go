@@ -0,0 +1,8 @@
+//go:build windows
+
+package utils
+
+// freeDiskSpace is not implemented on Windows; the pre-upload check is skipped
+func freeDiskSpace(dir string) (uint64, bool) {
+	return 0, false
+}
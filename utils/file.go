@@ -1,11 +1,15 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"mime/multipart"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // MediaType represents the type of media file
@@ -26,56 +30,126 @@ var MediaLimits = map[MediaType]int64{
 	MediaTypeDocument: 100 * 1024 * 1024, // 100MB
 }
 
+// MediaExtensions lists the file extensions recognised for each media type.
+// Any other extension is sent as a document.
+var MediaExtensions = map[MediaType][]string{
+	MediaTypeImage: {".jpg", ".jpeg", ".png", ".gif"},
+	MediaTypeVideo: {".mp4", ".avi", ".mkv"},
+	MediaTypeAudio: {".mp3", ".ogg", ".opus", ".m4a"},
+}
+
+// LoadMediaLimits overrides the default size limits from MAX_<TYPE>_SIZE_MB
+// environment variables (e.g. MAX_VIDEO_SIZE_MB=32)
+func LoadMediaLimits() {
+	for mediaType := range MediaLimits {
+		envKey := "MAX_" + strings.ToUpper(string(mediaType)) + "_SIZE_MB"
+		mb, err := strconv.ParseInt(os.Getenv(envKey), 10, 64)
+		if err == nil && mb > 0 {
+			MediaLimits[mediaType] = mb * 1024 * 1024
+		}
+	}
+}
+
 // GetMediaType determines the media type based on file extension
 func GetMediaType(filename string) MediaType {
 	ext := strings.ToLower(filepath.Ext(filename))
-	
-	imageExts := []string{".jpg", ".jpeg", ".png", ".gif"}
-	videoExts := []string{".mp4", ".avi", ".mkv"}
-	audioExts := []string{".mp3", ".ogg", ".m4a"}
-	
-	for _, e := range imageExts {
-		if ext == e {
-			return MediaTypeImage
-		}
-	}
-	
-	for _, e := range videoExts {
-		if ext == e {
-			return MediaTypeVideo
-		}
-	}
-	
-	for _, e := range audioExts {
-		if ext == e {
-			return MediaTypeAudio
+
+	for _, mediaType := range []MediaType{MediaTypeImage, MediaTypeVideo, MediaTypeAudio} {
+		for _, e := range MediaExtensions[mediaType] {
+			if ext == e {
+				return mediaType
+			}
 		}
 	}
-	
+
 	return MediaTypeDocument
 }
 
 // ValidateFileSize checks if file size is within limits for its type
 func ValidateFileSize(fileHeader *multipart.FileHeader) error {
-	mediaType := GetMediaType(fileHeader.Filename)
+	return ValidateSize(fileHeader.Filename, fileHeader.Size)
+}
+
+// ValidateSize checks a file size against the limit for the media type of filename
+func ValidateSize(filename string, size int64) error {
+	mediaType := GetMediaType(filename)
 	maxSize := MediaLimits[mediaType]
-	
-	if fileHeader.Size > maxSize {
+
+	if size > maxSize {
 		return fmt.Errorf("file size exceeds maximum limit for %s type (%d MB)", mediaType, maxSize/(1024*1024))
 	}
-	
+
 	return nil
 }
 
-// SaveUploadedFile saves an uploaded file to the specified directory
+// maxFilenameLength caps sanitized filenames, in bytes, well below filesystem limits
+const maxFilenameLength = 128
+
+// uniquePrefixLength is the length of the random hex prefix of saved uploads, including its separator
+const uniquePrefixLength = 33
+
+// SafeFilename reduces a client-supplied filename to a plain base name that
+// can't escape its directory, keeping the extension for media type detection
+func SafeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '/' || r == ':' {
+			return -1
+		}
+		return r
+	}, name)
+	name = strings.TrimLeft(strings.TrimSpace(name), ".")
+
+	ext := filepath.Ext(name)
+	if len(ext) > 16 {
+		ext = ""
+	}
+	stem := strings.TrimSuffix(name, ext)
+	if stem == "" {
+		stem = "file"
+	}
+	for len(stem)+len(ext) > maxFilenameLength {
+		// Trim whole runes so the name stays valid UTF-8
+		_, size := utf8.DecodeLastRuneInString(stem)
+		stem = stem[:len(stem)-size]
+	}
+	return stem + ext
+}
+
+// uniqueFilename prefixes a sanitized filename with random hex so concurrent uploads never collide
+func uniqueFilename(name string) (string, error) {
+	prefix := make([]byte, 16)
+	if _, err := rand.Read(prefix); err != nil {
+		return "", fmt.Errorf("failed to generate filename: %v", err)
+	}
+	return hex.EncodeToString(prefix) + "_" + SafeFilename(name), nil
+}
+
+// OriginalFilename returns the client-facing name of a file saved by SaveUploadedFile or SaveBase64File
+func OriginalFilename(path string) string {
+	name := filepath.Base(path)
+	if len(name) > uniquePrefixLength && name[uniquePrefixLength-1] == '_' {
+		if _, err := hex.DecodeString(name[:uniquePrefixLength-1]); err == nil {
+			return name[uniquePrefixLength:]
+		}
+	}
+	return name
+}
+
+// SaveUploadedFile saves an uploaded file to the specified directory under a
+// unique, sanitized name
 func SaveUploadedFile(fileHeader *multipart.FileHeader, destDir string) (string, error) {
 	// Create destination directory if it doesn't exist
 	if err := os.MkdirAll(destDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create directory: %v", err)
 	}
-	
+
 	// Generate destination path
-	destPath := filepath.Join(destDir, fileHeader.Filename)
+	name, err := uniqueFilename(fileHeader.Filename)
+	if err != nil {
+		return "", err
+	}
+	destPath := filepath.Join(destDir, name)
 	
 	// Open source file
 	src, err := fileHeader.Open()
@@ -91,8 +165,13 @@ func SaveUploadedFile(fileHeader *multipart.FileHeader, destDir string) (string,
 	}
 	defer dst.Close()
 	
-	// Copy file content
+	// Copy file content, dropping partial files
 	if _, err := dst.ReadFrom(src); err != nil {
+		dst.Close()
+		os.Remove(destPath)
+		if isNoSpace(err) {
+			return "", fmt.Errorf("%w: %v", ErrInsufficientStorage, err)
+		}
 		return "", fmt.Errorf("failed to save file: %v", err)
 	}
 	
@@ -0,0 +1,135 @@
+package utils
+
+import (
+	"bytes"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSafeFilename(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"photo.jpg", "photo.jpg"},
+		{"../../etc/passwd", "passwd"},
+		{`..\..\windows\win.ini`, "win.ini"},
+		{"../", "file"},
+		{"", "file"},
+		{"...", "file"},
+		{".jpg", "jpg"},
+		{"evil\x00.jpg", "evil.jpg"},
+		{"line\r\nbreak.png", "linebreak.png"},
+		{"C:report.pdf", "Creport.pdf"},
+		{"foto liburan ñ 🌴.jpg", "foto liburan ñ 🌴.jpg"},
+		{"CON", "CON"},
+	}
+	for _, tt := range tests {
+		if got := SafeFilename(tt.name); got != tt.want {
+			t.Errorf("SafeFilename(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSafeFilenameTruncatesOverlongNames(t *testing.T) {
+	for _, name := range []string{
+		strings.Repeat("a", 1000) + ".mp4",
+		strings.Repeat("é", 500) + ".mp4",
+		strings.Repeat("a", 200) + "." + strings.Repeat("x", 100),
+	} {
+		got := SafeFilename(name)
+		if len(got) > maxFilenameLength {
+			t.Errorf("SafeFilename kept %d bytes, want at most %d", len(got), maxFilenameLength)
+		}
+		if !utf8.ValidString(got) {
+			t.Errorf("SafeFilename cut a rune in half: %q", got)
+		}
+		if ext := filepath.Ext(name); len(ext) <= 16 && filepath.Ext(got) != ext {
+			t.Errorf("SafeFilename dropped extension %q: %q", ext, got)
+		}
+	}
+}
+
+// windowsReserved are device names Windows refuses as filenames, with or without an extension
+var windowsReserved = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM9": true, "LPT1": true, "LPT9": true,
+}
+
+func TestSaveUploadedFileStaysInDestDir(t *testing.T) {
+	destDir := t.TempDir()
+	names := []string{
+		"photo.jpg",
+		"photo.jpg",
+		"../../escape.txt",
+		"..",
+		"",
+		"nul\x00byte.png",
+		"CON",
+		"nul.txt",
+		"LPT1.doc",
+		strings.Repeat("ü", 300) + ".pdf",
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range names {
+		path, err := SaveUploadedFile(uploadedFile(t, name, "content"), destDir)
+		if err != nil {
+			t.Fatalf("SaveUploadedFile(%q): %v", name, err)
+		}
+		if filepath.Dir(path) != destDir {
+			t.Fatalf("SaveUploadedFile(%q) wrote outside destDir: %s", name, path)
+		}
+		if seen[path] {
+			t.Fatalf("SaveUploadedFile(%q) reused path %s", name, path)
+		}
+		seen[path] = true
+
+		if got, want := OriginalFilename(path), SafeFilename(name); got != want {
+			t.Errorf("OriginalFilename = %q, want %q", got, want)
+		}
+		base := filepath.Base(path)
+		if stem := strings.ToUpper(strings.TrimSuffix(base, filepath.Ext(base))); windowsReserved[stem] {
+			t.Errorf("SaveUploadedFile(%q) saved under reserved name %q", name, base)
+		}
+		if data, err := os.ReadFile(path); err != nil || string(data) != "content" {
+			t.Errorf("SaveUploadedFile(%q) content = %q, %v", name, data, err)
+		}
+	}
+
+	entries, err := os.ReadDir(destDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(names) {
+		t.Fatalf("destDir holds %d files, want %d", len(entries), len(names))
+	}
+}
+
+// uploadedFile builds a multipart file header carrying content under the given client filename
+func uploadedFile(t *testing.T, filename, content string) *multipart.FileHeader {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "upload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write([]byte(content))
+	writer.Close()
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { form.RemoveAll() })
+
+	// Set the name directly so bytes a multipart header can't carry, like NUL, reach SaveUploadedFile
+	fileHeader := form.File["file"][0]
+	fileHeader.Filename = filename
+	return fileHeader
+}
@@ -6,26 +6,49 @@ import (
 
 // Response represents the standard API response structure
 type Response struct {
-	Success bool        `json:"success"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data"`
+	Success   bool        `json:"success"`
+	Code      string      `json:"code,omitempty"`
+	Message   string      `json:"message"`
+	Data      interface{} `json:"data"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// RequestIDKey is the gin context key holding the request ID
+const RequestIDKey = "request_id"
+
+// RequestID returns the ID of the current request, or "" outside the request ID middleware
+func RequestID(c *gin.Context) string {
+	return c.GetString(RequestIDKey)
 }
 
 // SuccessResponse sends a success response with data
 func SuccessResponse(c *gin.Context, statusCode int, message string, data interface{}) {
 	c.JSON(statusCode, Response{
-		Success: true,
-		Message: message,
-		Data:    data,
+		Success:   true,
+		Message:   message,
+		Data:      data,
+		RequestID: RequestID(c),
 	})
 }
 
-// ErrorResponse sends an error response
-func ErrorResponse(c *gin.Context, statusCode int, message string) {
+// ErrorResponseWithData sends an error response with an error code and additional data
+func ErrorResponseWithData(c *gin.Context, statusCode int, code, message string, data interface{}) {
 	c.JSON(statusCode, Response{
-		Success: false,
-		Message: message,
-		Data:    nil,
+		Success:   false,
+		Code:      code,
+		Message:   message,
+		Data:      data,
+		RequestID: RequestID(c),
 	})
 }
 
+// ErrorResponseWithCode sends an error response with a specific error code
+func ErrorResponseWithCode(c *gin.Context, statusCode int, code, message string) {
+	ErrorResponseWithData(c, statusCode, code, message, nil)
+}
+
+// ErrorResponse sends an error response with the generic code for its status
+func ErrorResponse(c *gin.Context, statusCode int, message string) {
+	ErrorResponseWithData(c, statusCode, CodeForStatus(statusCode), message, nil)
+}
+
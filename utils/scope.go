@@ -0,0 +1,47 @@
+package utils
+
+import "github.com/gin-gonic/gin"
+
+// TokenScopeKey is the gin context key holding the caller's token scope
+const TokenScopeKey = "token_scope"
+
+// TokenScope is the set of devices an API token may operate on
+type TokenScope struct {
+	// Name identifies the token in logs without revealing it
+	Name string
+	// All grants access to every device, for admin tokens
+	All     bool
+	Devices map[string]bool
+}
+
+// Allows reports whether the scope covers a device. A nil scope, outside the
+// auth middleware, allows everything.
+func (s *TokenScope) Allows(deviceID string) bool {
+	return s == nil || s.All || s.Devices[deviceID]
+}
+
+// IsAdmin reports whether the scope covers every device
+func (s *TokenScope) IsAdmin() bool {
+	return s == nil || s.All
+}
+
+// Scope returns the token scope of the current request
+func Scope(c *gin.Context) *TokenScope {
+	scope, _ := c.Get(TokenScopeKey)
+	s, _ := scope.(*TokenScope)
+	return s
+}
+
+// RateLimitWaitKey is the gin context key holding the wait function of the
+// rate limits that apply to the current request
+const RateLimitWaitKey = "rate_limit_wait"
+
+// RateLimitWait returns a function that blocks until the request's rate limits
+// allow one more message. Work fanned out from a single request, such as a
+// bulk send, calls it per message. It never blocks when no limit applies.
+func RateLimitWait(c *gin.Context) func() {
+	if wait, ok := c.Value(RateLimitWaitKey).(func()); ok {
+		return wait
+	}
+	return func() {}
+}